@@ -0,0 +1,76 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+AddOffsetsToTxn Request (Version: 0) => transactional_id producer_id producer_epoch group_id
+  transactional_id => STRING
+  producer_id => INT64
+  producer_epoch => INT16
+  group_id => STRING
+
+FIELD	DESCRIPTION
+  transactional_id	The transactional id corresponding to the transaction.
+  producer_id	Current producer id in use by the transactional id.
+  producer_epoch	Current epoch associated with the producer id.
+  group_id	The unique group identifier.
+*/
+
+type AddOffsetsToTxnRequest struct {
+	RequestHeader   *RequestHeader
+	TransactionalID string
+	ProducerID      int64
+	ProducerEpoch   int16
+	GroupID         string
+}
+
+func NewAddOffsetsToTxnRequest(apiVersion uint16, clientID, transactionalID string, producerID int64, producerEpoch int16, groupID string) *AddOffsetsToTxnRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_AddOffsetsToTxn,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &AddOffsetsToTxnRequest{requestHeader, transactionalID, producerID, producerEpoch, groupID}
+}
+
+func (r *AddOffsetsToTxnRequest) Length() int {
+	return r.RequestHeader.length() + 2 + len(r.TransactionalID) + 8 + 2 + 2 + len(r.GroupID)
+}
+
+func (r *AddOffsetsToTxnRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(len(r.TransactionalID)))
+	offset += 2
+	offset += copy(payload[offset:], r.TransactionalID)
+
+	binary.BigEndian.PutUint64(payload[offset:], uint64(r.ProducerID))
+	offset += 8
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(r.ProducerEpoch))
+	offset += 2
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(len(r.GroupID)))
+	offset += 2
+	copy(payload[offset:], r.GroupID)
+
+	return payload
+}
+
+func (r *AddOffsetsToTxnRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *AddOffsetsToTxnRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
@@ -0,0 +1,119 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+AddPartitionsToTxn Request (Version: 0) => transactional_id producer_id producer_epoch [topics]
+  transactional_id => STRING
+  producer_id => INT64
+  producer_epoch => INT16
+  topics => name [partitions]
+    name => STRING
+    partitions => INT32
+
+FIELD	DESCRIPTION
+  transactional_id	The transactional id corresponding to the transaction.
+  producer_id	Current producer id in use by the transactional id.
+  producer_epoch	Current epoch associated with the producer id.
+  topics	The partitions to add to the transaction.
+  name	The name of the topic.
+  partitions	The partition indexes to add to the transaction
+*/
+
+type AddPartitionsToTxnTopic struct {
+	Topic      string
+	Partitions []int32
+}
+
+type AddPartitionsToTxnRequest struct {
+	RequestHeader   *RequestHeader
+	TransactionalID string
+	ProducerID      int64
+	ProducerEpoch   int16
+	Topics          []*AddPartitionsToTxnTopic
+}
+
+func NewAddPartitionsToTxnRequest(apiVersion uint16, clientID, transactionalID string, producerID int64, producerEpoch int16) *AddPartitionsToTxnRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_AddPartitionsToTxn,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &AddPartitionsToTxnRequest{
+		RequestHeader:   requestHeader,
+		TransactionalID: transactionalID,
+		ProducerID:      producerID,
+		ProducerEpoch:   producerEpoch,
+		Topics:          make([]*AddPartitionsToTxnTopic, 0),
+	}
+}
+
+func (r *AddPartitionsToTxnRequest) AddPartition(topic string, partitionID int32) {
+	for _, t := range r.Topics {
+		if t.Topic == topic {
+			t.Partitions = append(t.Partitions, partitionID)
+			return
+		}
+	}
+	r.Topics = append(r.Topics, &AddPartitionsToTxnTopic{topic, []int32{partitionID}})
+}
+
+func (r *AddPartitionsToTxnRequest) Length() int {
+	l := r.RequestHeader.length()
+	l += 2 + len(r.TransactionalID) + 8 + 2
+	l += 4
+	for _, t := range r.Topics {
+		l += 2 + len(t.Topic)
+		l += 4 + 4*len(t.Partitions)
+	}
+	return l
+}
+
+func (r *AddPartitionsToTxnRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(len(r.TransactionalID)))
+	offset += 2
+	offset += copy(payload[offset:], r.TransactionalID)
+
+	binary.BigEndian.PutUint64(payload[offset:], uint64(r.ProducerID))
+	offset += 8
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(r.ProducerEpoch))
+	offset += 2
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(len(r.Topics)))
+	offset += 4
+	for _, t := range r.Topics {
+		binary.BigEndian.PutUint16(payload[offset:], uint16(len(t.Topic)))
+		offset += 2
+		offset += copy(payload[offset:], t.Topic)
+
+		binary.BigEndian.PutUint32(payload[offset:], uint32(len(t.Partitions)))
+		offset += 4
+		for _, partitionID := range t.Partitions {
+			binary.BigEndian.PutUint32(payload[offset:], uint32(partitionID))
+			offset += 4
+		}
+	}
+
+	return payload
+}
+
+func (r *AddPartitionsToTxnRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *AddPartitionsToTxnRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
@@ -0,0 +1,93 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+AddPartitionsToTxn Response (Version: 0) => throttle_time_ms [results]
+  throttle_time_ms => INT32
+  results => name [results]
+    name => STRING
+    results => partition_index error_code
+      partition_index => INT32
+      error_code => INT16
+
+FIELD	DESCRIPTION
+  throttle_time_ms	Duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+  results	The results for each topic.
+  name	The topic name.
+  results	The results for each partition
+  partition_index	The partition indexes.
+  error_code	The response error code.
+*/
+
+type AddPartitionsToTxnResponsePartition struct {
+	PartitionID int32
+	ErrorCode   int16
+}
+
+type AddPartitionsToTxnResponseTopic struct {
+	Topic      string
+	Partitions []*AddPartitionsToTxnResponsePartition
+}
+
+type AddPartitionsToTxnResponse struct {
+	CorrelationID  uint32
+	ThrottleTimeMS int32
+	Topics         []*AddPartitionsToTxnResponseTopic
+}
+
+func NewAddPartitionsToTxnResponse(payload []byte) (*AddPartitionsToTxnResponse, error) {
+	var (
+		r      *AddPartitionsToTxnResponse = &AddPartitionsToTxnResponse{}
+		err    error                       = nil
+		offset int                         = 0
+		l      int                         = 0
+	)
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("AddPartitionsToTxn reseponse length did not match: %d!=%d", responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	r.ThrottleTimeMS = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	l = int(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	r.Topics = make([]*AddPartitionsToTxnResponseTopic, l)
+	for i := range r.Topics {
+		topic := &AddPartitionsToTxnResponseTopic{}
+		r.Topics[i] = topic
+
+		l = int(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		topic.Topic = string(payload[offset : offset+l])
+		offset += l
+
+		l = int(binary.BigEndian.Uint32(payload[offset:]))
+		offset += 4
+		topic.Partitions = make([]*AddPartitionsToTxnResponsePartition, l)
+		for j := range topic.Partitions {
+			p := &AddPartitionsToTxnResponsePartition{}
+			topic.Partitions[j] = p
+
+			p.PartitionID = int32(binary.BigEndian.Uint32(payload[offset:]))
+			offset += 4
+			p.ErrorCode = int16(binary.BigEndian.Uint16(payload[offset:]))
+			offset += 2
+
+			// other partitions get OPERATION_NOT_ATTEMPTED if one fails, return the real cause
+			if p.ErrorCode != 0 && (err == nil || err == AllError[55]) {
+				err = getErrorFromErrorCode(p.ErrorCode)
+			}
+		}
+	}
+
+	return r, err
+}
@@ -0,0 +1,108 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+AlterConfigs Request (Version: 0) => [resources] validate_only
+  resources => resource_type resource_name [config_entries]
+    resource_type => INT8
+    resource_name => STRING
+    config_entries => config_name config_value
+      config_name => STRING
+      config_value => NULLABLE_STRING
+  validate_only => BOOLEAN
+
+FIELD	DESCRIPTION
+  resources	The updates for each resource.
+  resource_type	The resource type
+  resource_name	The resource name
+  config_entries	The configurations. The configurations of the resource that are not in the request are reverted to default
+  config_name	The configuration key name
+  config_value	The value to set for the configuration key
+  validate_only	True if we should validate the request, but not change the configurations.
+*/
+
+type AlterConfigsRequestResource struct {
+	ResourceType  int8
+	ResourceName  string
+	ConfigEntries map[string]string
+}
+
+type AlterConfigsRequest struct {
+	RequestHeader *RequestHeader
+	Resources     []*AlterConfigsRequestResource
+	ValidateOnly  bool
+}
+
+func NewAlterConfigsRequest(apiVersion uint16, clientID string, resources []*AlterConfigsRequestResource, validateOnly bool) *AlterConfigsRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_AlterConfigs,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &AlterConfigsRequest{requestHeader, resources, validateOnly}
+}
+
+func (r *AlterConfigsRequest) Length() int {
+	l := r.RequestHeader.length()
+	l += 4
+	for _, resource := range r.Resources {
+		l += 1 + 2 + len(resource.ResourceName)
+		l += 4
+		for name, value := range resource.ConfigEntries {
+			l += 2 + len(name) + 2 + len(value)
+		}
+	}
+	l++
+	return l
+}
+
+func (r *AlterConfigsRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(len(r.Resources)))
+	offset += 4
+	for _, resource := range r.Resources {
+		payload[offset] = byte(resource.ResourceType)
+		offset++
+
+		binary.BigEndian.PutUint16(payload[offset:], uint16(len(resource.ResourceName)))
+		offset += 2
+		offset += copy(payload[offset:], resource.ResourceName)
+
+		binary.BigEndian.PutUint32(payload[offset:], uint32(len(resource.ConfigEntries)))
+		offset += 4
+		for name, value := range resource.ConfigEntries {
+			binary.BigEndian.PutUint16(payload[offset:], uint16(len(name)))
+			offset += 2
+			offset += copy(payload[offset:], name)
+			binary.BigEndian.PutUint16(payload[offset:], uint16(len(value)))
+			offset += 2
+			offset += copy(payload[offset:], value)
+		}
+	}
+
+	if r.ValidateOnly {
+		payload[offset] = 1
+	}
+
+	return payload
+}
+
+func (r *AlterConfigsRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *AlterConfigsRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
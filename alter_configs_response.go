@@ -0,0 +1,87 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+AlterConfigs Response (Version: 0) => throttle_time_ms [resources]
+  throttle_time_ms => INT32
+  resources => error_code error_message resource_type resource_name
+    error_code => INT16
+    error_message => NULLABLE_STRING
+    resource_type => INT8
+    resource_name => STRING
+
+FIELD	DESCRIPTION
+  throttle_time_ms	Duration in milliseconds for which the request was throttled due to quota violation (Zero if the request did not violate any quota)
+  error_code	The resource error code.
+  error_message	The resource error message, or null if there was no error.
+  resource_type	The resource type
+  resource_name	The resource name
+*/
+
+type AlterConfigsResponseResource struct {
+	ErrorCode    int16
+	ErrorMessage string
+	ResourceType int8
+	ResourceName string
+}
+
+type AlterConfigsResponse struct {
+	CorrelationID  uint32
+	ThrottleTimeMS int32
+	Resources      []*AlterConfigsResponseResource
+}
+
+func NewAlterConfigsResponse(payload []byte) (*AlterConfigsResponse, error) {
+	return decodeAlterConfigsResponse(payload, "AlterConfigs")
+}
+
+// decodeAlterConfigsResponse decodes AlterConfigs response and IncrementalAlterConfigs response, which have the same layout
+func decodeAlterConfigsResponse(payload []byte, apiName string) (*AlterConfigsResponse, error) {
+	var (
+		r      *AlterConfigsResponse = &AlterConfigsResponse{}
+		offset int                   = 0
+		l      int                   = 0
+		err    error
+	)
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("%s reseponse length did not match: %d!=%d", apiName, responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	r.ThrottleTimeMS = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	l = int(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	r.Resources = make([]*AlterConfigsResponseResource, l)
+	for i := range r.Resources {
+		resource := &AlterConfigsResponseResource{}
+		r.Resources[i] = resource
+
+		resource.ErrorCode = int16(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		resource.ErrorMessage, offset = decodeNullableString(payload, offset)
+
+		resource.ResourceType = int8(payload[offset])
+		offset++
+
+		l = int(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		resource.ResourceName = string(payload[offset : offset+l])
+		offset += l
+
+		if err == nil && resource.ErrorCode != 0 {
+			err = configResourceError(resource.ResourceName, resource.ErrorCode, resource.ErrorMessage)
+		}
+	}
+
+	return r, err
+}
@@ -1,9 +1,11 @@
 package healer
 
 import (
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
 	"time"
@@ -25,15 +27,39 @@ type Broker struct {
 
 	correlationID uint32
 
+	// mux is held while the connection is opened and each request is written
 	mux sync.Mutex
 
 	dead bool
+
+	// exclusive is held for read by Request until the response is read,
+	// and for write by requestFetchStreamingly, which reads the connection by itself
+	exclusive sync.RWMutex
+	// inFlight limits the requests written and waiting for responses to max.in.flight.requests.per.connection
+	inFlight chan struct{}
+	// readTurn is held by the waiting request which reads the next response from the connection for all of them
+	readTurn   chan struct{}
+	pendingMux sync.Mutex
+	pending    []*pendingRequest // in the order they are written. kafka responds in the same order
+}
+
+// pendingRequest is a request written to conn and waiting for its response
+type pendingRequest struct {
+	conn          net.Conn
+	correlationID uint32
+	timeout       int
+
+	done     chan struct{}
+	response []byte
+	err      error
 }
 
 // NewBroker is used just as bootstrap in NewBrokers.
 // user must always init a Brokers instance by NewBrokers
 func NewBroker(address string, nodeID int32, config *BrokerConfig) (*Broker, error) {
-	//TODO get available api versions
+	if err := config.checkValid(); err != nil {
+		return nil, fmt.Errorf("broker config error: %s", err)
+	}
 	broker := &Broker{
 		config:  config,
 		address: address,
@@ -41,23 +67,194 @@ func NewBroker(address string, nodeID int32, config *BrokerConfig) (*Broker, err
 
 		correlationID: 0,
 		dead:          true,
+
+		inFlight: make(chan struct{}, config.MaxInFlightRequestsPerConnection),
+		readTurn: make(chan struct{}, 1),
 	}
 
-	conn, err := net.DialTimeout("tcp4", address, time.Duration(config.ConnectTimeoutMS)*time.Millisecond)
-	if err != nil {
+	if err := broker.connect(); err != nil {
 		return nil, fmt.Errorf("failed to establish connection when init broker: %s", err)
 	}
+
+	return broker, nil
+}
+
+func (broker *Broker) dial() error {
+	var (
+		conn net.Conn
+		err  error
+	)
+	timeout := time.Duration(broker.config.ConnectTimeoutMS) * time.Millisecond
+	if broker.config.TLSEnabled {
+		var tlsConfig *tls.Config
+		if tlsConfig, err = createTLSConfig(&broker.config.TLS); err != nil {
+			return err
+		}
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp4", broker.address, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp4", broker.address, timeout)
+	}
+	if err != nil {
+		return err
+	}
 	broker.conn = conn
 	broker.dead = false
+	return nil
+}
 
-	// TODO since ??
-	//apiVersionsResponse, err := broker.requestApiVersions()
-	//if err != nil {
-	//return nil, fmt.Errorf("failed to request api versions when init broker: %s", err)
-	//}
-	//broker.apiVersions = apiVersionsResponse.ApiVersions
+// connect dials the broker and negotiates api versions with it. it must be called with broker.mux held
+func (broker *Broker) connect() error {
+	if err := broker.dial(); err != nil {
+		return err
+	}
 
-	return broker, nil
+	// ApiVersions is supported since 0.10.0. older brokers close the connection, so reconnect and use v0 for all apis
+	apiVersionsResponse, err := broker.negotiateApiVersions()
+	if err != nil {
+		glog.Infof("failed to request api versions from %s, use version 0 for all apis: %s", broker.address, err)
+		broker.apiVersions = nil
+		if broker.dead {
+			if err := broker.dial(); err != nil {
+				return err
+			}
+		}
+	} else {
+		broker.apiVersions = apiVersionsResponse.ApiVersions
+	}
+
+	if broker.config.SASL.Mechanism != "" {
+		if err := broker.authenticate(); err != nil {
+			broker.Close()
+			return fmt.Errorf("sasl authentication to %s failed: %s", broker.address, err)
+		}
+	}
+	return nil
+}
+
+// authenticate does SaslHandshake and then exchanges sasl tokens with the broker.
+// tokens are wrapped in SaslAuthenticate requests if SaslHandshake v1 is available, or else sent as opaque packets
+func (broker *Broker) authenticate() error {
+	mechanism, err := newSaslMechanism(&broker.config.SASL)
+	if err != nil {
+		return err
+	}
+
+	version := broker.getHighestAvailableAPIVersion(API_SaslHandshake)
+	request := NewSaslHandshakeRequest(version, broker.config.ClientID, broker.config.SASL.Mechanism)
+	broker.correlationID++
+	request.SetCorrelationID(broker.correlationID)
+
+	responseBuf, err := broker.request(request.Encode(), broker.config.TimeoutMS)
+	if err != nil {
+		return err
+	}
+	if response, err := NewSaslHandshakeResponse(responseBuf); err != nil {
+		if response != nil {
+			return fmt.Errorf("%s enabled mechanisms: %v", err, response.EnabledMechanisms)
+		}
+		return err
+	}
+
+	var challenge []byte
+	for {
+		token, err := mechanism.step(challenge)
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			glog.V(5).Infof("sasl authentication to %s succeeded", broker.address)
+			return nil
+		}
+		if version >= 1 {
+			challenge, err = broker.saslAuthenticate(token)
+		} else {
+			challenge, err = broker.exchangeSaslToken(token)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (broker *Broker) saslAuthenticate(token []byte) ([]byte, error) {
+	version := broker.getHighestAvailableAPIVersion(API_SaslAuthenticate)
+	request := NewSaslAuthenticateRequest(version, broker.config.ClientID, token)
+	broker.correlationID++
+	request.SetCorrelationID(broker.correlationID)
+
+	responseBuf, err := broker.request(request.Encode(), broker.config.TimeoutMS)
+	if err != nil {
+		return nil, err
+	}
+	response, err := NewSaslAuthenticateResponse(responseBuf)
+	if err != nil {
+		return nil, err
+	}
+	return response.AuthBytes, nil
+}
+
+// exchangeSaslToken sends the token without kafka header and reads the size-delimited challenge, as SaslHandshake v0 requires
+func (broker *Broker) exchangeSaslToken(token []byte) ([]byte, error) {
+	if broker.config.TimeoutMS > 0 {
+		broker.conn.SetDeadline(time.Now().Add(time.Duration(broker.config.TimeoutMS) * time.Millisecond))
+		defer broker.conn.SetDeadline(time.Time{})
+	}
+	if _, err := broker.conn.Write(encodeSaslToken(token)); err != nil {
+		return nil, err
+	}
+
+	lengthBuf := make([]byte, 4)
+	if _, err := io.ReadFull(broker.conn, lengthBuf); err != nil {
+		return nil, err
+	}
+	challenge := make([]byte, binary.BigEndian.Uint32(lengthBuf))
+	if _, err := io.ReadFull(broker.conn, challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// negotiateApiVersions does not lock broker.mux, so it could be called in connect
+func (broker *Broker) negotiateApiVersions() (*ApiVersionsResponse, error) {
+	request := NewApiVersionsRequest(0, broker.config.ClientID)
+	broker.correlationID++
+	request.SetCorrelationID(broker.correlationID)
+
+	responseBuf, err := broker.request(request.Encode(), broker.config.TimeoutMS)
+	if err != nil {
+		return nil, err
+	}
+	apiVersionsResponse, err := NewApiVersionsResponse(responseBuf)
+	if err != nil {
+		return nil, err
+	}
+	if apiVersionsResponse.ErrorCode != 0 {
+		return nil, getErrorFromErrorCode(int16(apiVersionsResponse.ErrorCode))
+	}
+	return apiVersionsResponse, nil
+}
+
+// getHighestAvailableAPIVersion returns the highest version of apiKey that both healer and the broker support
+func (broker *Broker) getHighestAvailableAPIVersion(apiKey uint16) uint16 {
+	versions, ok := availableVersions[apiKey]
+	if !ok {
+		return 0
+	}
+	for _, apiVersion := range broker.apiVersions {
+		if apiVersion.apiKey != int16(apiKey) {
+			continue
+		}
+		for i := len(versions) - 1; i >= 0; i-- {
+			v := int16(versions[i])
+			if v >= apiVersion.minVersion && v <= apiVersion.maxVersion {
+				return versions[i]
+			}
+		}
+	}
+	if len(broker.apiVersions) > 0 {
+		glog.Warningf("broker %s does not support any version of api %d that healer knows, use version 0", broker.address, apiKey)
+	}
+	return 0
 }
 
 func (broker *Broker) GetAddress() string {
@@ -73,92 +270,211 @@ func (broker *Broker) IsDead() bool {
 	return broker.dead
 }
 
-func (broker *Broker) ensureOpen() {
+// ensureOpen reconnects the broker if it is dead. it must be called with broker.mux held
+func (broker *Broker) ensureOpen() error {
 	if broker.dead {
 		glog.Infof("broker %s dead, reopen it", broker.address)
-		conn, err := net.DialTimeout("tcp4", broker.address, time.Duration(broker.config.ConnectTimeoutMS)*time.Millisecond)
-		if err != nil {
-			// TODO fatal?
-			glog.Fatalf("could not conn to %s:%s", broker.address, err)
+		// the broker may be upgraded or downgraded, so negotiate api versions again
+		if err := broker.connect(); err != nil {
+			return fmt.Errorf("could not conn to %s: %s", broker.address, err)
 		}
-		broker.conn = conn
-		broker.dead = false
 	}
+	return nil
 }
 
+// Request writes the request and waits for its response. requests from different goroutines are pipelined on the connection,
+// up to max.in.flight.requests.per.connection, and the responses are matched to them by correlation id.
+// the broker never answers a ProduceRequest with acks=0, so it returns nil response once the request is written
 func (broker *Broker) Request(r Request) ([]byte, error) {
-	broker.mux.Lock()
-	defer broker.mux.Unlock()
+	broker.exclusive.RLock()
+	defer broker.exclusive.RUnlock()
 
-	broker.ensureOpen()
+	broker.inFlight <- struct{}{}
+	defer func() { <-broker.inFlight }()
 
-	broker.correlationID++
-	r.SetCorrelationID(broker.correlationID)
 	timeout := broker.config.TimeoutMS
 	if len(broker.config.TimeoutMSForEachAPI) > int(r.API()) {
 		timeout = broker.config.TimeoutMSForEachAPI[r.API()]
 	}
-	return broker.request(r.Encode(), timeout)
+
+	broker.mux.Lock()
+	if err := broker.ensureOpen(); err != nil {
+		broker.mux.Unlock()
+		return nil, err
+	}
+
+	broker.correlationID++
+	r.SetCorrelationID(broker.correlationID)
+
+	if produceRequest, ok := r.(*ProduceRequest); ok && produceRequest.RequiredAcks == 0 {
+		err := broker.write(r.Encode())
+		if err != nil {
+			broker.Close()
+		}
+		broker.mux.Unlock()
+		return nil, err
+	}
+
+	p := &pendingRequest{
+		conn:          broker.conn,
+		correlationID: broker.correlationID,
+		timeout:       timeout,
+		done:          make(chan struct{}),
+	}
+	// it is pending before written, so that the response is never read before it could be matched
+	broker.pendingMux.Lock()
+	broker.pending = append(broker.pending, p)
+	broker.pendingMux.Unlock()
+
+	if err := broker.write(r.Encode()); err != nil {
+		// the pending requests of the connection fail when their responses are read from the closed connection
+		broker.Close()
+	}
+	broker.mux.Unlock()
+
+	return broker.wait(p)
 }
 
-func (broker *Broker) request(payload []byte, timeout int) ([]byte, error) {
-	// TODO log?
+// wait returns the response of p. the waiting requests take turns to read responses from the connection and hand them out,
+// so that p returns as soon as its response is read by anyone
+func (broker *Broker) wait(p *pendingRequest) ([]byte, error) {
+	for {
+		select {
+		case <-p.done:
+			return p.response, p.err
+		case broker.readTurn <- struct{}{}:
+		}
+
+		select {
+		case <-p.done:
+		default:
+			broker.readPendingResponse(p.conn)
+		}
+		<-broker.readTurn
+	}
+}
+
+// readPendingResponse reads one response from conn and hands it out to the first pending request of conn.
+// all pending requests of conn fail if the response could not be read or does not match the first one
+func (broker *Broker) readPendingResponse(conn net.Conn) {
+	var first *pendingRequest
+	broker.pendingMux.Lock()
+	for _, p := range broker.pending {
+		if p.conn == conn {
+			first = p
+			break
+		}
+	}
+	broker.pendingMux.Unlock()
+	if first == nil {
+		return
+	}
+
+	responseBuf, err := readResponse(conn, first.timeout)
+	if err == nil {
+		if correlationID := binary.BigEndian.Uint32(responseBuf[4:]); correlationID != first.correlationID {
+			err = fmt.Errorf("correlation id of response from %s is %d, expect %d", broker.address, correlationID, first.correlationID)
+		}
+	}
+
+	if err != nil {
+		broker.mux.Lock()
+		if broker.conn == conn {
+			broker.Close()
+		} else {
+			conn.Close()
+		}
+		broker.mux.Unlock()
+	}
+
+	broker.pendingMux.Lock()
+	defer broker.pendingMux.Unlock()
+
+	pending := broker.pending[:0]
+	for _, p := range broker.pending {
+		switch {
+		case p == first && err == nil:
+			p.response = responseBuf
+			close(p.done)
+		case p.conn == conn && err != nil:
+			p.err = err
+			close(p.done)
+		default:
+			pending = append(pending, p)
+		}
+	}
+	for i := len(pending); i < len(broker.pending); i++ {
+		broker.pending[i] = nil
+	}
+	broker.pending = pending
+}
+
+// write writes the whole payload to the connection
+func (broker *Broker) write(payload []byte) error {
 	glog.V(10).Infof("%s -> %s", broker.conn.LocalAddr(), broker.conn.RemoteAddr())
 	glog.V(10).Infof("request length: %d. api: %d CorrelationID: %d", len(payload), binary.BigEndian.Uint16(payload[4:]), binary.BigEndian.Uint32(payload[8:]))
 	n, err := broker.conn.Write(payload)
 	if err != nil {
 		glog.Error(err)
+		return err
 	}
 	if n != len(payload) {
 		glog.Errorf("write only partial data. api: %d CorrelationID: %d", binary.BigEndian.Uint16(payload[4:]), binary.BigEndian.Uint32(payload[8:]))
+		return io.ErrShortWrite
 	}
+	return nil
+}
 
-	l := 0
-	responseLengthBuf := make([]byte, 4)
-	for {
-		if timeout > 0 {
-			broker.conn.SetReadDeadline(time.Now().Add(time.Duration(timeout) * time.Millisecond))
-		}
-		length, err := broker.conn.Read(responseLengthBuf[l:])
-		if err != nil {
-			broker.Close()
-			return nil, err
-		}
+// request writes the payload and reads the response at once. it is used only while the connection is being opened,
+// when no other request is on the connection
+func (broker *Broker) request(payload []byte, timeout int) ([]byte, error) {
+	if err := broker.write(payload); err != nil {
+		broker.Close()
+		return nil, err
+	}
+	responseBuf, err := readResponse(broker.conn, timeout)
+	if err != nil {
+		broker.Close()
+		return nil, err
+	}
+	return responseBuf, nil
+}
 
-		if length+l == 4 {
-			break
-		}
-		l = length
+// readResponse reads one size-delimited response, including the 4 bytes of size. the read deadline is reset before each read if timeout > 0
+func readResponse(conn net.Conn, timeout int) ([]byte, error) {
+	responseLengthBuf := make([]byte, 4)
+	if err := readWithTimeout(conn, responseLengthBuf, timeout); err != nil {
+		return nil, err
 	}
 
 	responseLength := int(binary.BigEndian.Uint32(responseLengthBuf))
 	glog.V(10).Infof("response length in header: %d", responseLength+4)
 	responseBuf := make([]byte, 4+responseLength)
+	copy(responseBuf[0:4], responseLengthBuf)
+	if err := readWithTimeout(conn, responseBuf[4:], timeout); err != nil {
+		return nil, err
+	}
+	if len(responseBuf) < 8 {
+		return nil, fmt.Errorf("response is too short: %d bytes", len(responseBuf))
+	}
+	glog.V(10).Infof("response length: %d. CorrelationID: %d", len(responseBuf), binary.BigEndian.Uint32(responseBuf[4:]))
+	glog.V(100).Infof("response:%v", responseBuf)
 
-	readLength := 0
-	for {
+	return responseBuf, nil
+}
+
+func readWithTimeout(conn net.Conn, buf []byte, timeout int) error {
+	for readLength := 0; readLength < len(buf); {
 		if timeout > 0 {
-			broker.conn.SetReadDeadline(time.Now().Add(time.Duration(timeout) * time.Millisecond))
+			conn.SetReadDeadline(time.Now().Add(time.Duration(timeout) * time.Millisecond))
 		}
-		length, err := broker.conn.Read(responseBuf[4+readLength:])
+		length, err := conn.Read(buf[readLength:])
 		if err != nil {
-			broker.Close()
-			return nil, err
+			return err
 		}
-
 		readLength += length
-		if readLength > responseLength {
-			return nil, errors.New("fetch more data than needed while read response")
-		}
-		if readLength == responseLength {
-			break
-		}
 	}
-	copy(responseBuf[0:4], responseLengthBuf)
-	glog.V(10).Infof("response length: %d. CorrelationID: %d", len(responseBuf), binary.BigEndian.Uint32(responseBuf[4:]))
-	glog.V(100).Infof("response:%v", responseBuf)
-
-	return responseBuf, nil
+	return nil
 }
 
 func (broker *Broker) requestStreamingly(payload []byte, buffers chan []byte, timeout int) error {
@@ -258,12 +574,13 @@ func (broker *Broker) requestListGroups(clientID string) (*ListGroupsResponse, e
 }
 
 func (broker *Broker) requestMetaData(clientID string, topics []string) (*MetadataResponse, error) {
+	version := broker.getHighestAvailableAPIVersion(API_MetadataRequest)
 	metadataRequest := &MetadataRequest{
 		Topics: topics,
 	}
 	metadataRequest.RequestHeader = &RequestHeader{
 		ApiKey:     API_MetadataRequest,
-		ApiVersion: 0,
+		ApiVersion: version,
 		ClientId:   clientID,
 	}
 
@@ -272,19 +589,25 @@ func (broker *Broker) requestMetaData(clientID string, topics []string) (*Metada
 		return nil, err
 	}
 
-	return NewMetadataResponse(responseBuf)
+	return NewMetadataResponse(responseBuf, version)
 }
 
-// RequestOffsets return the offset values array from ther broker. all partitionID in partitionIDs must be in THIS broker
+// RequestOffsets return the offset values array from ther broker. all partitionID in partitionIDs must be in THIS broker.
+// version 1 is used if offsets is 1 and the broker supports it, which returns the exact offset of the timestamp
 func (broker *Broker) requestOffsets(clientID, topic string, partitionIDs []int32, timeValue int64, offsets uint32) (*OffsetsResponse, error) {
+	var version uint16
+	if offsets == 1 {
+		version = broker.getHighestAvailableAPIVersion(API_OffsetRequest)
+	}
 	offsetsRequest := NewOffsetsRequest(topic, partitionIDs, timeValue, offsets, clientID)
+	offsetsRequest.RequestHeader.ApiVersion = version
 
 	responseBuf, err := broker.Request(offsetsRequest)
 	if err != nil {
 		return nil, err
 	}
 
-	offsetsResponse, err := NewOffsetsResponse(responseBuf)
+	offsetsResponse, err := NewOffsetsResponse(responseBuf, version)
 	if err != nil {
 		return nil, err
 	}
@@ -293,14 +616,15 @@ func (broker *Broker) requestOffsets(clientID, topic string, partitionIDs []int3
 }
 
 func (broker *Broker) requestFindCoordinator(clientID, groupID string) (*FindCoordinatorResponse, error) {
-	findCoordinatorRequest := NewFindCoordinatorRequest(clientID, groupID)
+	version := broker.getHighestAvailableAPIVersion(API_FindCoordinator)
+	findCoordinatorRequest := NewFindCoordinatorRequest(version, clientID, groupID)
 
 	responseBuf, err := broker.Request(findCoordinatorRequest)
 	if err != nil {
 		return nil, err
 	}
 
-	findCoordinatorResponse, err := NewFindCoordinatorResponse(responseBuf)
+	findCoordinatorResponse, err := NewFindCoordinatorResponse(responseBuf, version)
 	if err != nil {
 		return nil, err
 	}
@@ -308,11 +632,31 @@ func (broker *Broker) requestFindCoordinator(clientID, groupID string) (*FindCoo
 	return findCoordinatorResponse, nil
 }
 
+func (broker *Broker) requestInitProducerID(clientID, transactionalID string, transactionTimeoutMS int32) (*InitProducerIDResponse, error) {
+	version := broker.getHighestAvailableAPIVersion(API_InitProducerID)
+	request := NewInitProducerIDRequest(version, clientID, transactionalID, transactionTimeoutMS)
+
+	responseBuf, err := broker.Request(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewInitProducerIDResponse(responseBuf)
+}
+
+// requestFetchStreamingly waits for the pipelined requests to finish, because it reads the connection by itself
 func (broker *Broker) requestFetchStreamingly(fetchRequest *FetchRequest, buffers chan []byte) error {
+	broker.exclusive.Lock()
+	defer broker.exclusive.Unlock()
+
 	broker.mux.Lock()
 	defer broker.mux.Unlock()
 
-	broker.ensureOpen()
+	if err := broker.ensureOpen(); err != nil {
+		// the reader of buffers waits until it is closed
+		close(buffers)
+		return err
+	}
 
 	broker.correlationID++
 	fetchRequest.SetCorrelationID(broker.correlationID)
@@ -326,14 +670,20 @@ func (broker *Broker) requestFetchStreamingly(fetchRequest *FetchRequest, buffer
 	return broker.requestStreamingly(payload, buffers, timeout)
 }
 
-func (broker *Broker) findCoordinator(clientID, groupID string) (*FindCoordinatorResponse, error) {
-	request := NewFindCoordinatorRequest(clientID, groupID)
+// findCoordinator finds the coordinator of the group or transactional id. transaction coordinator needs FindCoordinator v1
+func (broker *Broker) findCoordinator(clientID, key string, coordinatorType int8) (*FindCoordinatorResponse, error) {
+	version := broker.getHighestAvailableAPIVersion(API_FindCoordinator)
+	if coordinatorType != COORDINATOR_TYPE_GROUP && version < 1 {
+		return nil, fmt.Errorf("broker %s does not support FindCoordinator v1 to find transaction coordinator", broker.address)
+	}
+	request := NewFindCoordinatorRequest(version, clientID, key)
+	request.CoordinatorType = coordinatorType
 
 	responseBytes, err := broker.Request(request)
 	if err != nil {
 		return nil, err
 	}
-	return NewFindCoordinatorResponse(responseBytes)
+	return NewFindCoordinatorResponse(responseBytes, version)
 }
 
 func (broker *Broker) requestJoinGroup(clientID, groupID string, sessionTimeoutMS int32, memberID, protocolType string, gps []*GroupProtocol) (*JoinGroupResponse, error) {
@@ -382,3 +732,97 @@ func (broker *Broker) requestHeartbeat(clientID, groupID string, generationID in
 
 	return NewHeartbeatResponse(responseBytes)
 }
+
+func (broker *Broker) requestCreateTopics(clientID string, topics map[string]*TopicDetail, timeoutMS int32, validateOnly bool) (*CreateTopicsResponse, error) {
+	version := broker.getHighestAvailableAPIVersion(API_CreateTopics)
+	if validateOnly && version < 1 {
+		return nil, createTopicsValidateOnlyNotSupported
+	}
+	request := NewCreateTopicsRequest(version, clientID, timeoutMS, validateOnly)
+	for topic, detail := range topics {
+		request.AddTopic(topic, detail)
+	}
+
+	responseBuf, err := broker.Request(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCreateTopicsResponse(responseBuf, version)
+}
+
+func (broker *Broker) requestDeleteTopics(clientID string, topics []string, timeoutMS int32) (*DeleteTopicsResponse, error) {
+	version := broker.getHighestAvailableAPIVersion(API_DeleteTopics)
+	request := NewDeleteTopicsRequest(version, clientID, topics, timeoutMS)
+
+	responseBuf, err := broker.Request(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDeleteTopicsResponse(responseBuf)
+}
+
+func (broker *Broker) requestCreatePartitions(clientID, topic string, count int32, assignment [][]int32, timeoutMS int32, validateOnly bool) (*CreatePartitionsResponse, error) {
+	version := broker.getHighestAvailableAPIVersion(API_CreatePartitions)
+	request := NewCreatePartitionsRequest(version, clientID, timeoutMS, validateOnly)
+	request.AddTopic(topic, count, assignment)
+
+	responseBuf, err := broker.Request(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCreatePartitionsResponse(responseBuf)
+}
+
+func (broker *Broker) requestDescribeConfigs(clientID string, resources []*DescribeConfigsRequestResource) (*DescribeConfigsResponse, error) {
+	version := broker.getHighestAvailableAPIVersion(API_DescribeConfigs)
+	request := NewDescribeConfigsRequest(version, clientID, resources)
+
+	responseBuf, err := broker.Request(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDescribeConfigsResponse(responseBuf, version)
+}
+
+func (broker *Broker) requestAlterConfigs(clientID string, resources []*AlterConfigsRequestResource, validateOnly bool) (*AlterConfigsResponse, error) {
+	version := broker.getHighestAvailableAPIVersion(API_AlterConfigs)
+	request := NewAlterConfigsRequest(version, clientID, resources, validateOnly)
+
+	responseBuf, err := broker.Request(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAlterConfigsResponse(responseBuf)
+}
+
+func (broker *Broker) requestIncrementalAlterConfigs(clientID string, resources []*IncrementalAlterConfigsRequestResource, validateOnly bool) (*IncrementalAlterConfigsResponse, error) {
+	version := broker.getHighestAvailableAPIVersion(API_IncrementalAlterConfigs)
+	request := NewIncrementalAlterConfigsRequest(version, clientID, resources, validateOnly)
+
+	responseBuf, err := broker.Request(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewIncrementalAlterConfigsResponse(responseBuf)
+}
+
+func (broker *Broker) requestDeleteRecords(clientID, topic string, offsets map[int32]int64, timeoutMS int32) (*DeleteRecordsResponse, error) {
+	version := broker.getHighestAvailableAPIVersion(API_DeleteRecords)
+	request := NewDeleteRecordsRequest(version, clientID, timeoutMS)
+	for partitionID, offset := range offsets {
+		request.AddPartition(topic, partitionID, offset)
+	}
+
+	responseBuf, err := broker.Request(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDeleteRecordsResponse(responseBuf)
+}
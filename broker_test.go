@@ -2,6 +2,8 @@ package healer
 
 import (
 	"flag"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -70,3 +72,168 @@ func TestRequestApiVersions(t *testing.T) {
 		t.Logf("broker %s apiKey is %d, minVersion is %d, maxVersion is %d", *brokerAddress, ApiVersion.apiKey, ApiVersion.minVersion, ApiVersion.maxVersion)
 	}
 }
+
+func TestGetHighestAvailableAPIVersion(t *testing.T) {
+	broker := &Broker{}
+	if v := broker.getHighestAvailableAPIVersion(API_FetchRequest); v != 0 {
+		t.Errorf("version should be 0 if api versions are unknown, got %d", v)
+	}
+
+	broker.apiVersions = []*ApiVersion{
+		&ApiVersion{apiKey: int16(API_FetchRequest), minVersion: 0, maxVersion: 10},
+		&ApiVersion{apiKey: int16(API_ProduceRequest), minVersion: 0, maxVersion: 2},
+		&ApiVersion{apiKey: int16(API_MetadataRequest), minVersion: 0, maxVersion: 5},
+	}
+	if v := broker.getHighestAvailableAPIVersion(API_FetchRequest); v != 10 {
+		t.Errorf("fetch version should be 10, got %d", v)
+	}
+	if v := broker.getHighestAvailableAPIVersion(API_ProduceRequest); v != 0 {
+		t.Errorf("produce version should be 0, got %d", v)
+	}
+	if v := broker.getHighestAvailableAPIVersion(API_MetadataRequest); v != 1 {
+		t.Errorf("metadata version should be 1, got %d", v)
+	}
+
+	// the highest version below the max of the broker is picked
+	broker.apiVersions = []*ApiVersion{
+		&ApiVersion{apiKey: int16(API_FetchRequest), minVersion: 0, maxVersion: 9},
+	}
+	if v := broker.getHighestAvailableAPIVersion(API_FetchRequest); v != 4 {
+		t.Errorf("fetch version should be 4 if the broker supports up to 9, got %d", v)
+	}
+}
+
+// pipelinedBroker answers ApiVersions at once, and then reads batch requests before answering them in order.
+// the body of each response is the client id of its request. the correlation id is wrong in all responses if badCorrelationID is true
+type pipelinedBroker struct {
+	batch            int
+	badCorrelationID bool
+}
+
+func (b *pipelinedBroker) serve(conn *fakeConn) {
+	request := conn.readRequest()
+	if request == nil || conn.writeResponse(request.correlationID, make([]byte, 6)) != nil {
+		return
+	}
+	for {
+		requests := make([]*fakeRequest, 0, b.batch)
+		for len(requests) < b.batch {
+			request := conn.readRequest()
+			if request == nil {
+				return
+			}
+			requests = append(requests, request)
+		}
+		for _, request := range requests {
+			correlationID := request.correlationID
+			if b.badCorrelationID {
+				correlationID = 0
+			}
+			if conn.writeResponse(correlationID, []byte(request.clientID)) != nil {
+				return
+			}
+		}
+	}
+}
+
+func newPipelinedBroker(t *testing.T, fakeBroker *pipelinedBroker, maxInFlight int) (*Broker, func()) {
+	server := newFakeBrokerWithConn(t, fakeBroker.serve)
+	config := DefaultBrokerConfig()
+	config.TimeoutMS = 5000
+	config.MaxInFlightRequestsPerConnection = maxInFlight
+	broker := server.connect(t, config)
+	return broker, func() {
+		broker.Close()
+		server.close()
+	}
+}
+
+func TestPipelinedRequests(t *testing.T) {
+	// the fake broker answers only after 3 requests are written, so they must be in flight together
+	broker, cleanup := newPipelinedBroker(t, &pipelinedBroker{batch: 3}, 3)
+	defer cleanup()
+
+	var wg sync.WaitGroup
+	for _, clientID := range []string{"client-a", "client-b", "client-c"} {
+		wg.Add(1)
+		go func(clientID string) {
+			defer wg.Done()
+			response, err := broker.Request(NewApiVersionsRequest(0, clientID))
+			if err != nil {
+				t.Errorf("request of %s error: %s", clientID, err)
+				return
+			}
+			if got := string(response[8:]); got != clientID {
+				t.Errorf("expect the response of %s, got %s", clientID, got)
+			}
+		}(clientID)
+	}
+	wg.Wait()
+}
+
+func TestRequestWithoutAcks(t *testing.T) {
+	// produce requests are never answered, like a real broker does with acks=0
+	produced := make(chan *fakeRequest, 2)
+	server := newFakeBroker(t, func(request *fakeRequest) []byte {
+		if request.apiKey == API_ProduceRequest {
+			produced <- request
+			return []byte{}
+		}
+		return fakeApiVersionsResponse(map[uint16]uint16{API_ProduceRequest: 3})
+	})
+	defer server.close()
+	broker := server.connect(t, nil)
+	defer broker.Close()
+
+	for i := 0; i < 2; i++ {
+		request := newProduceTask("test", 0, 10).request
+		request.RequiredAcks = 0
+		if response, err := broker.Request(request); response != nil || err != nil {
+			t.Fatalf("expect nil response of acks=0 once written, got %v %v", response, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		<-produced
+	}
+
+	// nothing is pending, so the next response goes to the next request
+	if _, err := broker.Request(NewApiVersionsRequest(0, "healer")); err != nil {
+		t.Errorf("request after acks=0 error: %s", err)
+	}
+}
+
+func TestPipelinedRequestsCorrelationIDMismatch(t *testing.T) {
+	broker, cleanup := newPipelinedBroker(t, &pipelinedBroker{batch: 2, badCorrelationID: true}, 2)
+	defer cleanup()
+
+	var wg sync.WaitGroup
+	for _, clientID := range []string{"client-a", "client-b"} {
+		wg.Add(1)
+		go func(clientID string) {
+			defer wg.Done()
+			if _, err := broker.Request(NewApiVersionsRequest(0, clientID)); err == nil {
+				t.Errorf("expect error of mismatched correlation id for %s", clientID)
+			}
+		}(clientID)
+	}
+	wg.Wait()
+	if !broker.IsDead() {
+		t.Error("broker should be closed after mismatched correlation id")
+	}
+}
+
+func TestNewBrokerInvalidConfig(t *testing.T) {
+	config := DefaultBrokerConfig()
+	config.SASL = SASLConfig{Mechanism: "UNKNOWN", User: "user"}
+	// nothing listens on the address, so the error must come from the config check before dialing
+	if _, err := NewBroker("127.0.0.1:1", -1, config); err == nil || !strings.HasPrefix(err.Error(), "broker config error") {
+		t.Errorf("expect broker config error of unknown sasl mechanism, got %v", err)
+	}
+
+	config = DefaultBrokerConfig()
+	config.TLSEnabled = true
+	config.TLS.CA = "/nonexistent/ca.pem"
+	if _, err := NewBrokers("127.0.0.1:1", "healer", config); err == nil || !strings.HasPrefix(err.Error(), "broker config error") {
+		t.Errorf("expect broker config error of missing ca file, got %v", err)
+	}
+}
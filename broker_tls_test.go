@@ -0,0 +1,121 @@
+package healer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert writes a self signed cert/key for 127.0.0.1 into dir, and returns the file paths
+func writeSelfSignedCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "healer-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:              []string{"localhost"},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSBroker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "healer-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeSelfSignedCert(t, dir)
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := tls.Listen("tcp4", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// every request is answered with an empty ApiVersions response
+			go func() {
+				defer conn.Close()
+				(&fakeConn{conn}).serve(func(request *fakeRequest) []byte {
+					return make([]byte, 6)
+				})
+			}()
+		}
+	}()
+
+	config := DefaultBrokerConfig()
+	config.ConnectTimeoutMS = 5000
+	config.TimeoutMS = 5000
+	config.TLSEnabled = true
+
+	// self signed cert could not be verified without ca
+	if _, err := NewBroker(listener.Addr().String(), -1, config); err == nil {
+		t.Error("it should not connect to tls server without ca")
+	}
+
+	config.TLS.CA = certFile
+	broker, err := NewBroker(listener.Addr().String(), -1, config)
+	if err != nil {
+		t.Fatalf("connect to tls server error: %s", err)
+	}
+	if _, ok := broker.conn.(*tls.Conn); !ok {
+		t.Error("connection should be tls")
+	}
+	if _, err := broker.requestApiVersions("healer"); err != nil {
+		t.Errorf("request api versions over tls error: %s", err)
+	}
+	broker.Close()
+
+	config.TLS.CA = ""
+	config.TLS.InsecureSkipVerify = true
+	broker, err = NewBroker(listener.Addr().String(), -1, config)
+	if err != nil {
+		t.Fatalf("connect to tls server with insecure.skip.verify error: %s", err)
+	}
+	broker.Close()
+}
@@ -58,6 +58,14 @@ func newBrokersFromOne(broker *Broker, clientID string, config *BrokerConfig) (*
 }
 
 func NewBrokers(bootstrapServers string, clientID string, config *BrokerConfig) (*Brokers, error) {
+	if err := config.checkValid(); err != nil {
+		return nil, fmt.Errorf("broker config error: %s", err)
+	}
+	if config.ClientID == "" {
+		c := *config
+		c.ClientID = clientID
+		config = &c
+	}
 	for _, brokerAddr := range strings.Split(bootstrapServers, ",") {
 		broker, err := NewBroker(brokerAddr, -1, config)
 
@@ -245,7 +253,9 @@ func (brokers *Brokers) RequestMetaData(clientID string, topics []string) (*Meta
 	return metadataResponse, err
 }
 
-// RequestOffsets return the offset values array. return all partitions if partitionID < 0
+// RequestOffsets return the offset values array. return all partitions if partitionID < 0.
+// if offsets is 1 and the leader supports ListOffsets v1, the offset is the first one whose timestamp is at or after timeValue,
+// and PartitionOffset.Timestamp is the timestamp of that record
 func (brokers *Brokers) RequestOffsets(clientID, topic string, partitionID int32, timeValue int64, offsets uint32) ([]*OffsetsResponse, error) {
 	// have to find which leader own the partition by request metadata
 	// TODO cache
@@ -318,6 +328,64 @@ func (brokers *Brokers) findLeader(clientID, topic string, partitionID int32) (i
 	return -1, fmt.Errorf("could not find out leader of topic %s", topic)
 }
 
+// DeleteRecords deletes the records before the offsets (partitionID -> offset) of the topic, -1 means the high watermark.
+// each partition is sent to its leader. it returns the new low watermarks of the partitions. if some partitions fail,
+// the low watermarks of the others are returned together with the first error
+func (brokers *Brokers) DeleteRecords(clientID, topic string, offsets map[int32]int64, timeoutMS int32) (map[int32]int64, error) {
+	var firstErr error
+	leaderOffsets := make(map[int32]map[int32]int64) // leader: partitionID: offset
+	for partitionID, offset := range offsets {
+		leaderID, err := brokers.findLeader(clientID, topic, partitionID)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if _, ok := leaderOffsets[leaderID]; !ok {
+			leaderOffsets[leaderID] = make(map[int32]int64)
+		}
+		leaderOffsets[leaderID][partitionID] = offset
+	}
+
+	lowWatermarks := make(map[int32]int64)
+	for leaderID, partitionOffsets := range leaderOffsets {
+		leader, err := brokers.GetBroker(leaderID)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("could not get leader %d of %s: %s", leaderID, topic, err)
+			}
+			continue
+		}
+		// the response comes with the error of the first failed partition
+		response, err := leader.requestDeleteRecords(clientID, topic, partitionOffsets, timeoutMS)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if response == nil {
+			continue
+		}
+		for _, p := range response.Topics[topic] {
+			if p.ErrorCode == 0 {
+				lowWatermarks[p.PartitionIndex] = p.LowWatermark
+			}
+		}
+	}
+	return lowWatermarks, firstErr
+}
+
+// getController returns the controller broker, which topic administration requests must be sent to. it needs metadata v1+
+func (brokers *Brokers) getController(clientID string) (*Broker, error) {
+	metadataResponse, err := brokers.RequestMetaData(clientID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not get metadata: %s", err)
+	}
+	if metadataResponse.ControllerID < 0 {
+		return nil, fmt.Errorf("could not find out controller, metadata v1+ is needed")
+	}
+	return brokers.GetBroker(metadataResponse.ControllerID)
+}
+
 //func (brokers *Brokers) RequestListGroups(clientID string) (*ListGroupsResponse, error) {
 //for _, brokerInfo := range brokers.brokersInfo {
 //broker, err := brokers.GetBroker(brokerInfo.NodeId)
@@ -336,13 +404,22 @@ func (brokers *Brokers) findLeader(clientID, topic string, partitionID int32) (i
 //}
 
 func (brokers *Brokers) FindCoordinator(clientID, groupID string) (*FindCoordinatorResponse, error) {
+	return brokers.findCoordinator(clientID, groupID, COORDINATOR_TYPE_GROUP)
+}
+
+// FindTransactionCoordinator finds the coordinator of the transactional id. it needs kafka 0.11.0+
+func (brokers *Brokers) FindTransactionCoordinator(clientID, transactionalID string) (*FindCoordinatorResponse, error) {
+	return brokers.findCoordinator(clientID, transactionalID, COORDINATOR_TYPE_TRANSACTION)
+}
+
+func (brokers *Brokers) findCoordinator(clientID, key string, coordinatorType int8) (*FindCoordinatorResponse, error) {
 	for _, brokerInfo := range brokers.brokersInfo {
 		broker, err := brokers.GetBroker(brokerInfo.NodeId)
 		if err != nil {
 			glog.Errorf("get broker[%d] error:%s", brokerInfo.NodeId, err)
 			continue
 		}
-		response, err := broker.findCoordinator(clientID, groupID)
+		response, err := broker.findCoordinator(clientID, key, coordinatorType)
 		if err != nil {
 			glog.Errorf("could not find coordinator from %s:%s", broker.address, err)
 		} else {
@@ -353,6 +430,25 @@ func (brokers *Brokers) FindCoordinator(clientID, groupID string) (*FindCoordina
 	return nil, fmt.Errorf("could not find coordinator from all brokers")
 }
 
+// InitProducerID gets a producer id and epoch for idempotent producer from any broker
+func (brokers *Brokers) InitProducerID(clientID string) (*InitProducerIDResponse, error) {
+	for _, brokerInfo := range brokers.brokersInfo {
+		broker, err := brokers.GetBroker(brokerInfo.NodeId)
+		if err != nil {
+			glog.Errorf("get broker[%d] error:%s", brokerInfo.NodeId, err)
+			continue
+		}
+		response, err := broker.requestInitProducerID(clientID, "", 0)
+		if err != nil {
+			glog.Errorf("could not init producer id from %s:%s", broker.address, err)
+		} else {
+			return response, nil
+		}
+	}
+
+	return nil, fmt.Errorf("could not init producer id from all brokers")
+}
+
 func (brokers *Brokers) RequestDescribeGroups(clientID string, groups []string) (*DescribeGroupsResponse, error) {
 	for _, brokerInfo := range brokers.brokersInfo {
 		broker, err := brokers.GetBroker(brokerInfo.NodeId)
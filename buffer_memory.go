@@ -0,0 +1,120 @@
+package healer
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var bufferExhaustedError = errors.New("buffer.memory is exhausted and no space is released in max.block.ms")
+
+// BufferMetrics tells the usage of buffer.memory
+type BufferMetrics struct {
+	TotalBytes     int64
+	AvailableBytes int64
+	// Waiters is the count of AddMessage calls blocked for space now
+	Waiters int
+	// ExhaustedTotal is the count of messages rejected because no space is released in max.block.ms
+	ExhaustedTotal int64
+	// BlockedTime is the total time AddMessage calls have been blocked for space
+	BlockedTime time.Duration
+}
+
+// bufferMemory is the budget of the buffered messages, shared by all SimpleProducers of a Producer.
+// the space of a message is allocated in AddMessage, and released after the batch of it is sent or fails
+type bufferMemory struct {
+	total    int64
+	maxBlock time.Duration
+
+	mutex     sync.Mutex
+	used      int64
+	released  chan struct{} // closed and replaced on each release to wake up the waiters
+	waiters   int
+	exhausted int64
+	blocked   time.Duration
+}
+
+func newBufferMemory(config *ProducerConfig) *bufferMemory {
+	return &bufferMemory{
+		total:    config.BufferMemory,
+		maxBlock: time.Duration(config.MaxBlockMS) * time.Millisecond,
+		released: make(chan struct{}),
+	}
+}
+
+// allocate blocks until size bytes are available or max.block.ms passes
+func (m *bufferMemory) allocate(size int) error {
+	if int64(size) > m.total {
+		return AllError[10]
+	}
+
+	var (
+		start time.Time
+		timer *time.Timer
+	)
+	m.mutex.Lock()
+	for m.used+int64(size) > m.total {
+		if timer == nil {
+			if m.maxBlock <= 0 {
+				m.exhausted++
+				m.mutex.Unlock()
+				return bufferExhaustedError
+			}
+			start = time.Now()
+			timer = time.NewTimer(m.maxBlock)
+			defer timer.Stop()
+		}
+
+		released := m.released
+		m.waiters++
+		m.mutex.Unlock()
+
+		var timeout bool
+		select {
+		case <-released:
+		case <-timer.C:
+			timeout = true
+		}
+
+		m.mutex.Lock()
+		m.waiters--
+		if timeout && m.used+int64(size) > m.total {
+			m.exhausted++
+			m.blocked += time.Since(start)
+			m.mutex.Unlock()
+			return bufferExhaustedError
+		}
+	}
+	m.used += int64(size)
+	if timer != nil {
+		m.blocked += time.Since(start)
+	}
+	m.mutex.Unlock()
+	return nil
+}
+
+// release gives back the space of the messages, and wakes up the waiters
+func (m *bufferMemory) release(size int) {
+	if size == 0 {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.used -= int64(size)
+	close(m.released)
+	m.released = make(chan struct{})
+}
+
+func (m *bufferMemory) metrics() BufferMetrics {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return BufferMetrics{
+		TotalBytes:     m.total,
+		AvailableBytes: m.total - m.used,
+		Waiters:        m.waiters,
+		ExhaustedTotal: m.exhausted,
+		BlockedTime:    m.blocked,
+	}
+}
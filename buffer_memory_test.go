@@ -0,0 +1,115 @@
+package healer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBufferMemoryAllocate(t *testing.T) {
+	config := DefaultProducerConfig()
+	config.BufferMemory = 100
+	config.MaxBlockMS = 0
+	m := newBufferMemory(config)
+
+	if err := m.allocate(101); err != AllError[10] {
+		t.Errorf("expect MESSAGE_TOO_LARGE for message larger than buffer.memory, got %v", err)
+	}
+	if err := m.allocate(80); err != nil {
+		t.Fatalf("allocate error: %s", err)
+	}
+	if err := m.allocate(30); err != bufferExhaustedError {
+		t.Errorf("expect bufferExhaustedError without blocking, got %v", err)
+	}
+	m.release(80)
+	if err := m.allocate(30); err != nil {
+		t.Errorf("allocate after release error: %s", err)
+	}
+
+	metrics := m.metrics()
+	if metrics.TotalBytes != 100 || metrics.AvailableBytes != 70 || metrics.ExhaustedTotal != 1 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+}
+
+func TestBufferMemoryBlock(t *testing.T) {
+	config := DefaultProducerConfig()
+	config.BufferMemory = 100
+	config.MaxBlockMS = 5000
+	m := newBufferMemory(config)
+
+	if err := m.allocate(80); err != nil {
+		t.Fatalf("allocate error: %s", err)
+	}
+	allocated := make(chan error, 1)
+	go func() {
+		allocated <- m.allocate(50)
+	}()
+
+	for i := 0; m.metrics().Waiters != 1; i++ {
+		if i > 100 {
+			t.Fatal("allocate should be blocked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.release(80)
+
+	select {
+	case err := <-allocated:
+		if err != nil {
+			t.Errorf("blocked allocate error: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocked allocate is not woken up by release")
+	}
+	if metrics := m.metrics(); metrics.Waiters != 0 || metrics.AvailableBytes != 50 || metrics.BlockedTime <= 0 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+}
+
+func TestBufferMemoryTimeout(t *testing.T) {
+	config := DefaultProducerConfig()
+	config.BufferMemory = 100
+	config.MaxBlockMS = 20
+	m := newBufferMemory(config)
+
+	m.allocate(100)
+	start := time.Now()
+	if err := m.allocate(1); err != bufferExhaustedError {
+		t.Errorf("expect bufferExhaustedError after max.block.ms, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("allocate should block for max.block.ms, returned after %s", elapsed)
+	}
+	if metrics := m.metrics(); metrics.ExhaustedTotal != 1 || metrics.Waiters != 0 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+}
+
+func TestSimpleProducerBufferMemory(t *testing.T) {
+	config := DefaultProducerConfig()
+	config.BufferMemory = 300
+	config.MaxBlockMS = 0
+	config.LingerMS = 3600000
+	fakeBroker := &fakeProduceBroker{baseOffset: 100, logAppendTime: -1}
+	p, cleanup := newFakeProduceSimpleProducer(t, fakeBroker, config)
+	defer cleanup()
+
+	// each message is estimated as 136 bytes
+	for i := 0; i < 2; i++ {
+		if err := p.AddMessage(nil, make([]byte, 100)); err != nil {
+			t.Fatalf("add message error: %s", err)
+		}
+	}
+	if err := p.AddMessage(nil, make([]byte, 100)); err != bufferExhaustedError {
+		t.Errorf("expect bufferExhaustedError, got %v", err)
+	}
+	if err := p.Flush(); err != nil {
+		t.Fatalf("flush error: %s", err)
+	}
+	if metrics := p.BufferMetrics(); metrics.AvailableBytes != 300 {
+		t.Errorf("expect all space released after flush, got %+v", metrics)
+	}
+	if err := p.AddMessage(nil, make([]byte, 100)); err != nil {
+		t.Errorf("add message after flush error: %s", err)
+	}
+}
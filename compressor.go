@@ -1,19 +1,168 @@
 package healer
 
+import (
+	"fmt"
+	"sync"
+)
+
 type Compressor interface {
 	Compress([]byte) ([]byte, error)
 }
 
+type Decompressor interface {
+	Decompress([]byte) ([]byte, error)
+}
+
+// Codec compresses the messages in producer and decompresses them in consumer.
+// it must be safe for concurrent use
+type Codec interface {
+	Compressor
+	Decompressor
+}
+
+// AppendCompressor is implemented by the builtin codecs which could compress into a reused buffer.
+// AppendCompress appends the compressed src to dst and returns the extended buffer, like append does.
+// RecordBatch.Encode prefers it to Compress only for the builtin codecs, see builtinAppendCompressor
+type AppendCompressor interface {
+	AppendCompress(dst, src []byte) ([]byte, error)
+}
+
+// builtinAppendCompressor returns the compressor as AppendCompressor if it is a builtin codec.
+// a registered codec is always called by Compress, even if it embeds a builtin one and gets AppendCompress promoted
+func builtinAppendCompressor(compressor Compressor) (AppendCompressor, bool) {
+	switch c := compressor.(type) {
+	case *GzipCompressor:
+		return c, true
+	case *SnappyCompressor:
+		return c, true
+	case *LZ4Compressor:
+		return c, true
+	case *ZstdCompressor:
+		return c, true
+	}
+	return nil, false
+}
+
+// LeveledCodec is implemented by the codecs supporting compression.level.
+// WithLevel returns a codec compressing in the level, or error if the level is out of range of the codec
+type LeveledCodec interface {
+	Codec
+	WithLevel(level int) (Codec, error)
+}
+
+type registeredCodec struct {
+	id    int8
+	codec Codec
+}
+
+var (
+	codecsMutex  sync.RWMutex
+	codecsByName = make(map[string]*registeredCodec)
+	codecsByID   = make(map[int8]Codec)
+)
+
+func init() {
+	RegisterCodec("none", COMPRESSION_NONE, &NoneCompressor{})
+	RegisterCodec("gzip", COMPRESSION_GZIP, &GzipCompressor{})
+	RegisterCodec("snappy", COMPRESSION_SNAPPY, &SnappyCompressor{})
+	RegisterCodec("lz4", COMPRESSION_LZ4, &LZ4Compressor{})
+	RegisterCodec("zstd", COMPRESSION_ZSTD, &ZstdCompressor{})
+}
+
+// RegisterCodec registers the codec with the name used in compression.type and the id in the compression bits of attributes.
+// it replaces the codec registered before with the same name or id, so applications could use a faster implementation of the builtin codecs.
+// it should be called before any producer or consumer is created
+func RegisterCodec(name string, id int8, codec Codec) {
+	if id < 0 || id > 7 {
+		panic(fmt.Sprintf("codec id %d of %s is out of range [0, 7]", id, name))
+	}
+
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+
+	for n, c := range codecsByName {
+		if c.id == id && n != name {
+			delete(codecsByName, n)
+		}
+	}
+	codecsByName[name] = &registeredCodec{id, codec}
+	codecsByID[id] = codec
+}
+
+// getLeveledCodec returns the codec registered with the name in the compression level. level 0 means the default level of the codec
+func getLeveledCodec(name string, level int) (int8, Codec, error) {
+	id, codec, ok := getCodec(name)
+	if !ok {
+		return 0, nil, unknownCompressionType
+	}
+	if level == 0 {
+		return id, codec, nil
+	}
+	leveled, ok := codec.(LeveledCodec)
+	if !ok {
+		return 0, nil, fmt.Errorf("compression.level is not supported by %s", name)
+	}
+	codec, err := leveled.WithLevel(level)
+	if err != nil {
+		return 0, nil, err
+	}
+	return id, codec, nil
+}
+
+// getCodec returns the id and the codec registered with the name
+func getCodec(name string) (int8, Codec, bool) {
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+
+	if c, ok := codecsByName[name]; ok {
+		return c.id, c.codec, true
+	}
+	return 0, nil, false
+}
+
+func getCodecByID(id int8) (Codec, bool) {
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+
+	codec, ok := codecsByID[id]
+	return codec, ok
+}
+
+// NewCompressor returns the codec registered with the name, or nil if not registered
 func NewCompressor(cType string) Compressor {
-	switch cType {
-	case "gzip":
-		return &GzipCompressor{}
-	case "lz4":
-		return &LZ4Compressor{}
-	case "snappy":
-		return &SnappyCompressor{}
-	case "none":
-		return &NoneCompressor{}
+	if _, codec, ok := getCodec(cType); ok {
+		return codec
 	}
 	return nil
 }
+
+// bufferPool holds the buffers of the encoded records, which are copied into the request once encoded
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
+// getBuffer returns a buffer from the pool whose capacity is at least size. call putBuffer after use
+func getBuffer(size int) *[]byte {
+	buf := bufferPool.Get().(*[]byte)
+	if cap(*buf) < size {
+		*buf = make([]byte, size)
+	}
+	*buf = (*buf)[:size]
+	return buf
+}
+
+func putBuffer(buf *[]byte) {
+	bufferPool.Put(buf)
+}
+
+// growBuffer makes sure that there are n bytes of spare capacity after len(buf)
+func growBuffer(buf []byte, n int) []byte {
+	if cap(buf)-len(buf) >= n {
+		return buf
+	}
+	grown := make([]byte, len(buf), 2*cap(buf)+n)
+	copy(grown, buf)
+	return grown
+}
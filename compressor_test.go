@@ -0,0 +1,226 @@
+package healer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+)
+
+// countingCodec wraps the builtin gzip codec and counts the calls. AppendCompress of GzipCompressor is promoted,
+// but it must never be used in place of Compress
+type countingCodec struct {
+	GzipCompressor
+	compressed   int
+	decompressed int
+}
+
+func (c *countingCodec) Compress(value []byte) ([]byte, error) {
+	c.compressed++
+	return c.GzipCompressor.Compress(value)
+}
+
+func (c *countingCodec) Decompress(value []byte) ([]byte, error) {
+	c.decompressed++
+	return c.GzipCompressor.Decompress(value)
+}
+
+func TestRegisterCodec(t *testing.T) {
+	codec := &countingCodec{}
+	RegisterCodec("counting-gzip", COMPRESSION_GZIP, codec)
+	defer RegisterCodec("gzip", COMPRESSION_GZIP, &GzipCompressor{})
+
+	if _, _, ok := getCodec("gzip"); ok {
+		t.Error("gzip should be replaced by counting-gzip with the same id")
+	}
+	id, _, ok := getCodec("counting-gzip")
+	if !ok || id != COMPRESSION_GZIP {
+		t.Fatalf("expect counting-gzip registered with id %d, got %d %v", COMPRESSION_GZIP, id, ok)
+	}
+	config := DefaultProducerConfig()
+	config.BootstrapServers = "127.0.0.1:9092"
+	config.CompressionType = "counting-gzip"
+	if err := config.checkValid(); err != nil {
+		t.Errorf("registered codec should be valid in producer config: %s", err)
+	}
+
+	batch := &RecordBatch{
+		PartitionLeaderEpoch: -1,
+		Magic:                2,
+		Attributes:           int16(id),
+		ProducerID:           -1,
+		ProducerEpoch:        -1,
+		BaseSequence:         -1,
+		Records:              []*Record{{Value: []byte("hello")}},
+	}
+	payload, err := batch.Encode(NewCompressor("counting-gzip"))
+	if err != nil {
+		t.Fatalf("encode record batch error: %s", err)
+	}
+	decoded, err := DecodeToRecordBatch(payload)
+	if err != nil {
+		t.Fatalf("decode record batch error: %s", err)
+	}
+	if len(decoded.Records) != 1 || string(decoded.Records[0].Value) != "hello" {
+		t.Errorf("unexpected records after decode")
+	}
+	if codec.compressed != 1 || codec.decompressed != 1 {
+		t.Errorf("expect the registered codec to compress and decompress once, got %d and %d", codec.compressed, codec.decompressed)
+	}
+}
+
+func TestBuiltinCodecs(t *testing.T) {
+	value := []byte("healer healer healer healer healer")
+	for _, name := range []string{"none", "gzip", "snappy", "lz4", "zstd"} {
+		id, codec, ok := getCodec(name)
+		if !ok {
+			t.Fatalf("%s is not registered", name)
+		}
+		compressed, err := codec.Compress(value)
+		if err != nil {
+			t.Fatalf("%s compress error: %s", name, err)
+		}
+		decompressed, err := decompress(id, compressed)
+		if err != nil {
+			t.Fatalf("%s decompress error: %s", name, err)
+		}
+		if string(decompressed) != string(value) {
+			t.Errorf("%s: expect %s, got %s", name, value, decompressed)
+		}
+	}
+}
+
+func TestCompressionLevel(t *testing.T) {
+	value := bytes.Repeat([]byte("healer "), 100)
+	for _, c := range []struct {
+		name  string
+		level int
+		valid bool
+	}{
+		{"gzip", 0, true},
+		{"gzip", 1, true},
+		{"gzip", 9, true},
+		{"gzip", 10, false},
+		{"zstd", 1, true},
+		{"zstd", 19, true},
+		{"zstd", 23, false},
+		{"lz4", 1, false},
+		{"snappy", 1, false},
+	} {
+		config := DefaultProducerConfig()
+		config.BootstrapServers = "127.0.0.1:9092"
+		config.CompressionType = c.name
+		config.CompressionLevel = c.level
+		err := config.checkValid()
+		if c.valid != (err == nil) {
+			t.Errorf("%s level %d: expect valid %v, got error %v", c.name, c.level, c.valid, err)
+		}
+		if !c.valid {
+			continue
+		}
+
+		_, codec, err := getLeveledCodec(c.name, c.level)
+		if err != nil {
+			t.Fatalf("get %s codec of level %d error: %s", c.name, c.level, err)
+		}
+		compressed, err := codec.Compress(value)
+		if err != nil {
+			t.Fatalf("%s level %d compress error: %s", c.name, c.level, err)
+		}
+		decompressed, err := codec.Decompress(compressed)
+		if err != nil {
+			t.Fatalf("%s level %d decompress error: %s", c.name, c.level, err)
+		}
+		if !bytes.Equal(decompressed, value) {
+			t.Errorf("%s level %d: value changed after compress and decompress", c.name, c.level)
+		}
+	}
+}
+
+func TestAppendCompress(t *testing.T) {
+	value := bytes.Repeat([]byte("healer "), 100)
+	for _, name := range []string{"gzip", "snappy", "lz4", "zstd"} {
+		_, codec, _ := getCodec(name)
+		c, ok := codec.(AppendCompressor)
+		if !ok {
+			t.Fatalf("%s should implement AppendCompressor", name)
+		}
+		compressed, err := codec.Compress(value)
+		if err != nil {
+			t.Fatalf("%s compress error: %s", name, err)
+		}
+
+		dst := make([]byte, 3, 1024)
+		copy(dst, "abc")
+		appended, err := c.AppendCompress(dst, value)
+		if err != nil {
+			t.Fatalf("%s append compress error: %s", name, err)
+		}
+		if string(appended[:3]) != "abc" || !bytes.Equal(appended[3:], compressed) {
+			t.Errorf("%s: append compress should append the same output as compress", name)
+		}
+	}
+}
+
+// unpooledGzipCompressor is how GzipCompressor compressed before the writers were pooled. it is kept to compare in benchmarks
+type unpooledGzipCompressor struct {
+}
+
+func (c *unpooledGzipCompressor) Compress(value []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(value); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// BenchmarkFlushEncode encodes a batch of 1000 messages as SimpleProducer does in each flush.
+// run it with -benchmem to compare the allocations per flush of gzip-unpooled and gzip
+func BenchmarkFlushEncode(b *testing.B) {
+	codecs := []struct {
+		name  string
+		id    int8
+		codec Compressor
+	}{
+		{"none", COMPRESSION_NONE, &NoneCompressor{}},
+		{"gzip-unpooled", COMPRESSION_GZIP, &unpooledGzipCompressor{}},
+		{"gzip", COMPRESSION_GZIP, &GzipCompressor{}},
+		{"snappy", COMPRESSION_SNAPPY, &SnappyCompressor{}},
+		{"lz4", COMPRESSION_LZ4, &LZ4Compressor{}},
+		{"zstd", COMPRESSION_ZSTD, &ZstdCompressor{}},
+	}
+
+	records := make([]*Record, 1000)
+	for i := range records {
+		records[i] = &Record{
+			OffsetDelta: int32(i),
+			Key:         []byte(fmt.Sprintf("key-%d", i)),
+			Value:       []byte(fmt.Sprintf(`{"id":%d,"message":"healer is a kafka client in golang"}`, i)),
+		}
+	}
+
+	for _, c := range codecs {
+		b.Run(c.name, func(b *testing.B) {
+			batch := &RecordBatch{
+				PartitionLeaderEpoch: -1,
+				Magic:                2,
+				Attributes:           int16(c.id),
+				LastOffsetDelta:      int32(len(records) - 1),
+				ProducerID:           -1,
+				ProducerEpoch:        -1,
+				BaseSequence:         -1,
+				Records:              records,
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := batch.Encode(c.codec); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,15 +1,65 @@
 package healer
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
 )
 
+// TLSConfig is used when tls.enabled is true. all files are in PEM format
+type TLSConfig struct {
+	Cert               string `json:"cert"`
+	Key                string `json:"key"`
+	CA                 string `json:"ca"`
+	InsecureSkipVerify bool   `json:"insecure.skip.verify"`
+	ServerName         string `json:"servername"`
+}
+
+func createTLSConfig(tlsConfig *TLSConfig) (*tls.Config, error) {
+	rst := &tls.Config{
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify,
+		ServerName:         tlsConfig.ServerName,
+	}
+
+	if tlsConfig.Cert != "" || tlsConfig.Key != "" {
+		cert, err := tls.LoadX509KeyPair(tlsConfig.Cert, tlsConfig.Key)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client cert/key: %s", err)
+		}
+		rst.Certificates = []tls.Certificate{cert}
+	}
+
+	if tlsConfig.CA != "" {
+		caCert, err := ioutil.ReadFile(tlsConfig.CA)
+		if err != nil {
+			return nil, fmt.Errorf("could not read ca file: %s", err)
+		}
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificate found in ca file %s", tlsConfig.CA)
+		}
+		rst.RootCAs = caCertPool
+	}
+
+	return rst, nil
+}
+
 type BrokerConfig struct {
-	ConnectTimeoutMS          int   `json:"connect.timeout.ms"`
-	TimeoutMS                 int   `json:"timeout.ms"`
-	TimeoutMSForEachAPI       []int `json:"timeout.ms.for.eachapi"`
-	MetadataRefreshIntervalMS int   `json:"metadata.refresh.interval.ms"`
+	ClientID                  string     `json:"client.id"` // client id of the requests sent while connecting, such as ApiVersions and sasl
+	ConnectTimeoutMS          int        `json:"connect.timeout.ms"`
+	TimeoutMS                 int        `json:"timeout.ms"`
+	TimeoutMSForEachAPI       []int      `json:"timeout.ms.for.eachapi"`
+	MetadataRefreshIntervalMS int        `json:"metadata.refresh.interval.ms"`
+	TLSEnabled                bool       `json:"tls.enabled"`
+	TLS                       TLSConfig  `json:"tls"`
+	SASL                      SASLConfig `json:"sasl"`
+
+	// MaxInFlightRequestsPerConnection is how many requests could be written to the connection before their responses are read
+	MaxInFlightRequestsPerConnection int `json:"max.in.flight.requests.per.connection"`
 }
 
 func DefaultBrokerConfig() *BrokerConfig {
@@ -18,23 +68,48 @@ func DefaultBrokerConfig() *BrokerConfig {
 		TimeoutMS:                 30000,
 		TimeoutMSForEachAPI:       make([]int, 0),
 		MetadataRefreshIntervalMS: 300 * 1000,
+
+		MaxInFlightRequestsPerConnection: 5,
 	}
 }
 
 func getBrokerConfigFromConsumerConfig(c *ConsumerConfig) *BrokerConfig {
 	b := DefaultBrokerConfig()
+	b.ClientID = c.ClientID
 	b.ConnectTimeoutMS = c.ConnectTimeoutMS
 	b.TimeoutMS = c.TimeoutMS
 	b.TimeoutMSForEachAPI = c.TimeoutMSForEachAPI
+	b.TLSEnabled = c.TLSEnabled
+	b.TLS = c.TLS
+	b.SASL = c.SASL
+	return b
+}
+
+func getBrokerConfigFromProducerConfig(p *ProducerConfig) *BrokerConfig {
+	b := DefaultBrokerConfig()
+	b.ClientID = p.ClientID
+	b.TLSEnabled = p.TLSEnabled
+	b.TLS = p.TLS
+	b.SASL = p.SASL
+	b.MaxInFlightRequestsPerConnection = p.MaxInFlightRequestsPerConnection
 	return b
 }
 
 var (
 	brokerAddressNotSet = errors.New("broker address not set in broker config")
+	maxInFlightError    = errors.New("max.in.flight.requests.per.connection must > 0")
 )
 
 func (c *BrokerConfig) checkValid() error {
-	return nil
+	if c.MaxInFlightRequestsPerConnection <= 0 {
+		return maxInFlightError
+	}
+	if c.TLSEnabled {
+		if _, err := createTLSConfig(&c.TLS); err != nil {
+			return err
+		}
+	}
+	return c.SASL.checkValid()
 }
 
 type ConsumerConfig struct {
@@ -55,6 +130,13 @@ type ConsumerConfig struct {
 	ConnectTimeoutMS     int    `json:"connect.timeout.ms"`
 	TimeoutMS            int    `json:"timeout.ms"`
 	TimeoutMSForEachAPI  []int  `json:"timeout.ms.for.eachapi"`
+
+	// IsolationLevel is read_uncommitted or read_committed. messages of aborted transactions are dropped if it is read_committed
+	IsolationLevel string `json:"isolation.level"`
+
+	TLSEnabled bool       `json:"tls.enabled"`
+	TLS        TLSConfig  `json:"tls"`
+	SASL       SASLConfig `json:"sasl"`
 }
 
 func DefaultConsumerConfig() *ConsumerConfig {
@@ -74,6 +156,7 @@ func DefaultConsumerConfig() *ConsumerConfig {
 		OffsetsStorage:       1,
 		ConnectTimeoutMS:     30000,
 		TimeoutMS:            30000,
+		IsolationLevel:       "read_uncommitted",
 	}
 
 	if c.TimeoutMSForEachAPI == nil {
@@ -111,21 +194,26 @@ func GetConsumerConfig(config map[string]interface{}) (*ConsumerConfig, error) {
 		c.TimeoutMSForEachAPI[API_FetchRequest] = c.TimeoutMS + int(c.FetchMaxWaitMS)
 	}
 
+	if err = c.checkValid(); err != nil {
+		return nil, err
+	}
 	return c, nil
 }
 
 var (
-	emptyGroupID = errors.New("group.id is empty")
+	emptyGroupID          = errors.New("group.id is empty")
+	unknownIsolationLevel = errors.New("isolation.level must be read_uncommitted or read_committed")
 )
 
+// checkValid checks the config shared by all consumers. group.id is checked only by GroupConsumer
 func (config *ConsumerConfig) checkValid() error {
 	if config.BootstrapServers == "" {
 		return bootstrapServersNotSet
 	}
-	if config.GroupID == "" {
-		return emptyGroupID
+	if config.IsolationLevel != "read_uncommitted" && config.IsolationLevel != "read_committed" {
+		return unknownIsolationLevel
 	}
-	return nil
+	return config.SASL.checkValid()
 }
 
 type ProducerConfig struct {
@@ -133,16 +221,43 @@ type ProducerConfig struct {
 	ClientID                 string `json:"client.id"`
 	Acks                     int16  `json:"acks"`
 	CompressionType          string `json:"compress.type"`
-	BatchSize                int    `json:"batch.size"`
+	CompressionLevel         int    `json:"compression.level"` // 0 means the default level of the codec
+	BatchSize                int    `json:"batch.size"`        // max encoded size of a batch in bytes. the batch is sent once it is full
 	MessageMaxCount          int    `json:"message.max.count"`
-	FlushIntervalMS          int    `json:"flush.interval.ms"`
+	LingerMS                 int    `json:"linger.ms"`         // how long the batch waits for more messages since its first message. -1 means flush.interval.ms
+	FlushIntervalMS          int    `json:"flush.interval.ms"` // Deprecated: use linger.ms
+	MaxRequestSize           int    `json:"max.request.size"`  // max encoded size of a message. larger messages are rejected with MESSAGE_TOO_LARGE
+	BufferMemory             int64  `json:"buffer.memory"`     // total bytes of the messages waiting to be sent, shared by all partitions of a Producer
+	MaxBlockMS               int    `json:"max.block.ms"`      // how long AddMessage blocks when buffer.memory is exhausted. 0 means failing at once
 	MetadataMaxAgeMS         int    `json:"metadata.max.age.ms"`
 	FetchTopicMetaDataRetrys int    `json:"fetch.topic.metadata.retrys"`
 	ConnectionsMaxIdleMS     int    `json:"connections.max.idle.ms"`
 
-	// TODO
+	// Partitioner chooses the partition of each message in Producer. Murmur2Partitioner, the same as the java client, is used if nil
+	Partitioner Partitioner `json:"-"`
+	// DeliveryReports receives the report of each message once it is sent or fails. it must be drained, or else flush blocks
+	DeliveryReports chan<- *DeliveryReport `json:"-"`
+
+	// Retries is how many times a batch is sent again on retriable errors, after retry.backoff.ms.
+	// the leader is found out from metadata again if it might have changed
 	Retries          int   `json:"retries"`
 	RequestTimeoutMS int32 `json:"request.timeout.ms"`
+
+	// MaxInFlightRequestsPerConnection is how many produce requests are sent to a broker before their responses come back.
+	// batches of one partition are always sent one by one, so it never reorders messages
+	MaxInFlightRequestsPerConnection int `json:"max.in.flight.requests.per.connection"`
+
+	// EnableIdempotence makes sure that retries never write duplicates of a batch. it needs acks=-1 and kafka 0.11.0+
+	EnableIdempotence bool `json:"enable.idempotence"`
+
+	// TransactionalID enables transactions of Producer. it needs enable.idempotence
+	TransactionalID      string `json:"transactional.id"`
+	TransactionTimeoutMS int32  `json:"transaction.timeout.ms"`
+	RetryBackOffMS       int    `json:"retry.backoff.ms"`
+
+	TLSEnabled bool       `json:"tls.enabled"`
+	TLS        TLSConfig  `json:"tls"`
+	SASL       SASLConfig `json:"sasl"`
 }
 
 func DefaultProducerConfig() *ProducerConfig {
@@ -152,23 +267,45 @@ func DefaultProducerConfig() *ProducerConfig {
 		CompressionType:          "none",
 		BatchSize:                16384,
 		MessageMaxCount:          1024,
+		LingerMS:                 -1,
 		FlushIntervalMS:          200,
+		MaxRequestSize:           1048576,
+		BufferMemory:             33554432,
+		MaxBlockMS:               60000,
 		MetadataMaxAgeMS:         300000,
 		FetchTopicMetaDataRetrys: 3,
 		ConnectionsMaxIdleMS:     540000,
 
 		Retries:          0,
 		RequestTimeoutMS: 30000,
+
+		MaxInFlightRequestsPerConnection: 5,
+
+		TransactionTimeoutMS: 60000,
+		RetryBackOffMS:       100,
 	}
 }
 
 var (
 	messageMaxCountError   = errors.New("message.max.count must > 0")
-	flushIntervalMSError   = errors.New("flush.interval.ms must > 0")
-	unknownCompressionType = errors.New("unknown compression type")
+	flushIntervalMSError   = errors.New("flush.interval.ms must > 0 if linger.ms is -1")
+	batchSizeError         = errors.New("batch.size must > 0")
+	maxRequestSizeError    = errors.New("max.request.size must > 0")
+	bufferMemoryError      = errors.New("buffer.memory must > 0")
+	unknownCompressionType = errors.New("unknown compression type. it should be registered by RegisterCodec")
 	bootstrapServersNotSet = errors.New("bootstrap servers not set")
+	idempotenceAcksError   = errors.New("enable.idempotence needs acks=-1")
+	transactionalIDError   = errors.New("transactional.id needs enable.idempotence")
 )
 
+// linger returns linger.ms, or flush.interval.ms if linger.ms is -1
+func (config *ProducerConfig) linger() time.Duration {
+	if config.LingerMS < 0 {
+		return time.Duration(config.FlushIntervalMS) * time.Millisecond
+	}
+	return time.Duration(config.LingerMS) * time.Millisecond
+}
+
 func (config *ProducerConfig) checkValid() error {
 	if config.BootstrapServers == "" {
 		return bootstrapServersNotSet
@@ -176,17 +313,30 @@ func (config *ProducerConfig) checkValid() error {
 	if config.MessageMaxCount <= 0 {
 		return messageMaxCountError
 	}
-	if config.FlushIntervalMS <= 0 {
+	if config.LingerMS < 0 && config.FlushIntervalMS <= 0 {
 		return flushIntervalMSError
 	}
+	if config.BatchSize <= 0 {
+		return batchSizeError
+	}
+	if config.MaxRequestSize <= 0 {
+		return maxRequestSizeError
+	}
+	if config.BufferMemory <= 0 {
+		return bufferMemoryError
+	}
+	if config.MaxInFlightRequestsPerConnection <= 0 {
+		return maxInFlightError
+	}
+	if config.EnableIdempotence && config.Acks != -1 {
+		return idempotenceAcksError
+	}
+	if config.TransactionalID != "" && !config.EnableIdempotence {
+		return transactionalIDError
+	}
 
-	switch config.CompressionType {
-	case "none":
-	case "gzip":
-	case "snappy":
-	case "lz4":
-	default:
-		return unknownCompressionType
+	if _, _, err := getLeveledCodec(config.CompressionType, config.CompressionLevel); err != nil {
+		return err
 	}
-	return nil
+	return config.SASL.checkValid()
 }
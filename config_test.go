@@ -0,0 +1,25 @@
+package healer
+
+import "testing"
+
+func TestGetConsumerConfig(t *testing.T) {
+	config, err := GetConsumerConfig(map[string]interface{}{"bootstrap.servers": "127.0.0.1:9092"})
+	if err != nil {
+		t.Fatalf("get consumer config error: %s", err)
+	}
+	if config.IsolationLevel != "read_uncommitted" {
+		t.Errorf("expect default isolation.level read_uncommitted, got %s", config.IsolationLevel)
+	}
+
+	for _, c := range []struct {
+		config map[string]interface{}
+		err    error
+	}{
+		{map[string]interface{}{}, bootstrapServersNotSet},
+		{map[string]interface{}{"bootstrap.servers": "127.0.0.1:9092", "isolation.level": "committed"}, unknownIsolationLevel},
+	} {
+		if _, err := GetConsumerConfig(c.config); err != c.err {
+			t.Errorf("expect %v from %v, got %v", c.err, c.config, err)
+		}
+	}
+}
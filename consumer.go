@@ -1,6 +1,10 @@
 package healer
 
-import "github.com/golang/glog"
+import (
+	"fmt"
+
+	"github.com/golang/glog"
+)
 
 // Consumer instance is built to consume messages from kafka broker
 type Consumer struct {
@@ -14,6 +18,9 @@ type Consumer struct {
 
 func NewConsumer(topic string, config *ConsumerConfig) (*Consumer, error) {
 	var err error
+	if err = config.checkValid(); err != nil {
+		return nil, err
+	}
 	c := &Consumer{
 		config: config,
 		topic:  topic,
@@ -54,6 +61,16 @@ func (consumer *Consumer) Consume(fromBeginning bool) (chan *FullMessage, error)
 		topicName := topicMetadatas.TopicName
 		for _, partitionMetadataInfo := range topicMetadatas.PartitionMetadatas {
 			partitionID := partitionMetadataInfo.PartitionID
+			// all leaders are checked before any partition starts, so that no SimpleConsumer is left running after an error
+			if consumer.config.IsolationLevel == "read_committed" {
+				leader, err := consumer.brokers.GetBroker(partitionMetadataInfo.Leader)
+				if err != nil {
+					return nil, fmt.Errorf("could not get leader of %s[%d]: %s", topicName, partitionID, err)
+				}
+				if leader.getHighestAvailableAPIVersion(API_FetchRequest) < 4 {
+					return nil, readCommittedNotSupported
+				}
+			}
 			simpleConsumer := NewSimpleConsumerWithBrokers(topicName, partitionID, consumer.config, consumer.brokers)
 			consumer.SimpleConsumers = append(consumer.SimpleConsumers, simpleConsumer)
 		}
@@ -67,8 +84,14 @@ func (consumer *Consumer) Consume(fromBeginning bool) (chan *FullMessage, error)
 	}
 
 	messages := make(chan *FullMessage, 10)
-	for _, simpleConsumer := range consumer.SimpleConsumers {
-		simpleConsumer.Consume(offset, messages)
+	for i, simpleConsumer := range consumer.SimpleConsumers {
+		if _, err := simpleConsumer.Consume(offset, messages); err != nil {
+			// the leader has changed since the check above. the caller never receives messages, so stop the started partitions
+			for _, started := range consumer.SimpleConsumers[:i] {
+				started.Stop()
+			}
+			return nil, err
+		}
 	}
 
 	return messages, nil
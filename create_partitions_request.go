@@ -0,0 +1,124 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+CreatePartitions Request (Version: 0) => [topic_partitions] timeout validate_only
+  topic_partitions => topic new_partitions
+    topic => STRING
+    new_partitions => count [assignment]
+      count => INT32
+      assignment => ARRAY(INT32)
+  timeout => INT32
+  validate_only => BOOLEAN
+
+FIELD	DESCRIPTION
+  topic	Name of topic
+  count	The new partition count.
+  assignment	The assigned brokers of each new partition. null means the brokers are chosen by the controller
+  timeout	The time in ms to wait for the partitions to be created.
+  validate_only	If true then validate the request, but don't actually increase the number of partitions.
+*/
+
+type createPartitionsTopic struct {
+	topic      string
+	count      int32
+	assignment [][]int32
+}
+
+type CreatePartitionsRequest struct {
+	RequestHeader *RequestHeader
+	Topics        []*createPartitionsTopic
+	Timeout       int32
+	ValidateOnly  bool
+}
+
+func NewCreatePartitionsRequest(apiVersion uint16, clientID string, timeout int32, validateOnly bool) *CreatePartitionsRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_CreatePartitions,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &CreatePartitionsRequest{
+		RequestHeader: requestHeader,
+		Topics:        make([]*createPartitionsTopic, 0),
+		Timeout:       timeout,
+		ValidateOnly:  validateOnly,
+	}
+}
+
+// AddTopic increases the partitions of the topic to count. assignment is the brokers of each new partition, it could be nil
+func (r *CreatePartitionsRequest) AddTopic(topic string, count int32, assignment [][]int32) {
+	r.Topics = append(r.Topics, &createPartitionsTopic{topic, count, assignment})
+}
+
+func (r *CreatePartitionsRequest) Length() int {
+	l := r.RequestHeader.length()
+	l += 4
+	for _, t := range r.Topics {
+		l += 2 + len(t.topic) + 4 + 4
+		for _, brokers := range t.assignment {
+			l += 4 + 4*len(brokers)
+		}
+	}
+	l += 4 + 1
+	return l
+}
+
+func (r *CreatePartitionsRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(len(r.Topics)))
+	offset += 4
+	for _, t := range r.Topics {
+		binary.BigEndian.PutUint16(payload[offset:], uint16(len(t.topic)))
+		offset += 2
+		offset += copy(payload[offset:], t.topic)
+
+		binary.BigEndian.PutUint32(payload[offset:], uint32(t.count))
+		offset += 4
+
+		if t.assignment == nil {
+			binary.BigEndian.PutUint32(payload[offset:], 0xffffffff)
+			offset += 4
+			continue
+		}
+		binary.BigEndian.PutUint32(payload[offset:], uint32(len(t.assignment)))
+		offset += 4
+		for _, brokers := range t.assignment {
+			binary.BigEndian.PutUint32(payload[offset:], uint32(len(brokers)))
+			offset += 4
+			for _, broker := range brokers {
+				binary.BigEndian.PutUint32(payload[offset:], uint32(broker))
+				offset += 4
+			}
+		}
+	}
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(r.Timeout))
+	offset += 4
+
+	if r.ValidateOnly {
+		payload[offset] = 1
+	}
+
+	return payload
+}
+
+func (r *CreatePartitionsRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *CreatePartitionsRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
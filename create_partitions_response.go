@@ -0,0 +1,71 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+CreatePartitions Response (Version: 0) => throttle_time_ms [topic_errors]
+  throttle_time_ms => INT32
+  topic_errors => topic error_code error_message
+    topic => STRING
+    error_code => INT16
+    error_message => NULLABLE_STRING
+
+FIELD	DESCRIPTION
+  throttle_time_ms	Duration in milliseconds for which the request was throttled due to quota violation (Zero if the request did not violate any quota)
+  topic	Name of topic
+  error_code	Response error code
+  error_message	Response error message
+*/
+
+type CreatePartitionsResponse struct {
+	CorrelationID  uint32
+	ThrottleTimeMS int32
+	TopicErrors    []*TopicError
+}
+
+func NewCreatePartitionsResponse(payload []byte) (*CreatePartitionsResponse, error) {
+	var (
+		r      *CreatePartitionsResponse = &CreatePartitionsResponse{}
+		offset int                       = 0
+		l      int                       = 0
+	)
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("CreatePartitions reseponse length did not match: %d!=%d", responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	r.ThrottleTimeMS = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	l = int(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	r.TopicErrors = make([]*TopicError, l)
+	for i := range r.TopicErrors {
+		topicError := &TopicError{}
+		r.TopicErrors[i] = topicError
+
+		l = int(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		topicError.Topic = string(payload[offset : offset+l])
+		offset += l
+
+		topicError.ErrorCode = int16(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+
+		l = int(int16(binary.BigEndian.Uint16(payload[offset:])))
+		offset += 2
+		if l > 0 {
+			topicError.ErrorMessage = string(payload[offset : offset+l])
+			offset += l
+		}
+	}
+
+	return r, topicErrors(r.TopicErrors)
+}
@@ -0,0 +1,167 @@
+package healer
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+/*
+CreateTopics Request (Version: 0) => [create_topic_requests] timeout
+  create_topic_requests => topic num_partitions replication_factor [replica_assignment] [config_entries]
+    topic => STRING
+    num_partitions => INT32
+    replication_factor => INT16
+    replica_assignment => partition [replicas]
+      partition => INT32
+      replicas => INT32
+    config_entries => config_name config_value
+      config_name => STRING
+      config_value => NULLABLE_STRING
+  timeout => INT32
+
+CreateTopics Request (Version: 1) => [create_topic_requests] timeout validate_only
+  create_topic_requests => topic num_partitions replication_factor [replica_assignment] [config_entries]
+    (same as version 0)
+  timeout => INT32
+  validate_only => BOOLEAN
+
+FIELD	DESCRIPTION
+  topic	Name of topic
+  num_partitions	Number of partitions to be created. -1 indicates unset.
+  replication_factor	Replication factor for the topic. -1 indicates unset.
+  replica_assignment	Replica assignment among kafka brokers for this topic partitions. If this is set num_partitions and replication_factor must be unset.
+  partition	Topic partition id
+  replicas	The set of all nodes that should host this partition. The first replica in the list is the preferred leader.
+  config_entries	Topic level configuration for topic to be set.
+  config_name	Configuration name
+  config_value	Configuration value
+  timeout	The time in ms to wait for a topic to be completely created on the controller node. Values <= 0 will trigger topic creation and return immediately
+  validate_only	If this is true, the request will be validated, but the topic won't be created.
+*/
+
+// TopicDetail describes the topic to create. ReplicaAssignment is partitionID -> brokers, NumPartitions and ReplicationFactor must be -1 if it is set
+type TopicDetail struct {
+	NumPartitions     int32
+	ReplicationFactor int16
+	ReplicaAssignment map[int32][]int32
+	Configs           map[string]string
+}
+
+type CreateTopicsRequest struct {
+	RequestHeader *RequestHeader
+	Topics        map[string]*TopicDetail
+	Timeout       int32
+	ValidateOnly  bool
+}
+
+func NewCreateTopicsRequest(apiVersion uint16, clientID string, timeout int32, validateOnly bool) *CreateTopicsRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_CreateTopics,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &CreateTopicsRequest{
+		RequestHeader: requestHeader,
+		Topics:        make(map[string]*TopicDetail),
+		Timeout:       timeout,
+		ValidateOnly:  validateOnly,
+	}
+}
+
+func (r *CreateTopicsRequest) AddTopic(topic string, detail *TopicDetail) {
+	r.Topics[topic] = detail
+}
+
+func (r *CreateTopicsRequest) Length() int {
+	l := r.RequestHeader.length()
+	l += 4
+	for topic, detail := range r.Topics {
+		l += 2 + len(topic) + 4 + 2
+		l += 4
+		for _, replicas := range detail.ReplicaAssignment {
+			l += 4 + 4 + 4*len(replicas)
+		}
+		l += 4
+		for name, value := range detail.Configs {
+			l += 2 + len(name) + 2 + len(value)
+		}
+	}
+	l += 4
+	if r.RequestHeader.ApiVersion >= 1 {
+		l++
+	}
+	return l
+}
+
+func (r *CreateTopicsRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(len(r.Topics)))
+	offset += 4
+	for topic, detail := range r.Topics {
+		binary.BigEndian.PutUint16(payload[offset:], uint16(len(topic)))
+		offset += 2
+		offset += copy(payload[offset:], topic)
+
+		binary.BigEndian.PutUint32(payload[offset:], uint32(detail.NumPartitions))
+		offset += 4
+
+		binary.BigEndian.PutUint16(payload[offset:], uint16(detail.ReplicationFactor))
+		offset += 2
+
+		partitions := make([]int, 0, len(detail.ReplicaAssignment))
+		for partitionID := range detail.ReplicaAssignment {
+			partitions = append(partitions, int(partitionID))
+		}
+		sort.Ints(partitions)
+		binary.BigEndian.PutUint32(payload[offset:], uint32(len(partitions)))
+		offset += 4
+		for _, partitionID := range partitions {
+			binary.BigEndian.PutUint32(payload[offset:], uint32(partitionID))
+			offset += 4
+			replicas := detail.ReplicaAssignment[int32(partitionID)]
+			binary.BigEndian.PutUint32(payload[offset:], uint32(len(replicas)))
+			offset += 4
+			for _, replica := range replicas {
+				binary.BigEndian.PutUint32(payload[offset:], uint32(replica))
+				offset += 4
+			}
+		}
+
+		binary.BigEndian.PutUint32(payload[offset:], uint32(len(detail.Configs)))
+		offset += 4
+		for name, value := range detail.Configs {
+			binary.BigEndian.PutUint16(payload[offset:], uint16(len(name)))
+			offset += 2
+			offset += copy(payload[offset:], name)
+			binary.BigEndian.PutUint16(payload[offset:], uint16(len(value)))
+			offset += 2
+			offset += copy(payload[offset:], value)
+		}
+	}
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(r.Timeout))
+	offset += 4
+
+	if r.RequestHeader.ApiVersion >= 1 && r.ValidateOnly {
+		payload[offset] = 1
+	}
+
+	return payload
+}
+
+func (r *CreateTopicsRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *CreateTopicsRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
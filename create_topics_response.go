@@ -0,0 +1,99 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+CreateTopics Response (Version: 0) => [topic_errors]
+  topic_errors => topic error_code
+    topic => STRING
+    error_code => INT16
+
+CreateTopics Response (Version: 1) => [topic_errors]
+  topic_errors => topic error_code error_message
+    topic => STRING
+    error_code => INT16
+    error_message => NULLABLE_STRING
+
+FIELD	DESCRIPTION
+  topic	Name of topic
+  error_code	Response error code
+  error_message	Response error message
+*/
+
+// TopicError is the result of each topic in the topic administration responses
+type TopicError struct {
+	Topic        string
+	ErrorCode    int16
+	ErrorMessage string
+}
+
+// topicErrors returns the error of all failed topics, or nil if all succeed
+func topicErrors(topicErrors []*TopicError) error {
+	var err error
+	for _, topicError := range topicErrors {
+		if topicError.ErrorCode == 0 {
+			continue
+		}
+		e := fmt.Sprintf("%s: %s", topicError.Topic, getErrorFromErrorCode(topicError.ErrorCode))
+		if topicError.ErrorMessage != "" {
+			e += " " + topicError.ErrorMessage
+		}
+		if err == nil {
+			err = fmt.Errorf("%s", e)
+		} else {
+			err = fmt.Errorf("%s; %s", err, e)
+		}
+	}
+	return err
+}
+
+type CreateTopicsResponse struct {
+	CorrelationID uint32
+	TopicErrors   []*TopicError
+}
+
+func NewCreateTopicsResponse(payload []byte, version uint16) (*CreateTopicsResponse, error) {
+	var (
+		r      *CreateTopicsResponse = &CreateTopicsResponse{}
+		offset int                   = 0
+		l      int                   = 0
+	)
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("CreateTopics reseponse length did not match: %d!=%d", responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	l = int(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	r.TopicErrors = make([]*TopicError, l)
+	for i := range r.TopicErrors {
+		topicError := &TopicError{}
+		r.TopicErrors[i] = topicError
+
+		l = int(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		topicError.Topic = string(payload[offset : offset+l])
+		offset += l
+
+		topicError.ErrorCode = int16(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+
+		if version >= 1 {
+			l = int(int16(binary.BigEndian.Uint16(payload[offset:])))
+			offset += 2
+			if l > 0 {
+				topicError.ErrorMessage = string(payload[offset : offset+l])
+				offset += l
+			}
+		}
+	}
+
+	return r, topicErrors(r.TopicErrors)
+}
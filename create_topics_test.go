@@ -0,0 +1,79 @@
+package healer
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestCreateTopicsRequest(t *testing.T) {
+	request := NewCreateTopicsRequest(1, "healer", 1000, true)
+	request.AddTopic("test", &TopicDetail{
+		NumPartitions:     -1,
+		ReplicationFactor: -1,
+		ReplicaAssignment: map[int32][]int32{1: []int32{2, 1}, 0: []int32{1, 2}},
+		Configs:           map[string]string{"retention.ms": "3600000"},
+	})
+	payload := request.Encode()
+	if int(binary.BigEndian.Uint32(payload))+4 != len(payload) {
+		t.Fatalf("request length %d does not match payload length %d", binary.BigEndian.Uint32(payload), len(payload))
+	}
+
+	offset := 4 + request.RequestHeader.length()
+	if count := binary.BigEndian.Uint32(payload[offset:]); count != 1 {
+		t.Fatalf("expect 1 topic, got %d", count)
+	}
+	offset += 4 + 2 + len("test") + 4 + 2
+	if count := binary.BigEndian.Uint32(payload[offset:]); count != 2 {
+		t.Fatalf("expect 2 partitions in assignment, got %d", count)
+	}
+	offset += 4
+	if partition := binary.BigEndian.Uint32(payload[offset:]); partition != 0 {
+		t.Errorf("expect assignment sorted by partition, got partition %d at first", partition)
+	}
+	if payload[len(payload)-1] != 1 {
+		t.Error("expect validate_only to be true")
+	}
+}
+
+func TestCreateTopicsResponse(t *testing.T) {
+	// correlation_id [topic error_code error_message]
+	topics := []struct {
+		topic     string
+		errorCode int16
+		message   string
+	}{
+		{"ok", 0, ""},
+		{"exists", 36, "Topic 'exists' already exists."},
+	}
+	payload := make([]byte, 4+4+4)
+	binary.BigEndian.PutUint32(payload[8:], uint32(len(topics)))
+	for _, topic := range topics {
+		b := make([]byte, 2+len(topic.topic)+2+2+len(topic.message))
+		binary.BigEndian.PutUint16(b, uint16(len(topic.topic)))
+		copy(b[2:], topic.topic)
+		binary.BigEndian.PutUint16(b[2+len(topic.topic):], uint16(topic.errorCode))
+		if topic.message == "" {
+			binary.BigEndian.PutUint16(b[4+len(topic.topic):], 0xffff)
+		} else {
+			binary.BigEndian.PutUint16(b[4+len(topic.topic):], uint16(len(topic.message)))
+			copy(b[6+len(topic.topic):], topic.message)
+		}
+		payload = append(payload, b...)
+	}
+	binary.BigEndian.PutUint32(payload, uint32(len(payload)-4))
+
+	response, err := NewCreateTopicsResponse(payload, 1)
+	if response == nil {
+		t.Fatalf("decode response error: %s", err)
+	}
+	if len(response.TopicErrors) != 2 {
+		t.Fatalf("expect 2 topics, got %d", len(response.TopicErrors))
+	}
+	if err == nil || !strings.Contains(err.Error(), "exists:") || strings.Contains(err.Error(), "ok:") {
+		t.Errorf("expect error of topic exists only, got %v", err)
+	}
+	if response.TopicErrors[1].ErrorMessage != topics[1].message {
+		t.Errorf("expect error message %q, got %q", topics[1].message, response.TopicErrors[1].ErrorMessage)
+	}
+}
@@ -0,0 +1,109 @@
+package healer
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+/*
+DeleteRecords Request (Version: 0) => [topics] timeout_ms
+  topics => name [partitions]
+    name => STRING
+    partitions => partition_index offset
+      partition_index => INT32
+      offset => INT64
+  timeout_ms => INT32
+
+FIELD	DESCRIPTION
+  topics	Each topic that we want to delete records from.
+  name	The topic name.
+  partitions	Each partition that we want to delete records from.
+  partition_index	The partition index.
+  offset	The deletion offset. -1 means the high watermark of the partition.
+  timeout_ms	How long to wait for the deletion to complete, in milliseconds.
+*/
+
+type DeleteRecordsRequest struct {
+	RequestHeader *RequestHeader
+	Topics        map[string]map[int32]int64
+	TimeoutMS     int32
+}
+
+func NewDeleteRecordsRequest(apiVersion uint16, clientID string, timeoutMS int32) *DeleteRecordsRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_DeleteRecords,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &DeleteRecordsRequest{
+		RequestHeader: requestHeader,
+		Topics:        make(map[string]map[int32]int64),
+		TimeoutMS:     timeoutMS,
+	}
+}
+
+// AddPartition deletes the records before offset in the partition
+func (r *DeleteRecordsRequest) AddPartition(topic string, partition int32, offset int64) {
+	if _, ok := r.Topics[topic]; !ok {
+		r.Topics[topic] = make(map[int32]int64)
+	}
+	r.Topics[topic][partition] = offset
+}
+
+func (r *DeleteRecordsRequest) Length() int {
+	l := r.RequestHeader.length()
+	l += 4
+	for topic, partitions := range r.Topics {
+		l += 2 + len(topic)
+		l += 4 + 12*len(partitions)
+	}
+	l += 4
+	return l
+}
+
+func (r *DeleteRecordsRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(len(r.Topics)))
+	offset += 4
+	for topic, partitions := range r.Topics {
+		binary.BigEndian.PutUint16(payload[offset:], uint16(len(topic)))
+		offset += 2
+		offset += copy(payload[offset:], topic)
+
+		partitionIDs := make([]int, 0, len(partitions))
+		for partitionID := range partitions {
+			partitionIDs = append(partitionIDs, int(partitionID))
+		}
+		sort.Ints(partitionIDs)
+
+		binary.BigEndian.PutUint32(payload[offset:], uint32(len(partitionIDs)))
+		offset += 4
+		for _, partitionID := range partitionIDs {
+			binary.BigEndian.PutUint32(payload[offset:], uint32(partitionID))
+			offset += 4
+			binary.BigEndian.PutUint64(payload[offset:], uint64(partitions[int32(partitionID)]))
+			offset += 8
+		}
+	}
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(r.TimeoutMS))
+
+	return payload
+}
+
+func (r *DeleteRecordsRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *DeleteRecordsRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
@@ -0,0 +1,88 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+DeleteRecords Response (Version: 0) => throttle_time_ms [topics]
+  throttle_time_ms => INT32
+  topics => name [partitions]
+    name => STRING
+    partitions => partition_index low_watermark error_code
+      partition_index => INT32
+      low_watermark => INT64
+      error_code => INT16
+
+FIELD	DESCRIPTION
+  throttle_time_ms	The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+  name	The topic name.
+  partition_index	The partition index.
+  low_watermark	The partition low water mark.
+  error_code	The deletion error code, or 0 if the deletion succeeded.
+*/
+
+type DeleteRecordsResponsePartition struct {
+	PartitionIndex int32
+	LowWatermark   int64
+	ErrorCode      int16
+}
+
+type DeleteRecordsResponse struct {
+	CorrelationID  uint32
+	ThrottleTimeMS int32
+	Topics         map[string][]*DeleteRecordsResponsePartition
+}
+
+func NewDeleteRecordsResponse(payload []byte) (*DeleteRecordsResponse, error) {
+	var (
+		r      *DeleteRecordsResponse = &DeleteRecordsResponse{}
+		offset int                    = 0
+		l      int                    = 0
+		err    error
+	)
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("DeleteRecords reseponse length did not match: %d!=%d", responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	r.ThrottleTimeMS = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	topicCount := int(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	r.Topics = make(map[string][]*DeleteRecordsResponsePartition, topicCount)
+	for i := 0; i < topicCount; i++ {
+		l = int(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		topic := string(payload[offset : offset+l])
+		offset += l
+
+		l = int(binary.BigEndian.Uint32(payload[offset:]))
+		offset += 4
+		partitions := make([]*DeleteRecordsResponsePartition, l)
+		for j := range partitions {
+			p := &DeleteRecordsResponsePartition{}
+			partitions[j] = p
+
+			p.PartitionIndex = int32(binary.BigEndian.Uint32(payload[offset:]))
+			offset += 4
+			p.LowWatermark = int64(binary.BigEndian.Uint64(payload[offset:]))
+			offset += 8
+			p.ErrorCode = int16(binary.BigEndian.Uint16(payload[offset:]))
+			offset += 2
+
+			if err == nil && p.ErrorCode != 0 {
+				err = fmt.Errorf("%s[%d]: %s", topic, p.PartitionIndex, getErrorFromErrorCode(p.ErrorCode))
+			}
+		}
+		r.Topics[topic] = partitions
+	}
+
+	return r, err
+}
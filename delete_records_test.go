@@ -0,0 +1,65 @@
+package healer
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestDeleteRecordsRequest(t *testing.T) {
+	request := NewDeleteRecordsRequest(0, "healer", 1000)
+	request.AddPartition("test", 1, 100)
+	request.AddPartition("test", 0, -1)
+	payload := request.Encode()
+	if int(binary.BigEndian.Uint32(payload))+4 != len(payload) {
+		t.Fatalf("request length %d does not match payload length %d", binary.BigEndian.Uint32(payload), len(payload))
+	}
+
+	offset := 4 + request.RequestHeader.length() + 4 + 2 + len("test")
+	if count := binary.BigEndian.Uint32(payload[offset:]); count != 2 {
+		t.Fatalf("expect 2 partitions, got %d", count)
+	}
+	offset += 4
+	for _, expect := range []struct {
+		partition int32
+		offset    int64
+	}{{0, -1}, {1, 100}} {
+		partition := int32(binary.BigEndian.Uint32(payload[offset:]))
+		o := int64(binary.BigEndian.Uint64(payload[offset+4:]))
+		if partition != expect.partition || o != expect.offset {
+			t.Errorf("expect %d:%d, got %d:%d", expect.partition, expect.offset, partition, o)
+		}
+		offset += 12
+	}
+}
+
+func TestDeleteRecordsResponse(t *testing.T) {
+	// length correlation_id throttle_time_ms [topics]
+	payload := make([]byte, 16)
+	binary.BigEndian.PutUint32(payload[12:], 1)
+	payload = append(payload, 0, 4)
+	payload = append(payload, "test"...)
+	payload = append(payload, 0, 0, 0, 2)
+	for _, p := range []struct {
+		partition    int32
+		lowWatermark int64
+		errorCode    int16
+	}{{0, 100, 0}, {1, -1, 1}} {
+		b := make([]byte, 14)
+		binary.BigEndian.PutUint32(b, uint32(p.partition))
+		binary.BigEndian.PutUint64(b[4:], uint64(p.lowWatermark))
+		binary.BigEndian.PutUint16(b[12:], uint16(p.errorCode))
+		payload = append(payload, b...)
+	}
+	binary.BigEndian.PutUint32(payload, uint32(len(payload)-4))
+
+	response, err := NewDeleteRecordsResponse(payload)
+	if err == nil {
+		t.Error("expect error of partition 1")
+	}
+	if response == nil || len(response.Topics["test"]) != 2 {
+		t.Fatal("expect 2 partitions in response")
+	}
+	if p := response.Topics["test"][0]; p.PartitionIndex != 0 || p.LowWatermark != 100 {
+		t.Errorf("expect low watermark 100 of partition 0, got %+v", p)
+	}
+}
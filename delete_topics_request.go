@@ -0,0 +1,72 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+DeleteTopics Request (Version: 0) => [topics] timeout
+  topics => STRING
+  timeout => INT32
+
+FIELD	DESCRIPTION
+  topics	An array of topics to be deleted.
+  timeout	The time in ms to wait for a topic to be completely deleted on the controller node. Values <= 0 will trigger topic deletion and return immediately
+*/
+
+type DeleteTopicsRequest struct {
+	RequestHeader *RequestHeader
+	Topics        []string
+	Timeout       int32
+}
+
+func NewDeleteTopicsRequest(apiVersion uint16, clientID string, topics []string, timeout int32) *DeleteTopicsRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_DeleteTopics,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &DeleteTopicsRequest{requestHeader, topics, timeout}
+}
+
+func (r *DeleteTopicsRequest) Length() int {
+	l := r.RequestHeader.length()
+	l += 4
+	for _, topic := range r.Topics {
+		l += 2 + len(topic)
+	}
+	l += 4
+	return l
+}
+
+func (r *DeleteTopicsRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(len(r.Topics)))
+	offset += 4
+	for _, topic := range r.Topics {
+		binary.BigEndian.PutUint16(payload[offset:], uint16(len(topic)))
+		offset += 2
+		offset += copy(payload[offset:], topic)
+	}
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(r.Timeout))
+
+	return payload
+}
+
+func (r *DeleteTopicsRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *DeleteTopicsRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
@@ -0,0 +1,56 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+DeleteTopics Response (Version: 0) => [topic_error_codes]
+  topic_error_codes => topic error_code
+    topic => STRING
+    error_code => INT16
+
+FIELD	DESCRIPTION
+  topic	Name of topic
+  error_code	Response error code
+*/
+
+type DeleteTopicsResponse struct {
+	CorrelationID uint32
+	TopicErrors   []*TopicError
+}
+
+func NewDeleteTopicsResponse(payload []byte) (*DeleteTopicsResponse, error) {
+	var (
+		r      *DeleteTopicsResponse = &DeleteTopicsResponse{}
+		offset int                   = 0
+		l      int                   = 0
+	)
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("DeleteTopics reseponse length did not match: %d!=%d", responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	l = int(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	r.TopicErrors = make([]*TopicError, l)
+	for i := range r.TopicErrors {
+		topicError := &TopicError{}
+		r.TopicErrors[i] = topicError
+
+		l = int(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		topicError.Topic = string(payload[offset : offset+l])
+		offset += l
+
+		topicError.ErrorCode = int16(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+	}
+
+	return r, topicErrors(r.TopicErrors)
+}
@@ -0,0 +1,64 @@
+package healer
+
+// DeliveryReport tells the result of a message sent by producer, once the ProduceResponse is parsed
+type DeliveryReport struct {
+	Topic     string
+	Partition int32
+	// Offset is the offset of the message in the partition. it is -1 if Err is not nil or the broker does not tell it, which is always the case with acks=0
+	Offset int64
+	// Timestamp is the LogAppendTime set by the broker if the topic uses it, or else the CreateTime of the message
+	Timestamp int64
+	Key       []byte
+	Value     []byte
+	Headers   []RecordHeader
+	Err       error
+}
+
+// DeliveryCallback is called with the report of the message once it is sent or fails.
+// it is called in the flushing goroutine, so it should return quickly
+type DeliveryCallback func(*DeliveryReport)
+
+// reportDelivery sends the report of each message to its callback and the DeliveryReports channel in config.
+// partitionResponse is nil if err is not nil, or with acks=0 since the message is reported as soon as it is written
+func (p *SimpleProducer) reportDelivery(messageSet MessageSet, partitionResponse *ProduceResponse_PartitionResponse, err error) {
+	if p.config.DeliveryReports == nil {
+		hasCallback := false
+		for _, message := range messageSet {
+			if message.callback != nil {
+				hasCallback = true
+				break
+			}
+		}
+		if !hasCallback {
+			return
+		}
+	}
+
+	for i, message := range messageSet {
+		report := &DeliveryReport{
+			Topic:     p.topic,
+			Partition: p.partition,
+			Offset:    -1,
+			Timestamp: message.Timestamp,
+			Key:       message.Key,
+			Value:     message.Value,
+			Headers:   message.Headers,
+			Err:       err,
+		}
+		// base offset is -1 in the response of a duplicate batch, which is written by the previous try
+		if err == nil && partitionResponse != nil && partitionResponse.BaseOffset >= 0 {
+			report.Offset = partitionResponse.BaseOffset + int64(i)
+			// it is -1 if the topic uses CreateTime, and not in the response before produce v2
+			if partitionResponse.LogAppendTime > 0 {
+				report.Timestamp = partitionResponse.LogAppendTime
+			}
+		}
+
+		if message.callback != nil {
+			message.callback(report)
+		}
+		if p.config.DeliveryReports != nil {
+			p.config.DeliveryReports <- report
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package healer
+
+import (
+	"sync"
+	"testing"
+)
+
+// fakeProduceBroker answers produce v3 requests with the error code, base offset and log append time
+type fakeProduceBroker struct {
+	errorCode     int16
+	baseOffset    int64
+	logAppendTime int64
+
+	mutex           sync.Mutex
+	produceRequests int
+}
+
+func (b *fakeProduceBroker) getProduceRequests() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.produceRequests
+}
+
+func (b *fakeProduceBroker) handle(request *fakeRequest) []byte {
+	switch request.apiKey {
+	case API_ApiVersions:
+		return fakeApiVersionsResponse(map[uint16]uint16{API_ProduceRequest: 3})
+	case API_ProduceRequest:
+		produceRequest := parseFakeProduceRequest(request.body)
+		b.mutex.Lock()
+		b.produceRequests++
+		b.mutex.Unlock()
+		// the broker never answers acks=0
+		if produceRequest.acks == 0 {
+			return []byte{}
+		}
+
+		var results []fakeProduceResult
+		for _, p := range produceRequest.partitions {
+			results = append(results, fakeProduceResult{p.topic, p.partition, b.errorCode, b.baseOffset, b.logAppendTime})
+		}
+		return fakeProduceResponse(results)
+	}
+	return nil
+}
+
+// newFakeProduceSimpleProducer returns a SimpleProducer of test[2] whose leader is the fake broker
+func newFakeProduceSimpleProducer(t *testing.T, fakeBroker *fakeProduceBroker, config *ProducerConfig) (*SimpleProducer, func()) {
+	server := newFakeBroker(t, fakeBroker.handle)
+	p, cleanup := newFakeSimpleProducer(t, config, "test", 2, server.connect(t, nil))
+	return p, func() {
+		cleanup()
+		server.close()
+	}
+}
+
+func TestDeliveryReport(t *testing.T) {
+	reports := make(chan *DeliveryReport, 10)
+	config := DefaultProducerConfig()
+	config.DeliveryReports = reports
+	p, cleanup := newFakeProduceSimpleProducer(t, &fakeProduceBroker{baseOffset: 100, logAppendTime: -1}, config)
+	defer cleanup()
+
+	var callbackReports []*DeliveryReport
+	callback := func(report *DeliveryReport) {
+		callbackReports = append(callbackReports, report)
+	}
+	messageSet := MessageSet{
+		&Message{Value: []byte("hello"), Timestamp: 1000, callback: callback},
+		&Message{Value: []byte("world"), Timestamp: 2000},
+	}
+	if err := p.flush(messageSet); err != nil {
+		t.Fatalf("flush error: %s", err)
+	}
+
+	if len(callbackReports) != 1 || callbackReports[0].Offset != 100 || string(callbackReports[0].Value) != "hello" {
+		t.Errorf("callback should be called once for the first message with offset 100, got %+v", callbackReports)
+	}
+	close(reports)
+	i := 0
+	for report := range reports {
+		if report.Err != nil || report.Topic != "test" || report.Partition != 2 || report.Offset != int64(100+i) || report.Timestamp != int64(1000*(i+1)) {
+			t.Errorf("unexpected report of message %d: %+v", i, report)
+		}
+		i++
+	}
+	if i != 2 {
+		t.Errorf("expect 2 reports in channel, got %d", i)
+	}
+}
+
+func TestDeliveryReportError(t *testing.T) {
+	config := DefaultProducerConfig()
+	// MESSAGE_TOO_LARGE is not retriable
+	p, cleanup := newFakeProduceSimpleProducer(t, &fakeProduceBroker{errorCode: 10, baseOffset: -1, logAppendTime: -1}, config)
+	defer cleanup()
+
+	var report *DeliveryReport
+	messageSet := MessageSet{&Message{Value: []byte("hello"), callback: func(r *DeliveryReport) { report = r }}}
+	err := p.flush(messageSet)
+	if err != AllError[10] {
+		t.Fatalf("expect MESSAGE_TOO_LARGE, got %v", err)
+	}
+	if report == nil || report.Err != err || report.Offset != -1 {
+		t.Errorf("expect the error reported with offset -1, got %+v", report)
+	}
+}
+
+func TestDeliveryReportLogAppendTime(t *testing.T) {
+	config := DefaultProducerConfig()
+	p, cleanup := newFakeProduceSimpleProducer(t, &fakeProduceBroker{baseOffset: 7, logAppendTime: 123456}, config)
+	defer cleanup()
+
+	var report *DeliveryReport
+	messageSet := MessageSet{&Message{Value: []byte("hello"), Timestamp: 1000, callback: func(r *DeliveryReport) { report = r }}}
+	if err := p.flush(messageSet); err != nil {
+		t.Fatalf("flush error: %s", err)
+	}
+	if report == nil || report.Offset != 7 || report.Timestamp != 123456 {
+		t.Errorf("expect offset 7 and log append time 123456, got %+v", report)
+	}
+}
@@ -0,0 +1,125 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+DescribeConfigs Request (Version: 0) => [resources]
+  resources => resource_type resource_name [config_names]
+    resource_type => INT8
+    resource_name => STRING
+    config_names => STRING
+
+DescribeConfigs Request (Version: 1) => [resources] include_synonyms
+  resources => resource_type resource_name [config_names]
+    resource_type => INT8
+    resource_name => STRING
+    config_names => STRING
+  include_synonyms => BOOLEAN
+
+FIELD	DESCRIPTION
+  resources	An array of config resources to be returned.
+  resource_type	The resource type, 2 for topic and 4 for broker
+  resource_name	The resource name, topic name or broker id
+  config_names	The configuration keys to list, or null to list all configuration keys.
+  include_synonyms	True if we should include all synonyms.
+*/
+
+const (
+	RESOURCE_TYPE_UNKNOWN int8 = 0
+	RESOURCE_TYPE_ANY     int8 = 1
+	RESOURCE_TYPE_TOPIC   int8 = 2
+	RESOURCE_TYPE_GROUP   int8 = 3
+	RESOURCE_TYPE_BROKER  int8 = 4
+)
+
+type DescribeConfigsRequestResource struct {
+	ResourceType int8
+	ResourceName string
+	ConfigNames  []string
+}
+
+type DescribeConfigsRequest struct {
+	RequestHeader   *RequestHeader
+	Resources       []*DescribeConfigsRequestResource
+	IncludeSynonyms bool
+}
+
+func NewDescribeConfigsRequest(apiVersion uint16, clientID string, resources []*DescribeConfigsRequestResource) *DescribeConfigsRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_DescribeConfigs,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &DescribeConfigsRequest{
+		RequestHeader: requestHeader,
+		Resources:     resources,
+	}
+}
+
+func (r *DescribeConfigsRequest) Length() int {
+	l := r.RequestHeader.length()
+	l += 4
+	for _, resource := range r.Resources {
+		l += 1 + 2 + len(resource.ResourceName)
+		l += 4
+		for _, name := range resource.ConfigNames {
+			l += 2 + len(name)
+		}
+	}
+	if r.RequestHeader.ApiVersion >= 1 {
+		l++
+	}
+	return l
+}
+
+func (r *DescribeConfigsRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(len(r.Resources)))
+	offset += 4
+	for _, resource := range r.Resources {
+		payload[offset] = byte(resource.ResourceType)
+		offset++
+
+		binary.BigEndian.PutUint16(payload[offset:], uint16(len(resource.ResourceName)))
+		offset += 2
+		offset += copy(payload[offset:], resource.ResourceName)
+
+		if resource.ConfigNames == nil {
+			binary.BigEndian.PutUint32(payload[offset:], 0xffffffff)
+			offset += 4
+			continue
+		}
+		binary.BigEndian.PutUint32(payload[offset:], uint32(len(resource.ConfigNames)))
+		offset += 4
+		for _, name := range resource.ConfigNames {
+			binary.BigEndian.PutUint16(payload[offset:], uint16(len(name)))
+			offset += 2
+			offset += copy(payload[offset:], name)
+		}
+	}
+
+	if r.RequestHeader.ApiVersion >= 1 && r.IncludeSynonyms {
+		payload[offset] = 1
+	}
+
+	return payload
+}
+
+func (r *DescribeConfigsRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *DescribeConfigsRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
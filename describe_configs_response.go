@@ -0,0 +1,228 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+DescribeConfigs Response (Version: 0) => throttle_time_ms [resources]
+  throttle_time_ms => INT32
+  resources => error_code error_message resource_type resource_name [config_entries]
+    error_code => INT16
+    error_message => NULLABLE_STRING
+    resource_type => INT8
+    resource_name => STRING
+    config_entries => config_name config_value read_only is_default is_sensitive
+      config_name => STRING
+      config_value => NULLABLE_STRING
+      read_only => BOOLEAN
+      is_default => BOOLEAN
+      is_sensitive => BOOLEAN
+
+DescribeConfigs Response (Version: 1) => throttle_time_ms [resources]
+  throttle_time_ms => INT32
+  resources => error_code error_message resource_type resource_name [config_entries]
+    error_code => INT16
+    error_message => NULLABLE_STRING
+    resource_type => INT8
+    resource_name => STRING
+    config_entries => config_name config_value read_only config_source is_sensitive [config_synonyms]
+      config_name => STRING
+      config_value => NULLABLE_STRING
+      read_only => BOOLEAN
+      config_source => INT8
+      is_sensitive => BOOLEAN
+      config_synonyms => config_name config_value config_source
+        config_name => STRING
+        config_value => NULLABLE_STRING
+        config_source => INT8
+
+FIELD	DESCRIPTION
+  throttle_time_ms	Duration in milliseconds for which the request was throttled due to quota violation (Zero if the request did not violate any quota)
+  error_code	Response error code
+  error_message	Response error message
+  resource_type	The resource type
+  resource_name	The resource name
+  config_name	The configuration name
+  config_value	The configuration value, null if it is sensitive
+  read_only	True if the configuration is read-only
+  is_default	True if the configuration is not set
+  config_source	The configuration source
+  is_sensitive	True if this configuration is sensitive
+  config_synonyms	The synonyms for this configuration key, in the order they are applied
+*/
+
+const (
+	CONFIG_SOURCE_UNKNOWN                       int8 = 0
+	CONFIG_SOURCE_TOPIC_CONFIG                  int8 = 1
+	CONFIG_SOURCE_DYNAMIC_BROKER_CONFIG         int8 = 2
+	CONFIG_SOURCE_DYNAMIC_DEFAULT_BROKER_CONFIG int8 = 3
+	CONFIG_SOURCE_STATIC_BROKER_CONFIG          int8 = 4
+	CONFIG_SOURCE_DEFAULT_CONFIG                int8 = 5
+	CONFIG_SOURCE_DYNAMIC_BROKER_LOGGER_CONFIG  int8 = 6
+)
+
+var configSourceNames = map[int8]string{
+	CONFIG_SOURCE_UNKNOWN:                       "UNKNOWN",
+	CONFIG_SOURCE_TOPIC_CONFIG:                  "TOPIC_CONFIG",
+	CONFIG_SOURCE_DYNAMIC_BROKER_CONFIG:         "DYNAMIC_BROKER_CONFIG",
+	CONFIG_SOURCE_DYNAMIC_DEFAULT_BROKER_CONFIG: "DYNAMIC_DEFAULT_BROKER_CONFIG",
+	CONFIG_SOURCE_STATIC_BROKER_CONFIG:          "STATIC_BROKER_CONFIG",
+	CONFIG_SOURCE_DEFAULT_CONFIG:                "DEFAULT_CONFIG",
+	CONFIG_SOURCE_DYNAMIC_BROKER_LOGGER_CONFIG:  "DYNAMIC_BROKER_LOGGER_CONFIG",
+}
+
+// ConfigSourceName returns the readable name of the config source, like TOPIC_CONFIG
+func ConfigSourceName(source int8) string {
+	if name, ok := configSourceNames[source]; ok {
+		return name
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", source)
+}
+
+type ConfigSynonym struct {
+	ConfigName   string
+	ConfigValue  string
+	ConfigSource int8
+}
+
+// ConfigEntry is one config of the resource. v0 response has no config_source, which is set to DEFAULT_CONFIG or UNKNOWN by is_default
+type ConfigEntry struct {
+	ConfigName   string
+	ConfigValue  string
+	ReadOnly     bool
+	IsDefault    bool
+	ConfigSource int8
+	IsSensitive  bool
+	Synonyms     []*ConfigSynonym
+}
+
+type DescribeConfigsResponseResource struct {
+	ErrorCode     int16
+	ErrorMessage  string
+	ResourceType  int8
+	ResourceName  string
+	ConfigEntries []*ConfigEntry
+}
+
+type DescribeConfigsResponse struct {
+	CorrelationID  uint32
+	ThrottleTimeMS int32
+	Resources      []*DescribeConfigsResponseResource
+}
+
+// decodeNullableString returns the string and the new offset. null is decoded as empty string
+func decodeNullableString(payload []byte, offset int) (string, int) {
+	l := int(int16(binary.BigEndian.Uint16(payload[offset:])))
+	offset += 2
+	if l <= 0 {
+		return "", offset
+	}
+	return string(payload[offset : offset+l]), offset + l
+}
+
+func NewDescribeConfigsResponse(payload []byte, version uint16) (*DescribeConfigsResponse, error) {
+	var (
+		r      *DescribeConfigsResponse = &DescribeConfigsResponse{}
+		offset int                      = 0
+		l      int                      = 0
+		err    error
+	)
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("DescribeConfigs reseponse length did not match: %d!=%d", responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	r.ThrottleTimeMS = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	l = int(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	r.Resources = make([]*DescribeConfigsResponseResource, l)
+	for i := range r.Resources {
+		resource := &DescribeConfigsResponseResource{}
+		r.Resources[i] = resource
+
+		resource.ErrorCode = int16(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		resource.ErrorMessage, offset = decodeNullableString(payload, offset)
+
+		resource.ResourceType = int8(payload[offset])
+		offset++
+
+		l = int(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		resource.ResourceName = string(payload[offset : offset+l])
+		offset += l
+
+		if err == nil && resource.ErrorCode != 0 {
+			err = configResourceError(resource.ResourceName, resource.ErrorCode, resource.ErrorMessage)
+		}
+
+		l = int(binary.BigEndian.Uint32(payload[offset:]))
+		offset += 4
+		resource.ConfigEntries = make([]*ConfigEntry, l)
+		for j := range resource.ConfigEntries {
+			entry := &ConfigEntry{}
+			resource.ConfigEntries[j] = entry
+
+			l = int(binary.BigEndian.Uint16(payload[offset:]))
+			offset += 2
+			entry.ConfigName = string(payload[offset : offset+l])
+			offset += l
+
+			entry.ConfigValue, offset = decodeNullableString(payload, offset)
+
+			entry.ReadOnly = payload[offset] != 0
+			offset++
+
+			if version == 0 {
+				entry.IsDefault = payload[offset] != 0
+				if entry.IsDefault {
+					entry.ConfigSource = CONFIG_SOURCE_DEFAULT_CONFIG
+				}
+			} else {
+				entry.ConfigSource = int8(payload[offset])
+				entry.IsDefault = entry.ConfigSource == CONFIG_SOURCE_DEFAULT_CONFIG
+			}
+			offset++
+
+			entry.IsSensitive = payload[offset] != 0
+			offset++
+
+			if version >= 1 {
+				l = int(binary.BigEndian.Uint32(payload[offset:]))
+				offset += 4
+				entry.Synonyms = make([]*ConfigSynonym, l)
+				for k := range entry.Synonyms {
+					synonym := &ConfigSynonym{}
+					entry.Synonyms[k] = synonym
+
+					l = int(binary.BigEndian.Uint16(payload[offset:]))
+					offset += 2
+					synonym.ConfigName = string(payload[offset : offset+l])
+					offset += l
+
+					synonym.ConfigValue, offset = decodeNullableString(payload, offset)
+
+					synonym.ConfigSource = int8(payload[offset])
+					offset++
+				}
+			}
+		}
+	}
+
+	return r, err
+}
+
+func configResourceError(resourceName string, errorCode int16, errorMessage string) error {
+	if errorMessage == "" {
+		return fmt.Errorf("%s: %s", resourceName, getErrorFromErrorCode(errorCode))
+	}
+	return fmt.Errorf("%s: %s %s", resourceName, getErrorFromErrorCode(errorCode), errorMessage)
+}
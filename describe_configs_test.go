@@ -0,0 +1,82 @@
+package healer
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestDescribeConfigsResponse(t *testing.T) {
+	type entry struct {
+		name      string
+		value     string
+		null      bool
+		flag      byte // is_default in v0, config_source in v1
+		sensitive bool
+	}
+	encode := func(version uint16, resourceName string, entries []entry) []byte {
+		// length correlation_id throttle_time_ms [resources]
+		payload := make([]byte, 4+4+4+4)
+		binary.BigEndian.PutUint32(payload[12:], 1)
+
+		b := make([]byte, 2+2+1+2+len(resourceName)+4)
+		binary.BigEndian.PutUint16(b[2:], 0xffff)
+		b[4] = byte(RESOURCE_TYPE_TOPIC)
+		binary.BigEndian.PutUint16(b[5:], uint16(len(resourceName)))
+		copy(b[7:], resourceName)
+		binary.BigEndian.PutUint32(b[7+len(resourceName):], uint32(len(entries)))
+		payload = append(payload, b...)
+
+		for _, e := range entries {
+			b := make([]byte, 2+len(e.name)+2)
+			binary.BigEndian.PutUint16(b, uint16(len(e.name)))
+			copy(b[2:], e.name)
+			if e.null {
+				binary.BigEndian.PutUint16(b[2+len(e.name):], 0xffff)
+			} else {
+				binary.BigEndian.PutUint16(b[2+len(e.name):], uint16(len(e.value)))
+				b = append(b, e.value...)
+			}
+			var sensitive byte
+			if e.sensitive {
+				sensitive = 1
+			}
+			b = append(b, 0, e.flag, sensitive)
+			if version >= 1 {
+				// no synonyms
+				b = append(b, 0, 0, 0, 0)
+			}
+			payload = append(payload, b...)
+		}
+		binary.BigEndian.PutUint32(payload, uint32(len(payload)-4))
+		return payload
+	}
+
+	for version, entries := range map[uint16][]entry{
+		0: {{"retention.ms", "3600000", false, 0, false}, {"cleanup.policy", "delete", false, 1, false}, {"ssl.key.password", "", true, 1, true}},
+		1: {{"retention.ms", "3600000", false, byte(CONFIG_SOURCE_TOPIC_CONFIG), false}, {"cleanup.policy", "delete", false, byte(CONFIG_SOURCE_DEFAULT_CONFIG), false}, {"ssl.key.password", "", true, byte(CONFIG_SOURCE_STATIC_BROKER_CONFIG), true}},
+	} {
+		response, err := NewDescribeConfigsResponse(encode(version, "test", entries), version)
+		if err != nil {
+			t.Fatalf("decode v%d response error: %s", version, err)
+		}
+		if len(response.Resources) != 1 || response.Resources[0].ResourceName != "test" {
+			t.Fatalf("expect resource test in v%d response", version)
+		}
+		configs := response.Resources[0].ConfigEntries
+		if len(configs) != 3 {
+			t.Fatalf("expect 3 configs in v%d response, got %d", version, len(configs))
+		}
+		if configs[0].ConfigValue != "3600000" || configs[0].IsDefault {
+			t.Errorf("v%d: unexpected retention.ms %+v", version, configs[0])
+		}
+		if !configs[1].IsDefault || configs[1].ConfigSource != CONFIG_SOURCE_DEFAULT_CONFIG {
+			t.Errorf("v%d: expect cleanup.policy to be default, got %+v", version, configs[1])
+		}
+		if !configs[2].IsSensitive || configs[2].ConfigValue != "" {
+			t.Errorf("v%d: expect ssl.key.password to be sensitive without value, got %+v", version, configs[2])
+		}
+		if version == 1 && configs[0].ConfigSource != CONFIG_SOURCE_TOPIC_CONFIG {
+			t.Errorf("expect source of retention.ms to be TOPIC_CONFIG, got %s", ConfigSourceName(configs[0].ConfigSource))
+		}
+	}
+}
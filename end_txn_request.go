@@ -0,0 +1,76 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+EndTxn Request (Version: 0) => transactional_id producer_id producer_epoch committed
+  transactional_id => STRING
+  producer_id => INT64
+  producer_epoch => INT16
+  committed => BOOLEAN
+
+FIELD	DESCRIPTION
+  transactional_id	The ID of the transaction to end.
+  producer_id	The producer ID.
+  producer_epoch	The current epoch associated with the producer.
+  committed	True if the transaction was committed, false if it was aborted.
+*/
+
+type EndTxnRequest struct {
+	RequestHeader   *RequestHeader
+	TransactionalID string
+	ProducerID      int64
+	ProducerEpoch   int16
+	Committed       bool
+}
+
+func NewEndTxnRequest(apiVersion uint16, clientID, transactionalID string, producerID int64, producerEpoch int16, committed bool) *EndTxnRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_EndTxn,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &EndTxnRequest{requestHeader, transactionalID, producerID, producerEpoch, committed}
+}
+
+func (r *EndTxnRequest) Length() int {
+	return r.RequestHeader.length() + 2 + len(r.TransactionalID) + 8 + 2 + 1
+}
+
+func (r *EndTxnRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(len(r.TransactionalID)))
+	offset += 2
+	offset += copy(payload[offset:], r.TransactionalID)
+
+	binary.BigEndian.PutUint64(payload[offset:], uint64(r.ProducerID))
+	offset += 8
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(r.ProducerEpoch))
+	offset += 2
+
+	if r.Committed {
+		payload[offset] = 1
+	}
+
+	return payload
+}
+
+func (r *EndTxnRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *EndTxnRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
@@ -0,0 +1,45 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+EndTxn Response (Version: 0) => throttle_time_ms error_code
+  throttle_time_ms => INT32
+  error_code => INT16
+
+FIELD	DESCRIPTION
+  throttle_time_ms	The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+  error_code	The error code, or 0 if there was no error.
+*/
+
+type EndTxnResponse struct {
+	CorrelationID  uint32
+	ThrottleTimeMS int32
+	ErrorCode      int16
+}
+
+func NewEndTxnResponse(payload []byte) (*EndTxnResponse, error) {
+	r := &EndTxnResponse{}
+	offset := 0
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("EndTxn reseponse length did not match: %d!=%d", responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	r.ThrottleTimeMS = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	r.ErrorCode = int16(binary.BigEndian.Uint16(payload[offset:]))
+
+	if r.ErrorCode != 0 {
+		return r, getErrorFromErrorCode(r.ErrorCode)
+	}
+	return r, nil
+}
@@ -395,7 +395,7 @@ func init() {
 		Errorcode: 51,
 		ErrorMsg:  "CONCURRENT_TRANSACTIONS",
 		ErrorDesc: "The producer attempted to update a transaction while another concurrent operation on the same transaction was ongoing",
-		Retriable: false,
+		Retriable: true,
 	}
 
 	AllError[52] = &Error{
@@ -461,4 +461,11 @@ func init() {
 		Retriable: false,
 	}
 
+	AllError[76] = &Error{
+		Errorcode: 76,
+		ErrorMsg:  "UNSUPPORTED_COMPRESSION_TYPE",
+		ErrorDesc: "The requesting client does not support the compression type of given partition.",
+		Retriable: false,
+	}
+
 }
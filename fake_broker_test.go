@@ -0,0 +1,340 @@
+package healer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRequest is a request read by fakeBroker. body is what follows the request header
+type fakeRequest struct {
+	apiKey        uint16
+	apiVersion    uint16
+	correlationID uint32
+	clientID      string
+	body          []byte
+}
+
+// fakeConn reads size-delimited requests from a connection of fakeBroker and writes responses to it
+type fakeConn struct {
+	net.Conn
+}
+
+// readRequest returns nil once the connection is closed
+func (c *fakeConn) readRequest() *fakeRequest {
+	lengthBuf := make([]byte, 4)
+	if _, err := io.ReadFull(c, lengthBuf); err != nil {
+		return nil
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(lengthBuf))
+	if _, err := io.ReadFull(c, payload); err != nil {
+		return nil
+	}
+	clientIDLength := int(binary.BigEndian.Uint16(payload[8:]))
+	return &fakeRequest{
+		apiKey:        binary.BigEndian.Uint16(payload),
+		apiVersion:    binary.BigEndian.Uint16(payload[2:]),
+		correlationID: binary.BigEndian.Uint32(payload[4:]),
+		clientID:      string(payload[10 : 10+clientIDLength]),
+		body:          payload[10+clientIDLength:],
+	}
+}
+
+// writeResponse writes the response body after the size and the correlation id
+func (c *fakeConn) writeResponse(correlationID uint32, response []byte) error {
+	payload := make([]byte, 8+len(response))
+	binary.BigEndian.PutUint32(payload, uint32(4+len(response)))
+	binary.BigEndian.PutUint32(payload[4:], correlationID)
+	copy(payload[8:], response)
+	_, err := c.Write(payload)
+	return err
+}
+
+// serve answers the requests one by one with handler, until the connection is closed or handler returns nil.
+// the request is not answered if handler returns an empty response, like a produce request with acks=0
+func (c *fakeConn) serve(handler func(request *fakeRequest) []byte) {
+	for {
+		request := c.readRequest()
+		if request == nil {
+			return
+		}
+		response := handler(request)
+		if response == nil {
+			return
+		}
+		if len(response) > 0 && c.writeResponse(request.correlationID, response) != nil {
+			return
+		}
+	}
+}
+
+// fakeBroker listens on a random local port and serves each connection in its own goroutine
+type fakeBroker struct {
+	listener net.Listener
+}
+
+// newFakeBroker starts a fakeBroker answering the requests of each connection one by one with handler
+func newFakeBroker(t *testing.T, handler func(request *fakeRequest) []byte) *fakeBroker {
+	return newFakeBrokerWithConn(t, func(conn *fakeConn) {
+		conn.serve(handler)
+	})
+}
+
+// newFakeBrokerWithConn starts a fakeBroker serving each connection with serve, for the brokers which do not answer the requests one by one
+func newFakeBrokerWithConn(t *testing.T, serve func(conn *fakeConn)) *fakeBroker {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(&fakeConn{conn})
+			}()
+		}
+	}()
+	return &fakeBroker{listener: listener}
+}
+
+func (b *fakeBroker) address() string {
+	return b.listener.Addr().String()
+}
+
+func (b *fakeBroker) port() int32 {
+	return int32(b.listener.Addr().(*net.TCPAddr).Port)
+}
+
+// connect returns a Broker connected to the fake broker. config is DefaultBrokerConfig with 5s timeout if nil
+func (b *fakeBroker) connect(t *testing.T, config *BrokerConfig) *Broker {
+	if config == nil {
+		config = DefaultBrokerConfig()
+		config.TimeoutMS = 5000
+	}
+	broker, err := NewBroker(b.address(), -1, config)
+	if err != nil {
+		b.close()
+		t.Fatal(err)
+	}
+	return broker
+}
+
+func (b *fakeBroker) close() {
+	b.listener.Close()
+}
+
+// fakeApiVersionsResponse returns the ApiVersions response supporting the apis from version 0 up to the max versions
+func fakeApiVersionsResponse(maxVersions map[uint16]uint16) []byte {
+	response := make([]byte, 6+6*len(maxVersions))
+	binary.BigEndian.PutUint32(response[2:], uint32(len(maxVersions)))
+	offset := 6
+	for apiKey, maxVersion := range maxVersions {
+		binary.BigEndian.PutUint16(response[offset:], apiKey)
+		binary.BigEndian.PutUint16(response[offset+4:], maxVersion)
+		offset += 6
+	}
+	return response
+}
+
+// fakeDecoder reads the fields of a request body one by one
+type fakeDecoder struct {
+	body []byte
+}
+
+func (d *fakeDecoder) readInt16() int16 {
+	v := int16(binary.BigEndian.Uint16(d.body))
+	d.body = d.body[2:]
+	return v
+}
+
+func (d *fakeDecoder) readInt32() int32 {
+	v := int32(binary.BigEndian.Uint32(d.body))
+	d.body = d.body[4:]
+	return v
+}
+
+func (d *fakeDecoder) readInt64() int64 {
+	v := int64(binary.BigEndian.Uint64(d.body))
+	d.body = d.body[8:]
+	return v
+}
+
+// readString returns "" for a null string
+func (d *fakeDecoder) readString() string {
+	length := int(d.readInt16())
+	if length < 0 {
+		return ""
+	}
+	s := string(d.body[:length])
+	d.body = d.body[length:]
+	return s
+}
+
+func (d *fakeDecoder) readBytes(length int) []byte {
+	b := d.body[:length]
+	d.body = d.body[length:]
+	return b
+}
+
+// fakeEncoder writes the fields of a response body one by one
+type fakeEncoder struct {
+	bytes.Buffer
+}
+
+func (e *fakeEncoder) putInt16(v int16) {
+	binary.Write(&e.Buffer, binary.BigEndian, v)
+}
+
+func (e *fakeEncoder) putInt32(v int32) {
+	binary.Write(&e.Buffer, binary.BigEndian, v)
+}
+
+func (e *fakeEncoder) putInt64(v int64) {
+	binary.Write(&e.Buffer, binary.BigEndian, v)
+}
+
+func (e *fakeEncoder) putString(s string) {
+	e.putInt16(int16(len(s)))
+	e.WriteString(s)
+}
+
+// fakeProducePartition is the record batch of one partition in a produce request
+type fakeProducePartition struct {
+	topic     string
+	partition int32
+	batch     []byte
+}
+
+// fakeProduceRequest is a produce request of version 3
+type fakeProduceRequest struct {
+	transactionalID string
+	acks            int16
+	timeout         int32
+	partitions      []fakeProducePartition
+}
+
+// parseFakeProduceRequest parses the body of a produce request of version 3
+func parseFakeProduceRequest(body []byte) *fakeProduceRequest {
+	// transactional_id acks timeout [topic [partition size batch]]
+	d := &fakeDecoder{body}
+	request := &fakeProduceRequest{
+		transactionalID: d.readString(),
+		acks:            d.readInt16(),
+		timeout:         d.readInt32(),
+	}
+	for topicCount := d.readInt32(); topicCount > 0; topicCount-- {
+		topic := d.readString()
+		for partitionCount := d.readInt32(); partitionCount > 0; partitionCount-- {
+			partition := d.readInt32()
+			batch := d.readBytes(int(d.readInt32()))
+			request.partitions = append(request.partitions, fakeProducePartition{topic, partition, batch})
+		}
+	}
+	return request
+}
+
+// fakeProduceResult is the result of one partition in a produce response
+type fakeProduceResult struct {
+	topic         string
+	partition     int32
+	errorCode     int16
+	baseOffset    int64
+	logAppendTime int64
+}
+
+// fakeProduceResponse returns the produce response of version 3. results of the same topic are grouped together,
+// in the order the topics first appear
+func fakeProduceResponse(results []fakeProduceResult) []byte {
+	var topics []string
+	byTopic := make(map[string][]fakeProduceResult)
+	for _, result := range results {
+		if _, ok := byTopic[result.topic]; !ok {
+			topics = append(topics, result.topic)
+		}
+		byTopic[result.topic] = append(byTopic[result.topic], result)
+	}
+
+	// [topic [partition error_code base_offset log_append_time]] throttle_time_ms
+	var e fakeEncoder
+	e.putInt32(int32(len(topics)))
+	for _, topic := range topics {
+		e.putString(topic)
+		e.putInt32(int32(len(byTopic[topic])))
+		for _, result := range byTopic[topic] {
+			e.putInt32(result.partition)
+			e.putInt16(result.errorCode)
+			e.putInt64(result.baseOffset)
+			e.putInt64(result.logAppendTime)
+		}
+	}
+	e.putInt32(0)
+	return e.Bytes()
+}
+
+// fakeMetadataResponse returns the metadata response of version 0. node id of each broker is its index in nodes,
+// and partition i of the topic is led by leaders[i] and replicated to all nodes
+func fakeMetadataResponse(nodes []*fakeBroker, topic string, leaders []int32) []byte {
+	// [node_id host port] [error_code topic [error_code partition leader [replicas] [isr]]]
+	var e fakeEncoder
+	e.putInt32(int32(len(nodes)))
+	for nodeID, node := range nodes {
+		e.putInt32(int32(nodeID))
+		e.putString("127.0.0.1")
+		e.putInt32(node.port())
+	}
+	e.putInt32(1)
+	e.putInt16(0)
+	e.putString(topic)
+	e.putInt32(int32(len(leaders)))
+	for partition, leader := range leaders {
+		e.putInt16(0)
+		e.putInt32(int32(partition))
+		e.putInt32(leader)
+		for i := 0; i < 2; i++ {
+			e.putInt32(int32(len(nodes)))
+			for nodeID := range nodes {
+				e.putInt32(int32(nodeID))
+			}
+		}
+	}
+	return e.Bytes()
+}
+
+// newFakeSimpleProducer returns a SimpleProducer of topic[partition] without the idle timer closing it.
+// the leader is found from config.BootstrapServers if leader is nil. cleanup stops the timers and closes the leader
+func newFakeSimpleProducer(t *testing.T, config *ProducerConfig, topic string, partition int32, leader *Broker) (*SimpleProducer, func()) {
+	p := &SimpleProducer{
+		config:     config,
+		topic:      topic,
+		partition:  partition,
+		compressor: NewCompressor("none"),
+		mutex:      &sync.Mutex{},
+		timer:      time.NewTimer(time.Hour),
+		buffer:     newBufferMemory(config),
+	}
+	if leader == nil {
+		var err error
+		if leader, err = p.createLeader(); err != nil {
+			t.Fatalf("create leader error: %s", err)
+		}
+	}
+	p.setLeader(leader)
+	return p, func() {
+		p.timer.Stop()
+		p.mutex.Lock()
+		if p.lingerTimer != nil {
+			p.lingerTimer.Stop()
+		}
+		leader := p.leader
+		p.mutex.Unlock()
+		leader.Close()
+	}
+}
@@ -2,6 +2,7 @@ package healer
 
 import (
 	"encoding/binary"
+	"math"
 
 	"github.com/golang/glog"
 )
@@ -30,6 +31,49 @@ TopicName		The name of the topic.
 Partition		The id of the partition the fetch is for.
 FetchOffset		The offset to begin this fetch from.
 MaxBytes		The maximum bytes to include in the message set for this partition. This helps bound the size of the response.
+
+Fetch Request (Version: 4) => replica_id max_wait_time min_bytes max_bytes isolation_level [topics]
+  replica_id => INT32
+  max_wait_time => INT32
+  min_bytes => INT32
+  max_bytes => INT32
+  isolation_level => INT8
+  topics => topic [partitions]
+    topic => STRING
+    partitions => partition fetch_offset max_bytes
+      partition => INT32
+      fetch_offset => INT64
+      max_bytes => INT32
+
+max_bytes(request level)	Maximum bytes to accumulate in the response. Note that this is not an absolute maximum, if the first message in the first non-empty partition of the fetch is larger than this value, the message will still be returned to ensure that progress can be made.
+isolation_level		This setting controls the visibility of transactional records. Using READ_UNCOMMITTED (isolation_level = 0) makes all records visible. With READ_COMMITTED (isolation_level = 1), non-transactional and COMMITTED transactional records are visible.
+
+Fetch Request (Version: 10) => replica_id max_wait_time min_bytes max_bytes isolation_level session_id session_epoch [topics] [forgotten_topics_data]
+  replica_id => INT32
+  max_wait_time => INT32
+  min_bytes => INT32
+  max_bytes => INT32
+  isolation_level => INT8
+  session_id => INT32
+  session_epoch => INT32
+  topics => topic [partitions]
+    topic => STRING
+    partitions => partition current_leader_epoch fetch_offset log_start_offset partition_max_bytes
+      partition => INT32
+      current_leader_epoch => INT32
+      fetch_offset => INT64
+      log_start_offset => INT64
+      partition_max_bytes => INT32
+  forgotten_topics_data => topic [partitions]
+    topic => STRING
+    partitions => INT32
+
+session_id			The fetch session ID. healer does not use fetch session, it sends 0
+session_epoch		The fetch session epoch. healer sends -1, which means full fetch without session
+current_leader_epoch	The current leader epoch of the partition. healer sends -1, which means unknown
+log_start_offset	The earliest available offset of the follower replica. healer sends -1 as a consumer
+forgotten_topics_data	In an incremental fetch request, the partitions to remove. healer sends empty array
+zstd compressed records are only returned by version 10+
 */
 
 type PartitionBlock struct {
@@ -38,20 +82,22 @@ type PartitionBlock struct {
 	MaxBytes    int32
 }
 
-// version 0
+// version 0 & 4 & 10
 type FetchRequest struct {
-	RequestHeader *RequestHeader
-	ReplicaId     int32
-	MaxWaitTime   int32
-	MinBytes      int32
-	Topics        map[string][]*PartitionBlock
+	RequestHeader  *RequestHeader
+	ReplicaId      int32
+	MaxWaitTime    int32
+	MinBytes       int32
+	MaxBytes       int32
+	IsolationLevel int8
+	Topics         map[string][]*PartitionBlock
 }
 
 // TODO all partitions should have the SAME maxbytes?
-func NewFetchRequest(clientID string, maxWaitTime int32, minBytes int32) *FetchRequest {
+func NewFetchRequest(apiVersion uint16, clientID string, maxWaitTime int32, minBytes int32) *FetchRequest {
 	requestHeader := &RequestHeader{
 		ApiKey:     API_FetchRequest,
-		ApiVersion: 0,
+		ApiVersion: apiVersion,
 		ClientId:   clientID,
 	}
 
@@ -62,6 +108,7 @@ func NewFetchRequest(clientID string, maxWaitTime int32, minBytes int32) *FetchR
 		ReplicaId:     -1,
 		MaxWaitTime:   maxWaitTime,
 		MinBytes:      minBytes,
+		MaxBytes:      math.MaxInt32,
 		Topics:        topics,
 	}
 }
@@ -75,7 +122,7 @@ func (fetchRequest *FetchRequest) addPartition(topic string, partitionID int32,
 	}
 
 	if value, ok := fetchRequest.Topics[topic]; ok {
-		value = append(value, partitionBlock)
+		fetchRequest.Topics[topic] = append(value, partitionBlock)
 	} else {
 		fetchRequest.Topics[topic] = []*PartitionBlock{partitionBlock}
 	}
@@ -83,10 +130,29 @@ func (fetchRequest *FetchRequest) addPartition(topic string, partitionID int32,
 
 func (fetchRequest *FetchRequest) Encode() []byte {
 	requestLength := fetchRequest.RequestHeader.length() + 4 + 4 + 4
+	if fetchRequest.RequestHeader.ApiVersion >= 3 {
+		requestLength += 4
+	}
+	if fetchRequest.RequestHeader.ApiVersion >= 4 {
+		requestLength++
+	}
+	if fetchRequest.RequestHeader.ApiVersion >= 7 {
+		requestLength += 8
+	}
+	partitionBlockLength := 16
+	if fetchRequest.RequestHeader.ApiVersion >= 5 {
+		partitionBlockLength += 8
+	}
+	if fetchRequest.RequestHeader.ApiVersion >= 9 {
+		partitionBlockLength += 4
+	}
 	requestLength += 4
 	for topicname, partitionBlocks := range fetchRequest.Topics {
 		requestLength += 2 + len(topicname)
-		requestLength += 4 + len(partitionBlocks)*16
+		requestLength += 4 + len(partitionBlocks)*partitionBlockLength
+	}
+	if fetchRequest.RequestHeader.ApiVersion >= 7 {
+		requestLength += 4
 	}
 
 	payload := make([]byte, requestLength+4)
@@ -103,6 +169,21 @@ func (fetchRequest *FetchRequest) Encode() []byte {
 	offset += 4
 	binary.BigEndian.PutUint32(payload[offset:], uint32(fetchRequest.MinBytes))
 	offset += 4
+	if fetchRequest.RequestHeader.ApiVersion >= 3 {
+		binary.BigEndian.PutUint32(payload[offset:], uint32(fetchRequest.MaxBytes))
+		offset += 4
+	}
+	if fetchRequest.RequestHeader.ApiVersion >= 4 {
+		payload[offset] = byte(fetchRequest.IsolationLevel)
+		offset++
+	}
+	if fetchRequest.RequestHeader.ApiVersion >= 7 {
+		// session_id 0 and session_epoch -1
+		binary.BigEndian.PutUint32(payload[offset:], 0)
+		offset += 4
+		binary.BigEndian.PutUint32(payload[offset:], 0xffffffff)
+		offset += 4
+	}
 
 	binary.BigEndian.PutUint32(payload[offset:], uint32(len(fetchRequest.Topics)))
 	offset += 4
@@ -117,12 +198,24 @@ func (fetchRequest *FetchRequest) Encode() []byte {
 		for _, partitionBlock := range partitionBlocks {
 			binary.BigEndian.PutUint32(payload[offset:], uint32(partitionBlock.Partition))
 			offset += 4
+			if fetchRequest.RequestHeader.ApiVersion >= 9 {
+				binary.BigEndian.PutUint32(payload[offset:], 0xffffffff)
+				offset += 4
+			}
 			binary.BigEndian.PutUint64(payload[offset:], uint64(partitionBlock.FetchOffset))
 			offset += 8
+			if fetchRequest.RequestHeader.ApiVersion >= 5 {
+				binary.BigEndian.PutUint64(payload[offset:], 0xffffffffffffffff)
+				offset += 8
+			}
 			binary.BigEndian.PutUint32(payload[offset:], uint32(partitionBlock.MaxBytes))
 			offset += 4
 		}
 	}
+	if fetchRequest.RequestHeader.ApiVersion >= 7 {
+		// empty forgotten_topics_data
+		binary.BigEndian.PutUint32(payload[offset:], 0)
+	}
 	return payload
 }
 
@@ -2,6 +2,7 @@ package healer
 
 import (
 	"encoding/binary"
+	"sort"
 
 	"github.com/golang/glog"
 )
@@ -20,27 +21,141 @@ MessageSet				The message data fetched from this partition, in the format descri
 MessageSetSizeBytes			The size in bytes of the message set for this partition
 Partition				The id of the partition this response is for.
 TopicName				The name of the topic this response entry is for.
+
+Fetch Response (Version: 4) => throttle_time_ms [responses]
+  throttle_time_ms => INT32
+  responses => topic [partition_responses]
+    topic => STRING
+    partition_responses => partition_header record_set
+      partition_header => partition error_code high_watermark last_stable_offset [aborted_transactions]
+        partition => INT32
+        error_code => INT16
+        high_watermark => INT64
+        last_stable_offset => INT64
+        aborted_transactions => producer_id first_offset
+          producer_id => INT64
+          first_offset => INT64
+      record_set => RECORDS
+
+last_stable_offset		The last stable offset (or LSO) of the partition. This is the last offset such that the state of all transactional records prior to this offset have been decided (ABORTED or COMMITTED)
+aborted_transactions	The aborted transactions in the fetched range. It is null if isolation_level is READ_UNCOMMITTED
+
+Fetch Response (Version: 10) => throttle_time_ms error_code session_id [responses]
+  throttle_time_ms => INT32
+  error_code => INT16
+  session_id => INT32
+  responses => topic [partition_responses]
+    topic => STRING
+    partition_responses => partition_header record_set
+      partition_header => partition error_code high_watermark last_stable_offset log_start_offset [aborted_transactions]
+        partition => INT32
+        error_code => INT16
+        high_watermark => INT64
+        last_stable_offset => INT64
+        log_start_offset => INT64
+        aborted_transactions => producer_id first_offset
+          producer_id => INT64
+          first_offset => INT64
+      record_set => RECORDS
+
+error_code			The top level response error code, added in version 7
+session_id			The fetch session ID, added in version 7
+log_start_offset	The earliest available offset of the partition, added in version 5
 */
 
+type AbortedTransaction struct {
+	ProducerID  int64
+	FirstOffset int64
+}
+
 // PartitionResponse stores partitionID and MessageSet in the partition
 type PartitionResponse struct {
 	Partition           int32
 	ErrorCode           int16
 	HighwaterMarkOffset int64
+	LastStableOffset    int64
+	AbortedTransactions []*AbortedTransaction
 	MessageSetSizeBytes int32
 	MessageSet          MessageSet
 }
 
 // FetchResponse stores topicname and arrya of PartitionResponse
 type FetchResponse struct {
-	CorrelationID int32
-	Responses     []struct {
+	CorrelationID  int32
+	ThrottleTimeMS int32
+	Responses      []struct {
 		TopicName          string
 		PartitionResponses []PartitionResponse
 	}
 }
 
+// control record key is version(int16) and type(int16)
+const (
+	CONTROL_TYPE_ABORT  int16 = 0
+	CONTROL_TYPE_COMMIT int16 = 1
+)
+
+// offsets of fields in the RecordBatch header
+const (
+	batchAttributesOffset      = 21
+	batchLastOffsetDeltaOffset = 23
+	batchProducerIDOffset      = 43
+)
+
+// abortedTransactionFilter tells which batches belong to aborted transactions, as the java consumer does.
+// the producer is marked aborted once its batches reach the first offset of its aborted transaction, and unmarked by the ABORT marker
+type abortedTransactionFilter struct {
+	abortedTransactions []*AbortedTransaction
+	abortedProducerIDs  map[int64]bool
+}
+
+func newAbortedTransactionFilter(abortedTransactions []*AbortedTransaction) *abortedTransactionFilter {
+	sort.Slice(abortedTransactions, func(i, j int) bool {
+		return abortedTransactions[i].FirstOffset < abortedTransactions[j].FirstOffset
+	})
+	return &abortedTransactionFilter{
+		abortedTransactions: abortedTransactions,
+		abortedProducerIDs:  make(map[int64]bool),
+	}
+}
+
+// skip tells if the RecordBatch should be dropped. control batches are always dropped.
+// the filter could be nil, which means isolation level is read_uncommitted
+func (f *abortedTransactionFilter) skip(batch []byte) bool {
+	if len(batch) < recordBatchHeaderLength || batch[magicOffset] != 2 {
+		return false
+	}
+	baseOffset := int64(binary.BigEndian.Uint64(batch))
+	attributes := int16(binary.BigEndian.Uint16(batch[batchAttributesOffset:]))
+	producerID := int64(binary.BigEndian.Uint64(batch[batchProducerIDOffset:]))
+	isControl := attributes&controlMask != 0
+
+	if f == nil {
+		return isControl
+	}
+
+	for len(f.abortedTransactions) > 0 && f.abortedTransactions[0].FirstOffset <= baseOffset {
+		f.abortedProducerIDs[f.abortedTransactions[0].ProducerID] = true
+		f.abortedTransactions = f.abortedTransactions[1:]
+	}
+
+	if isControl {
+		if f.abortedProducerIDs[producerID] {
+			if recordBatch, err := DecodeToRecordBatch(batch); err == nil && len(recordBatch.Records) > 0 {
+				key := recordBatch.Records[0].Key
+				if len(key) >= 4 && int16(binary.BigEndian.Uint16(key[2:])) == CONTROL_TYPE_ABORT {
+					delete(f.abortedProducerIDs, producerID)
+				}
+			}
+		}
+		return true
+	}
+
+	return attributes&transactionalMask != 0 && f.abortedProducerIDs[producerID]
+}
+
 type FetchResponseStreamDecoder struct {
+	version       uint16
 	depositBuffer []byte
 	totalLength   int
 	length        int
@@ -79,7 +194,8 @@ func (streamDecoder *FetchResponseStreamDecoder) read(n int) ([]byte, int) {
 	return rst, length
 }
 
-func (streamDecoder *FetchResponseStreamDecoder) encodeMessageSet(topicName string, partitionID int32, messageSetSizeBytes int32) error {
+// encodeMessageSet decodes messages and sends them to the messages channel. batches that filter skips are replaced by placeholders
+func (streamDecoder *FetchResponseStreamDecoder) encodeMessageSet(topicName string, partitionID int32, messageSetSizeBytes int32, filter *abortedTransactionFilter) error {
 	var (
 		//messageOffset int64
 		messageSize int32
@@ -134,6 +250,19 @@ func (streamDecoder *FetchResponseStreamDecoder) encodeMessageSet(topicName stri
 
 		offset += messageSize
 
+		if filter.skip(value) {
+			lastOffset := int64(binary.BigEndian.Uint64(value)) + int64(int32(binary.BigEndian.Uint32(value[batchLastOffsetDeltaOffset:])))
+			glog.V(10).Infof("skip batch of %s[%d] to offset %d", topicName, partitionID, lastOffset)
+			streamDecoder.messages <- &FullMessage{
+				TopicName:   topicName,
+				PartitionID: partitionID,
+				Message:     &Message{Offset: lastOffset},
+				skipped:     true,
+			}
+			hasAtLeastOneMessage = true
+			continue
+		}
+
 		messageSet, err := DecodeToMessageSet(value)
 
 		if err != nil {
@@ -165,6 +294,10 @@ func (streamDecoder *FetchResponseStreamDecoder) encodePartitionResponse(topicNa
 		n      int
 	)
 
+	if streamDecoder.version >= 4 {
+		return streamDecoder.encodePartitionResponseV4(topicName)
+	}
+
 	buffer, n = streamDecoder.read(18)
 
 	if n < 18 {
@@ -182,10 +315,65 @@ func (streamDecoder *FetchResponseStreamDecoder) encodePartitionResponse(topicNa
 
 	messageSetSizeBytes = int32(binary.BigEndian.Uint32((buffer[14:])))
 
-	err = streamDecoder.encodeMessageSet(topicName, partition, messageSetSizeBytes)
+	err = streamDecoder.encodeMessageSet(topicName, partition, messageSetSizeBytes, nil)
 	return err
 }
 
+// partition header of v4 has last_stable_offset and aborted_transactions, v5+ has log_start_offset before aborted_transactions
+func (streamDecoder *FetchResponseStreamDecoder) encodePartitionResponseV4(topicName string) error {
+	var (
+		buffer []byte
+		n      int
+	)
+
+	headerLength := 26
+	if streamDecoder.version >= 5 {
+		headerLength += 8
+	}
+	buffer, n = streamDecoder.read(headerLength)
+	if n < headerLength {
+		return &maxBytesTooSmall
+	}
+
+	partition := int32(binary.BigEndian.Uint32(buffer))
+	errorCode := int16(binary.BigEndian.Uint16(buffer[4:]))
+	//highwaterMarkOffset = int64(binary.BigEndian.Uint64(buffer[6:]))
+	lastStableOffset := int64(binary.BigEndian.Uint64(buffer[14:]))
+
+	// aborted_transactions is null(-1) if isolation level is read_uncommitted
+	var filter *abortedTransactionFilter
+	abortedTransactionsCount := int32(binary.BigEndian.Uint32(buffer[headerLength-4:]))
+	if abortedTransactionsCount >= 0 {
+		abortedTransactions := make([]*AbortedTransaction, abortedTransactionsCount)
+		if abortedTransactionsCount > 0 {
+			buffer, n = streamDecoder.read(int(abortedTransactionsCount) * 16)
+			if n < int(abortedTransactionsCount)*16 {
+				return &maxBytesTooSmall
+			}
+			for i := range abortedTransactions {
+				abortedTransactions[i] = &AbortedTransaction{
+					ProducerID:  int64(binary.BigEndian.Uint64(buffer[i*16:])),
+					FirstOffset: int64(binary.BigEndian.Uint64(buffer[i*16+8:])),
+				}
+			}
+		}
+		filter = newAbortedTransactionFilter(abortedTransactions)
+	}
+	glog.V(10).Infof("%s[%d] last stable offset: %d, aborted transactions: %d", topicName, partition, lastStableOffset, abortedTransactionsCount)
+
+	buffer, n = streamDecoder.read(4)
+	if n < 4 {
+		return &maxBytesTooSmall
+	}
+	messageSetSizeBytes := int32(binary.BigEndian.Uint32(buffer))
+
+	if errorCode != 0 {
+		return getErrorFromErrorCode(errorCode)
+	}
+
+	return streamDecoder.encodeMessageSet(topicName, partition, messageSetSizeBytes, filter)
+}
+
 func (streamDecoder *FetchResponseStreamDecoder) encodeResponses() error {
 	var (
 		err    error
@@ -240,6 +428,37 @@ func (streamDecoder *FetchResponseStreamDecoder) consumeFetchResponse() {
 
 	responsesCount := binary.BigEndian.Uint32(buffer[4:])
 
+	// throttle_time_ms is before responses since v1
+	if streamDecoder.version >= 1 && streamDecoder.version < 7 {
+		buffer, n = streamDecoder.read(4)
+		if n != 4 {
+			glog.Errorf("could read enough bytes(4) from buffer channel for fetch responses count. read %d bytes", n)
+			return
+		}
+		responsesCount = binary.BigEndian.Uint32(buffer)
+	}
+
+	// error_code and session_id are between throttle_time_ms and responses since v7
+	if streamDecoder.version >= 7 {
+		buffer, n = streamDecoder.read(10)
+		if n != 10 {
+			glog.Errorf("could read enough bytes(10) from buffer channel for fetch response error_code and session_id. read %d bytes", n)
+			return
+		}
+		if errorCode := int16(binary.BigEndian.Uint16(buffer)); errorCode != 0 {
+			err := getErrorFromErrorCode(errorCode)
+			streamDecoder.messages <- &FullMessage{
+				TopicName:   "",
+				PartitionID: -1,
+				Error:       err,
+				Message:     nil,
+			}
+			glog.Error(err)
+			return
+		}
+		responsesCount = binary.BigEndian.Uint32(buffer[6:])
+	}
+
 	if responsesCount == 0 {
 		return
 	}
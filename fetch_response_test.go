@@ -0,0 +1,189 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+func buildTestBatch(t *testing.T, baseOffset int64, producerID int64, attributes int16, records []*Record) []byte {
+	batch := &RecordBatch{
+		BaseOffset:           baseOffset,
+		PartitionLeaderEpoch: -1,
+		Magic:                2,
+		Attributes:           attributes,
+		LastOffsetDelta:      int32(len(records) - 1),
+		ProducerID:           producerID,
+		ProducerEpoch:        0,
+		BaseSequence:         0,
+		Records:              records,
+	}
+	payload, err := batch.Encode(NewCompressor("none"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func controlRecord(controlType int16) []*Record {
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint16(key[2:], uint16(controlType))
+	return []*Record{{Key: key, Value: make([]byte, 6)}}
+}
+
+// buildTestFetchResponse builds a v4 or v10 fetch response of one partition. abortedTransactions nil means read_uncommitted
+func buildTestFetchResponse(version uint16, recordSet []byte, abortedTransactions []*AbortedTransaction) []byte {
+	topic := "test"
+	payload := make([]byte, 0)
+	payload = append(payload, make([]byte, 4+4+4)...)
+	if version >= 7 {
+		// error_code session_id
+		payload = append(payload, make([]byte, 2+4)...)
+	}
+	payload = append(payload, 0, 0, 0, 1)
+
+	headerLength := 2 + len(topic) + 4 + 4 + 2 + 8 + 8 + 4
+	if version >= 5 {
+		headerLength += 8
+	}
+	header := make([]byte, headerLength)
+	binary.BigEndian.PutUint16(header, uint16(len(topic)))
+	copy(header[2:], topic)
+	offset := 2 + len(topic)
+	binary.BigEndian.PutUint32(header[offset:], 1)
+	offset = headerLength - 4
+	if abortedTransactions == nil {
+		binary.BigEndian.PutUint32(header[offset:], 0xffffffff)
+	} else {
+		binary.BigEndian.PutUint32(header[offset:], uint32(len(abortedTransactions)))
+	}
+	payload = append(payload, header...)
+	for _, abortedTransaction := range abortedTransactions {
+		b := make([]byte, 16)
+		binary.BigEndian.PutUint64(b, uint64(abortedTransaction.ProducerID))
+		binary.BigEndian.PutUint64(b[8:], uint64(abortedTransaction.FirstOffset))
+		payload = append(payload, b...)
+	}
+
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(recordSet)))
+	payload = append(payload, size...)
+	payload = append(payload, recordSet...)
+
+	binary.BigEndian.PutUint32(payload, uint32(len(payload)-4))
+	return payload
+}
+
+func decodeTestFetchResponse(version uint16, payload []byte) []*FullMessage {
+	buffers := make(chan []byte, 1)
+	buffers <- payload
+	close(buffers)
+
+	messages := make(chan *FullMessage, 100)
+	decoder := FetchResponseStreamDecoder{
+		version:  version,
+		buffers:  buffers,
+		messages: messages,
+		more:     true,
+	}
+	decoder.consumeFetchResponse()
+
+	rst := make([]*FullMessage, 0)
+	for message := range messages {
+		rst = append(rst, message)
+	}
+	return rst
+}
+
+func TestFetchReadCommitted(t *testing.T) {
+	recordSet := make([]byte, 0)
+	// aborted transaction of producer 7
+	recordSet = append(recordSet, buildTestBatch(t, 0, 7, transactionalMask, []*Record{{Value: []byte("a0")}, {OffsetDelta: 1, Value: []byte("a1")}})...)
+	recordSet = append(recordSet, buildTestBatch(t, 2, 7, transactionalMask|controlMask, controlRecord(CONTROL_TYPE_ABORT))...)
+	// committed transaction of producer 8
+	recordSet = append(recordSet, buildTestBatch(t, 3, 8, transactionalMask, []*Record{{Value: []byte("c3")}, {OffsetDelta: 1, Value: []byte("c4")}})...)
+	recordSet = append(recordSet, buildTestBatch(t, 5, 8, transactionalMask|controlMask, controlRecord(CONTROL_TYPE_COMMIT))...)
+	// producer 7 is not aborted any more
+	recordSet = append(recordSet, buildTestBatch(t, 6, 7, 0, []*Record{{Value: []byte("n6")}})...)
+
+	for _, c := range []struct {
+		abortedTransactions []*AbortedTransaction
+		values              []string
+		skipped             []int64
+	}{
+		{[]*AbortedTransaction{{ProducerID: 7, FirstOffset: 0}}, []string{"c3", "c4", "n6"}, []int64{1, 2, 5}},
+		{nil, []string{"a0", "a1", "c3", "c4", "n6"}, []int64{2, 5}},
+	} {
+		messages := decodeTestFetchResponse(4, buildTestFetchResponse(4, recordSet, c.abortedTransactions))
+		values := make([]string, 0)
+		skipped := make([]int64, 0)
+		for _, message := range messages {
+			if message.Error != nil {
+				t.Fatalf("decode fetch response error: %s", message.Error)
+			}
+			if message.skipped {
+				skipped = append(skipped, message.Message.Offset)
+			} else {
+				values = append(values, string(message.Message.Value))
+			}
+		}
+		if len(values) != len(c.values) || len(skipped) != len(c.skipped) {
+			t.Fatalf("expect %v and skipped %v, got %v and %v", c.values, c.skipped, values, skipped)
+		}
+		for i := range values {
+			if values[i] != c.values[i] {
+				t.Errorf("expect %v, got %v", c.values, values)
+			}
+		}
+		for i := range skipped {
+			if skipped[i] != c.skipped[i] {
+				t.Errorf("expect skipped %v, got %v", c.skipped, skipped)
+			}
+		}
+	}
+}
+
+func TestFetchV10Zstd(t *testing.T) {
+	batch := &RecordBatch{
+		BaseOffset:           10,
+		PartitionLeaderEpoch: -1,
+		Magic:                2,
+		Attributes:           int16(COMPRESSION_ZSTD),
+		LastOffsetDelta:      1,
+		ProducerID:           -1,
+		ProducerEpoch:        -1,
+		BaseSequence:         -1,
+		Records:              []*Record{{Value: []byte("z10")}, {OffsetDelta: 1, Value: []byte("z11")}},
+	}
+	recordSet, err := batch.Encode(NewCompressor("zstd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := decodeTestFetchResponse(10, buildTestFetchResponse(10, recordSet, nil))
+	if len(messages) != 2 {
+		t.Fatalf("expect 2 messages, got %d", len(messages))
+	}
+	for i, message := range messages {
+		if message.Error != nil {
+			t.Fatalf("decode fetch response error: %s", message.Error)
+		}
+		if expect := fmt.Sprintf("z1%d", i); string(message.Message.Value) != expect || message.Message.Offset != int64(10+i) {
+			t.Errorf("expect %s at offset %d, got %s at %d", expect, 10+i, message.Message.Value, message.Message.Offset)
+		}
+	}
+}
+
+func TestFetchRequestV10(t *testing.T) {
+	fetchRequest := NewFetchRequest(10, "healer", 100, 1)
+	fetchRequest.addPartition("test", 0, 100, 1024)
+	payload := fetchRequest.Encode()
+	if int(binary.BigEndian.Uint32(payload))+4 != len(payload) {
+		t.Fatalf("request length %d does not match payload length %d", binary.BigEndian.Uint32(payload), len(payload))
+	}
+	// header, replica_id max_wait_time min_bytes max_bytes isolation_level session_id session_epoch, topics, forgotten_topics_data
+	expect := 4 + fetchRequest.RequestHeader.length() + 4*4 + 1 + 4 + 4 + 4 + 2 + len("test") + 4 + (4 + 4 + 8 + 8 + 4) + 4
+	if len(payload) != expect {
+		t.Errorf("expect fetch request v10 length %d, got %d", expect, len(payload))
+	}
+}
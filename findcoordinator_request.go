@@ -20,26 +20,36 @@ coordinator_key	Id to use for finding the coordinator (for groups, this is the g
 coordinator_type	The type of coordinator to find (0 = group, 1 = transaction)
 */
 
+const (
+	COORDINATOR_TYPE_GROUP       int8 = 0
+	COORDINATOR_TYPE_TRANSACTION int8 = 1
+)
+
 type FindCoordinatorRequest struct {
-	RequestHeader *RequestHeader
-	GroupID       string
+	RequestHeader   *RequestHeader
+	GroupID         string
+	CoordinatorType int8
 }
 
-func NewFindCoordinatorRequest(clientID, groupID string) *FindCoordinatorRequest {
+func NewFindCoordinatorRequest(apiVersion uint16, clientID, groupID string) *FindCoordinatorRequest {
 	requestHeader := &RequestHeader{
 		ApiKey:     API_FindCoordinator,
-		ApiVersion: 0,
+		ApiVersion: apiVersion,
 		ClientId:   clientID,
 	}
 
 	return &FindCoordinatorRequest{
-		RequestHeader: requestHeader,
-		GroupID:       groupID,
+		RequestHeader:   requestHeader,
+		GroupID:         groupID,
+		CoordinatorType: COORDINATOR_TYPE_GROUP,
 	}
 }
 
 func (findCoordinatorR *FindCoordinatorRequest) Encode() []byte {
 	requestLength := findCoordinatorR.RequestHeader.length() + 2 + len(findCoordinatorR.GroupID)
+	if findCoordinatorR.RequestHeader.ApiVersion >= 1 {
+		requestLength++
+	}
 
 	payload := make([]byte, requestLength+4)
 	offset := 0
@@ -52,7 +62,11 @@ func (findCoordinatorR *FindCoordinatorRequest) Encode() []byte {
 	binary.BigEndian.PutUint16(payload[offset:], uint16(len(findCoordinatorR.GroupID)))
 	offset += 2
 
-	copy(payload[offset:], findCoordinatorR.GroupID)
+	offset += copy(payload[offset:], findCoordinatorR.GroupID)
+
+	if findCoordinatorR.RequestHeader.ApiVersion >= 1 {
+		payload[offset] = byte(findCoordinatorR.CoordinatorType)
+	}
 
 	return payload
 }
@@ -5,6 +5,24 @@ import (
 	"fmt"
 )
 
+/*
+FindCoordinator Response (Version: 0) => error_code coordinator
+  error_code => INT16
+  coordinator => node_id host port
+    node_id => INT32
+    host => STRING
+    port => INT32
+
+FindCoordinator Response (Version: 1) => throttle_time_ms error_code error_message coordinator
+  throttle_time_ms => INT32
+  error_code => INT16
+  error_message => NULLABLE_STRING
+  coordinator => node_id host port
+    node_id => INT32
+    host => STRING
+    port => INT32
+*/
+
 type Coordinator struct {
 	NodeID int32
 	Host   string
@@ -12,12 +30,14 @@ type Coordinator struct {
 }
 
 type FindCoordinatorResponse struct {
-	CorrelationID uint32
-	ErrorCode     uint16
-	Coordinator   *Coordinator
+	CorrelationID  uint32
+	ThrottleTimeMS int32
+	ErrorCode      uint16
+	ErrorMessage   string
+	Coordinator    *Coordinator
 }
 
-func NewFindCoordinatorResponse(payload []byte) (*FindCoordinatorResponse, error) {
+func NewFindCoordinatorResponse(payload []byte, version uint16) (*FindCoordinatorResponse, error) {
 	findCoordinatorResponse := &FindCoordinatorResponse{}
 	offset := 0
 	responseLength := int(binary.BigEndian.Uint32(payload))
@@ -29,9 +49,23 @@ func NewFindCoordinatorResponse(payload []byte) (*FindCoordinatorResponse, error
 	findCoordinatorResponse.CorrelationID = uint32(binary.BigEndian.Uint32(payload[offset:]))
 	offset += 4
 
+	if version >= 1 {
+		findCoordinatorResponse.ThrottleTimeMS = int32(binary.BigEndian.Uint32(payload[offset:]))
+		offset += 4
+	}
+
 	findCoordinatorResponse.ErrorCode = binary.BigEndian.Uint16(payload[offset:])
 	offset += 2
 
+	if version >= 1 {
+		l := int(int16(binary.BigEndian.Uint16(payload[offset:])))
+		offset += 2
+		if l > 0 {
+			findCoordinatorResponse.ErrorMessage = string(payload[offset : offset+l])
+			offset += l
+		}
+	}
+
 	coordinator := &Coordinator{}
 	findCoordinatorResponse.Coordinator = coordinator
 
@@ -45,5 +79,8 @@ func NewFindCoordinatorResponse(payload []byte) (*FindCoordinatorResponse, error
 
 	coordinator.Port = int32(binary.BigEndian.Uint32(payload[offset:]))
 
+	if findCoordinatorResponse.ErrorCode != 0 {
+		return findCoordinatorResponse, getErrorFromErrorCode(int16(findCoordinatorResponse.ErrorCode))
+	}
 	return findCoordinatorResponse, nil
 }
@@ -8,7 +8,7 @@ func TestFindCoordinator(t *testing.T) {
 		groupID  string = "healer.topicname"
 	)
 
-	request := NewFindCoordinatorRequest(clientID, groupID)
+	request := NewFindCoordinatorRequest(0, clientID, groupID)
 
 	payload := request.Encode()
 	if len(payload) != 38 {
@@ -29,7 +29,7 @@ func TestFindCoordinator(t *testing.T) {
 		t.Logf("got response from findcoordinator request:%d bytes", len(responseBytes))
 	}
 
-	response, err := NewFindCoordinatorResponse(responseBytes)
+	response, err := NewFindCoordinatorResponse(responseBytes, 0)
 	if err != nil {
 		t.Errorf("decode findcoordinator response error:%s", err)
 	} else {
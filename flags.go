@@ -0,0 +1,25 @@
+package healer
+
+// FlagSet is the part of *flag.FlagSet, and of *pflag.FlagSet from github.com/spf13/pflag, that the tools need to register flags
+type FlagSet interface {
+	BoolVar(p *bool, name string, value bool, usage string)
+	StringVar(p *string, name string, value string, usage string)
+}
+
+// RegisterBrokerFlags registers the tls.* and sasl.* flags of the broker config
+func RegisterBrokerFlags(flags FlagSet, config *BrokerConfig) {
+	RegisterSecurityFlags(flags, &config.TLSEnabled, &config.TLS, &config.SASL)
+}
+
+// RegisterSecurityFlags registers the tls.* and sasl.* flags. ConsumerConfig and ProducerConfig have their own copies of these fields
+func RegisterSecurityFlags(flags FlagSet, tlsEnabled *bool, tls *TLSConfig, sasl *SASLConfig) {
+	flags.BoolVar(tlsEnabled, "tls.enabled", *tlsEnabled, "connect to brokers with TLS")
+	flags.StringVar(&tls.CA, "tls.ca", tls.CA, "CA bundle in PEM format to verify the brokers")
+	flags.StringVar(&tls.Cert, "tls.cert", tls.Cert, "client certificate in PEM format")
+	flags.StringVar(&tls.Key, "tls.key", tls.Key, "client private key in PEM format")
+	flags.StringVar(&tls.ServerName, "tls.servername", tls.ServerName, "server name to verify the certificates of brokers. host of the broker address is used if not set")
+	flags.BoolVar(&tls.InsecureSkipVerify, "tls.insecure.skip.verify", tls.InsecureSkipVerify, "do not verify the certificates of brokers")
+	flags.StringVar(&sasl.Mechanism, "sasl.mechanism", sasl.Mechanism, "sasl mechanism to authenticate with brokers. PLAIN/SCRAM-SHA-256/SCRAM-SHA-512. sasl is disabled if not set")
+	flags.StringVar(&sasl.User, "sasl.user", sasl.User, "sasl user")
+	flags.StringVar(&sasl.Password, "sasl.password", sasl.Password, "sasl password")
+}
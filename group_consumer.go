@@ -42,6 +42,13 @@ type GroupConsumer struct {
 }
 
 func NewGroupConsumer(topic string, config *ConsumerConfig) (*GroupConsumer, error) {
+	if err := config.checkValid(); err != nil {
+		return nil, err
+	}
+	if config.GroupID == "" {
+		return nil, emptyGroupID
+	}
+
 	var clientID string
 	if config.ClientID == "" {
 		clientID = config.GroupID
@@ -458,7 +465,11 @@ func (c *GroupConsumer) consumeWithoutHeartBeat(fromBeginning bool, messages cha
 		} else {
 			offset = -1
 		}
-		go simpleConsumer.Consume(offset, messages)
+		go func(simpleConsumer *SimpleConsumer) {
+			if _, err := simpleConsumer.Consume(offset, messages); err != nil {
+				glog.Errorf("consume %s[%d] error: %s", simpleConsumer.topic, simpleConsumer.partitionID, err)
+			}
+		}(simpleConsumer)
 	}
 
 	return messages, nil
@@ -3,22 +3,107 @@ package healer
 import (
 	"bytes"
 	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"sync"
 )
 
+// GzipCompressor reuses the gzip writers and readers by pools. the zero value compresses in gzip.DefaultCompression
 type GzipCompressor struct {
+	writers *sync.Pool
+}
+
+// appendWriter appends what is written to b, so the writer could compress into the buffer given by AppendCompress
+type appendWriter struct {
+	b []byte
+}
+
+func (w *appendWriter) Write(p []byte) (int, error) {
+	w.b = append(w.b, p...)
+	return len(p), nil
+}
+
+type pooledGzipWriter struct {
+	writer *gzip.Writer
+	output appendWriter
+}
+
+func newGzipWriterPool(level int) *sync.Pool {
+	return &sync.Pool{
+		New: func() interface{} {
+			w := &pooledGzipWriter{}
+			w.writer, _ = gzip.NewWriterLevel(&w.output, level)
+			return w
+		},
+	}
+}
+
+var (
+	defaultGzipWriters = newGzipWriterPool(gzip.DefaultCompression)
+	gzipReaders        sync.Pool
+
+	// writer pools are shared by the producers with the same level
+	gzipWritersMutex sync.Mutex
+	gzipWriters      = make(map[int]*sync.Pool)
+)
+
+// WithLevel returns a GzipCompressor compressing in the level, which is 1(best speed) to 9(best compression)
+func (c *GzipCompressor) WithLevel(level int) (Codec, error) {
+	if level < gzip.BestSpeed || level > gzip.BestCompression {
+		return nil, fmt.Errorf("invalid gzip compression level %d. it should be in [%d, %d]", level, gzip.BestSpeed, gzip.BestCompression)
+	}
+
+	gzipWritersMutex.Lock()
+	defer gzipWritersMutex.Unlock()
+
+	writers, ok := gzipWriters[level]
+	if !ok {
+		writers = newGzipWriterPool(level)
+		gzipWriters[level] = writers
+	}
+	return &GzipCompressor{writers: writers}, nil
 }
 
 func (c *GzipCompressor) Compress(value []byte) ([]byte, error) {
+	return c.AppendCompress(nil, value)
+}
+
+func (c *GzipCompressor) AppendCompress(dst, value []byte) ([]byte, error) {
+	writers := c.writers
+	if writers == nil {
+		writers = defaultGzipWriters
+	}
+	w := writers.Get().(*pooledGzipWriter)
+	defer writers.Put(w)
+
+	w.output.b = dst
+	w.writer.Reset(&w.output)
+	defer func() { w.output.b = nil }()
+
+	if _, err := w.writer.Write(value); err != nil {
+		return nil, err
+	}
+	if err := w.writer.Close(); err != nil {
+		return nil, err
+	}
+	return w.output.b, nil
+}
+
+func (c *GzipCompressor) Decompress(value []byte) ([]byte, error) {
 	var (
-		buf bytes.Buffer
-		err error
+		reader *gzip.Reader
+		err    error
 	)
-	writer := gzip.NewWriter(&buf)
-	if _, err = writer.Write(value); err != nil {
-		return nil, err
+	if r, ok := gzipReaders.Get().(*gzip.Reader); ok {
+		reader = r
+		err = reader.Reset(bytes.NewReader(value))
+	} else {
+		reader, err = gzip.NewReader(bytes.NewReader(value))
 	}
-	if err = writer.Close(); err != nil {
+	if err != nil {
 		return nil, err
 	}
-	return buf.Bytes(), nil
+	defer gzipReaders.Put(reader)
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
 }
@@ -1,6 +1,12 @@
 package healer
 
-import "github.com/golang/glog"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/golang/glog"
+)
 
 type MetaInfo struct {
 	brokers        []*BrokerInfo
@@ -92,3 +98,114 @@ func (h *Helper) GetGroups() []string {
 
 	return groups
 }
+
+var createTopicsValidateOnlyNotSupported = errors.New("validate only needs CreateTopics v1, which is not supported by the controller")
+
+// CreateTopics creates the topics by the controller. it only validates the request if validateOnly is true
+func (h *Helper) CreateTopics(topics map[string]*TopicDetail, timeoutMS int32, validateOnly bool) error {
+	controller, err := h.brokers.getController(h.clientID)
+	if err != nil {
+		return err
+	}
+	_, err = controller.requestCreateTopics(h.clientID, topics, timeoutMS, validateOnly)
+	return err
+}
+
+// DeleteTopics deletes the topics by the controller. delete.topic.enable must be true in the brokers
+func (h *Helper) DeleteTopics(topics []string, timeoutMS int32) error {
+	controller, err := h.brokers.getController(h.clientID)
+	if err != nil {
+		return err
+	}
+	_, err = controller.requestDeleteTopics(h.clientID, topics, timeoutMS)
+	return err
+}
+
+// CreatePartitions increases the partitions of the topic to count. assignment is the replicas of each new partition, nil lets the controller decide
+func (h *Helper) CreatePartitions(topic string, count int32, assignment [][]int32, timeoutMS int32, validateOnly bool) error {
+	controller, err := h.brokers.getController(h.clientID)
+	if err != nil {
+		return err
+	}
+	_, err = controller.requestCreatePartitions(h.clientID, topic, count, assignment, timeoutMS, validateOnly)
+	return err
+}
+
+// getConfigBroker returns the broker that the config request of the resource is sent to.
+// configs of a broker must be described and altered by the broker itself, others could be sent to any broker
+func (h *Helper) getConfigBroker(resourceType int8, resourceName string) (*Broker, error) {
+	if resourceType == RESOURCE_TYPE_BROKER {
+		nodeID, err := strconv.Atoi(resourceName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid broker id %s: %s", resourceName, err)
+		}
+		return h.brokers.GetBroker(int32(nodeID))
+	}
+
+	for nodeID := range h.brokers.brokersInfo {
+		broker, err := h.brokers.GetBroker(nodeID)
+		if err != nil {
+			glog.Errorf("get broker [%d] error:%s", nodeID, err)
+			continue
+		}
+		return broker, nil
+	}
+	return nil, errors.New("could not get any available broker")
+}
+
+// DescribeConfigs returns the configs of the topic or broker. all configs are returned if configNames is nil
+func (h *Helper) DescribeConfigs(resourceType int8, resourceName string, configNames []string) ([]*ConfigEntry, error) {
+	broker, err := h.getConfigBroker(resourceType, resourceName)
+	if err != nil {
+		return nil, err
+	}
+	resources := []*DescribeConfigsRequestResource{
+		{
+			ResourceType: resourceType,
+			ResourceName: resourceName,
+			ConfigNames:  configNames,
+		},
+	}
+	response, err := broker.requestDescribeConfigs(h.clientID, resources)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Resources) == 0 {
+		return nil, fmt.Errorf("no configs of %s returned", resourceName)
+	}
+	return response.Resources[0].ConfigEntries, nil
+}
+
+// AlterConfigs sets the configs of the topic or broker. NOTE the dynamic configs that are not in configs are reverted to default, use IncrementalAlterConfigs to change some configs only
+func (h *Helper) AlterConfigs(resourceType int8, resourceName string, configs map[string]string, validateOnly bool) error {
+	broker, err := h.getConfigBroker(resourceType, resourceName)
+	if err != nil {
+		return err
+	}
+	resources := []*AlterConfigsRequestResource{
+		{
+			ResourceType:  resourceType,
+			ResourceName:  resourceName,
+			ConfigEntries: configs,
+		},
+	}
+	_, err = broker.requestAlterConfigs(h.clientID, resources, validateOnly)
+	return err
+}
+
+// IncrementalAlterConfigs sets, deletes, appends or subtracts the configs of the topic or broker, other configs are untouched. it needs kafka 2.3+
+func (h *Helper) IncrementalAlterConfigs(resourceType int8, resourceName string, configs []*AlterableConfig, validateOnly bool) error {
+	broker, err := h.getConfigBroker(resourceType, resourceName)
+	if err != nil {
+		return err
+	}
+	resources := []*IncrementalAlterConfigsRequestResource{
+		{
+			ResourceType: resourceType,
+			ResourceName: resourceName,
+			Configs:      configs,
+		},
+	}
+	_, err = broker.requestIncrementalAlterConfigs(h.clientID, resources, validateOnly)
+	return err
+}
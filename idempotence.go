@@ -0,0 +1,164 @@
+package healer
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// producerIDNotInitedError is returned to a transactional producer whose producer id has not been inited. it is inited again only by BeginTransaction or AbortTransaction
+var producerIDNotInitedError = errors.New("producer id is not inited, abort the transaction to init it")
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// producerIDManager holds the producer id and epoch from InitProducerId, and the next sequence of each partition.
+// one Producer shares it among all its SimpleProducers
+type producerIDManager struct {
+	config *ProducerConfig
+
+	mutex         sync.Mutex
+	producerID    int64
+	producerEpoch int16
+	sequences     map[topicPartition]int32
+}
+
+func newProducerIDManager(config *ProducerConfig) (*producerIDManager, error) {
+	m := &producerIDManager{
+		config:     config,
+		producerID: -1,
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m, m.initProducerID()
+}
+
+// initProducerID must be called with m.mutex held
+func (m *producerIDManager) initProducerID() error {
+	brokers, err := NewBrokers(m.config.BootstrapServers, m.config.ClientID, getBrokerConfigFromProducerConfig(m.config))
+	if err != nil {
+		return err
+	}
+	defer brokers.Close()
+
+	var response *InitProducerIDResponse
+	if m.config.TransactionalID == "" {
+		response, err = brokers.InitProducerID(m.config.ClientID)
+	} else {
+		response, err = m.initTransactionalProducerID(brokers)
+	}
+	if err != nil {
+		return err
+	}
+	glog.V(5).Infof("got producer id %d, epoch %d", response.ProducerID, response.ProducerEpoch)
+	m.producerID = response.ProducerID
+	m.producerEpoch = response.ProducerEpoch
+	m.sequences = make(map[topicPartition]int32)
+	return nil
+}
+
+// initTransactionalProducerID sends InitProducerId to the transaction coordinator. it also aborts the ongoing transaction of the previous producer instance with the same transactional id
+func (m *producerIDManager) initTransactionalProducerID(brokers *Brokers) (*InitProducerIDResponse, error) {
+	deadline := time.Now().Add(time.Duration(m.config.TransactionTimeoutMS) * time.Millisecond)
+	for {
+		response, err := func() (*InitProducerIDResponse, error) {
+			coordinatorResponse, err := brokers.FindTransactionCoordinator(m.config.ClientID, m.config.TransactionalID)
+			if err != nil {
+				return nil, err
+			}
+			coordinator, err := brokers.GetBroker(coordinatorResponse.Coordinator.NodeID)
+			if err != nil {
+				return nil, err
+			}
+			return coordinator.requestInitProducerID(m.config.ClientID, m.config.TransactionalID, m.config.TransactionTimeoutMS)
+		}()
+		if err == nil || !isRetriableTxnError(err) || time.Now().After(deadline) {
+			return response, err
+		}
+		glog.Infof("init producer id of transactional id %s error: %s. retry", m.config.TransactionalID, err)
+		time.Sleep(time.Duration(m.config.RetryBackOffMS) * time.Millisecond)
+	}
+}
+
+// ensureProducerID must be called with m.mutex held. it inits a new producer id after reset, except for the transactional producer
+func (m *producerIDManager) ensureProducerID() error {
+	if m.producerID != -1 {
+		return nil
+	}
+	if m.config.TransactionalID != "" {
+		return producerIDNotInitedError
+	}
+	return m.initProducerID()
+}
+
+// current returns the producer id and epoch
+func (m *producerIDManager) current() (int64, int16, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.ensureProducerID(); err != nil {
+		return -1, -1, err
+	}
+	return m.producerID, m.producerEpoch, nil
+}
+
+// sequence returns the producer id, epoch and the base sequence of the next batch of the partition
+func (m *producerIDManager) sequence(topic string, partition int32) (int64, int16, int32, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.ensureProducerID(); err != nil {
+		return -1, -1, -1, err
+	}
+	return m.producerID, m.producerEpoch, m.sequences[topicPartition{topic, partition}], nil
+}
+
+// inited tells if the producer id is usable
+func (m *producerIDManager) inited() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.producerID != -1
+}
+
+// reinit inits a new producer id of the transactional producer, which bumps the epoch and resets the sequences.
+// the transaction manager calls it only when a transaction begins or is aborted
+func (m *producerIDManager) reinit() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.producerID = -1
+	return m.initProducerID()
+}
+
+// advance increases the sequence after a batch is written. batch stamped with a stale producer id is ignored
+func (m *producerIDManager) advance(topic string, partition int32, producerID int64, producerEpoch int16, count int32) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if producerID != m.producerID || producerEpoch != m.producerEpoch {
+		return
+	}
+	tp := topicPartition{topic, partition}
+	if m.sequences[tp] > math.MaxInt32-count {
+		m.sequences[tp] = count - (math.MaxInt32 - m.sequences[tp]) - 1
+	} else {
+		m.sequences[tp] += count
+	}
+}
+
+// reset drops the producer id whose sequences are out of sync with the broker. a new one is inited by the next call of sequence.
+// the transactional producer never resets, it aborts the transaction instead
+func (m *producerIDManager) reset(producerID int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if producerID == m.producerID {
+		glog.Infof("reset producer id %d", producerID)
+		m.producerID = -1
+	}
+}
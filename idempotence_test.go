@@ -0,0 +1,109 @@
+package healer
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestProducerIDManagerSequence(t *testing.T) {
+	m := &producerIDManager{
+		producerID:    1000,
+		producerEpoch: 1,
+		sequences:     make(map[topicPartition]int32),
+	}
+
+	m.advance("test", 0, 1000, 1, 10)
+	if _, _, sequence, _ := m.sequence("test", 0); sequence != 10 {
+		t.Errorf("sequence should be 10, got %d", sequence)
+	}
+	if _, _, sequence, _ := m.sequence("test", 1); sequence != 0 {
+		t.Errorf("sequence of another partition should be 0, got %d", sequence)
+	}
+
+	// stale producer id
+	m.advance("test", 0, 999, 1, 10)
+	if _, _, sequence, _ := m.sequence("test", 0); sequence != 10 {
+		t.Errorf("sequence should not be changed by stale producer id, got %d", sequence)
+	}
+
+	m.sequences[topicPartition{"test", 0}] = math.MaxInt32 - 1
+	m.advance("test", 0, 1000, 1, 5)
+	if _, _, sequence, _ := m.sequence("test", 0); sequence != 3 {
+		t.Errorf("sequence should wrap to 3, got %d", sequence)
+	}
+
+	m.reset(999)
+	if m.producerID != 1000 {
+		t.Error("reset with stale producer id should be ignored")
+	}
+}
+
+// fakeIdempotentBroker closes the connection when the first produce request arrives,
+// and answers DUPLICATE_SEQUENCE_NUMBER if the same sequence is sent again
+type fakeIdempotentBroker struct {
+	mutex     sync.Mutex
+	sequences []int32
+}
+
+func (b *fakeIdempotentBroker) getSequences() []int32 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.sequences
+}
+
+func (b *fakeIdempotentBroker) handle(request *fakeRequest) []byte {
+	switch request.apiKey {
+	case API_ApiVersions:
+		return fakeApiVersionsResponse(map[uint16]uint16{API_ProduceRequest: 3})
+	case API_ProduceRequest:
+		p := parseFakeProduceRequest(request.body).partitions[0]
+		// base_sequence is at offset 53 of the record batch
+		sequence := int32(binary.BigEndian.Uint32(p.batch[53:]))
+
+		b.mutex.Lock()
+		b.sequences = append(b.sequences, sequence)
+		count := len(b.sequences)
+		duplicate := count > 1 && sequence == b.sequences[count-2]
+		b.mutex.Unlock()
+		if count == 1 {
+			return nil
+		}
+
+		var errorCode int16
+		if duplicate {
+			errorCode = 46
+		}
+		return fakeProduceResponse([]fakeProduceResult{{p.topic, p.partition, errorCode, 0, 0}})
+	}
+	return nil
+}
+
+func TestIdempotentRetry(t *testing.T) {
+	fakeBroker := &fakeIdempotentBroker{}
+	server := newFakeBroker(t, fakeBroker.handle)
+	defer server.close()
+
+	config := DefaultProducerConfig()
+	config.Acks = -1
+	config.Retries = 3
+	config.EnableIdempotence = true
+	p, cleanup := newFakeSimpleProducer(t, config, "test", 0, server.connect(t, nil))
+	defer cleanup()
+	p.pid = &producerIDManager{
+		producerID: 1000,
+		sequences:  map[topicPartition]int32{{"test", 0}: 5},
+	}
+
+	messageSet := MessageSet{&Message{Value: []byte("hello")}, &Message{Value: []byte("world")}}
+	if err := p.flush(messageSet); err != nil {
+		t.Fatalf("flush error: %s", err)
+	}
+	if sequences := fakeBroker.getSequences(); len(sequences) != 2 || sequences[0] != 5 || sequences[1] != 5 {
+		t.Errorf("retry should keep the sequence 5, got %v", sequences)
+	}
+	if _, _, sequence, _ := p.pid.sequence("test", 0); sequence != 7 {
+		t.Errorf("sequence should be 7 after the batch is written, got %d", sequence)
+	}
+}
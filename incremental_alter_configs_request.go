@@ -0,0 +1,136 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+IncrementalAlterConfigs Request (Version: 0) => [resources] validate_only
+  resources => resource_type resource_name [configs]
+    resource_type => INT8
+    resource_name => STRING
+    configs => name config_operation value
+      name => STRING
+      config_operation => INT8
+      value => NULLABLE_STRING
+  validate_only => BOOLEAN
+
+FIELD	DESCRIPTION
+  resources	The incremental updates for each resource.
+  resource_type	The resource type.
+  resource_name	The resource name.
+  configs	The configurations.
+  name	The configuration key name.
+  config_operation	The type (Set, Delete, Append, Subtract) of operation.
+  value	The value to set for the configuration key.
+  validate_only	True if we should validate the request, but not change the configurations.
+*/
+
+const (
+	CONFIG_OPERATION_SET      int8 = 0
+	CONFIG_OPERATION_DELETE   int8 = 1
+	CONFIG_OPERATION_APPEND   int8 = 2
+	CONFIG_OPERATION_SUBTRACT int8 = 3
+)
+
+// AlterableConfig is one operation on the config. Value is ignored by CONFIG_OPERATION_DELETE
+type AlterableConfig struct {
+	Name      string
+	Operation int8
+	Value     string
+}
+
+type IncrementalAlterConfigsRequestResource struct {
+	ResourceType int8
+	ResourceName string
+	Configs      []*AlterableConfig
+}
+
+type IncrementalAlterConfigsRequest struct {
+	RequestHeader *RequestHeader
+	Resources     []*IncrementalAlterConfigsRequestResource
+	ValidateOnly  bool
+}
+
+func NewIncrementalAlterConfigsRequest(apiVersion uint16, clientID string, resources []*IncrementalAlterConfigsRequestResource, validateOnly bool) *IncrementalAlterConfigsRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_IncrementalAlterConfigs,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &IncrementalAlterConfigsRequest{requestHeader, resources, validateOnly}
+}
+
+func (r *IncrementalAlterConfigsRequest) Length() int {
+	l := r.RequestHeader.length()
+	l += 4
+	for _, resource := range r.Resources {
+		l += 1 + 2 + len(resource.ResourceName)
+		l += 4
+		for _, config := range resource.Configs {
+			l += 2 + len(config.Name) + 1 + 2
+			if config.Operation != CONFIG_OPERATION_DELETE {
+				l += len(config.Value)
+			}
+		}
+	}
+	l++
+	return l
+}
+
+func (r *IncrementalAlterConfigsRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(len(r.Resources)))
+	offset += 4
+	for _, resource := range r.Resources {
+		payload[offset] = byte(resource.ResourceType)
+		offset++
+
+		binary.BigEndian.PutUint16(payload[offset:], uint16(len(resource.ResourceName)))
+		offset += 2
+		offset += copy(payload[offset:], resource.ResourceName)
+
+		binary.BigEndian.PutUint32(payload[offset:], uint32(len(resource.Configs)))
+		offset += 4
+		for _, config := range resource.Configs {
+			binary.BigEndian.PutUint16(payload[offset:], uint16(len(config.Name)))
+			offset += 2
+			offset += copy(payload[offset:], config.Name)
+
+			payload[offset] = byte(config.Operation)
+			offset++
+
+			if config.Operation == CONFIG_OPERATION_DELETE {
+				binary.BigEndian.PutUint16(payload[offset:], 0xffff)
+				offset += 2
+				continue
+			}
+			binary.BigEndian.PutUint16(payload[offset:], uint16(len(config.Value)))
+			offset += 2
+			offset += copy(payload[offset:], config.Value)
+		}
+	}
+
+	if r.ValidateOnly {
+		payload[offset] = 1
+	}
+
+	return payload
+}
+
+func (r *IncrementalAlterConfigsRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *IncrementalAlterConfigsRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
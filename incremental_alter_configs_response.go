@@ -0,0 +1,25 @@
+package healer
+
+/*
+IncrementalAlterConfigs Response (Version: 0) => throttle_time_ms [responses]
+  throttle_time_ms => INT32
+  responses => error_code error_message resource_type resource_name
+    error_code => INT16
+    error_message => NULLABLE_STRING
+    resource_type => INT8
+    resource_name => STRING
+
+FIELD	DESCRIPTION
+  throttle_time_ms	Duration in milliseconds for which the request was throttled due to quota violation (Zero if the request did not violate any quota)
+  error_code	The resource error code.
+  error_message	The resource error message, or null if there was no error.
+  resource_type	The resource type.
+  resource_name	The resource name.
+*/
+
+// IncrementalAlterConfigsResponse has the same layout with AlterConfigsResponse
+type IncrementalAlterConfigsResponse = AlterConfigsResponse
+
+func NewIncrementalAlterConfigsResponse(payload []byte) (*IncrementalAlterConfigsResponse, error) {
+	return decodeAlterConfigsResponse(payload, "IncrementalAlterConfigs")
+}
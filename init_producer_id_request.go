@@ -0,0 +1,69 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+InitProducerId Request (Version: 0) => transactional_id transaction_timeout_ms
+  transactional_id => NULLABLE_STRING
+  transaction_timeout_ms => INT32
+
+FIELD	DESCRIPTION
+  transactional_id	The transactional id, or null if the producer is not transactional.
+  transaction_timeout_ms	The time in ms to wait before aborting idle transactions sent by this producer. This is only relevant if a TransactionalId has been defined.
+*/
+
+type InitProducerIDRequest struct {
+	RequestHeader        *RequestHeader
+	TransactionalID      string
+	TransactionTimeoutMS int32
+}
+
+// NewInitProducerIDRequest creates a InitProducerId request. transactionalID is encoded as null if it is empty
+func NewInitProducerIDRequest(apiVersion uint16, clientID string, transactionalID string, transactionTimeoutMS int32) *InitProducerIDRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_InitProducerID,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &InitProducerIDRequest{requestHeader, transactionalID, transactionTimeoutMS}
+}
+
+func (r *InitProducerIDRequest) Length() int {
+	return r.RequestHeader.length() + 2 + len(r.TransactionalID) + 4
+}
+
+func (r *InitProducerIDRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	if r.TransactionalID == "" {
+		binary.BigEndian.PutUint16(payload[offset:], 0xffff)
+		offset += 2
+	} else {
+		binary.BigEndian.PutUint16(payload[offset:], uint16(len(r.TransactionalID)))
+		offset += 2
+		copy(payload[offset:], r.TransactionalID)
+		offset += len(r.TransactionalID)
+	}
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(r.TransactionTimeoutMS))
+
+	return payload
+}
+
+func (r *InitProducerIDRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *InitProducerIDRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
@@ -0,0 +1,58 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+InitProducerId Response (Version: 0) => throttle_time_ms error_code producer_id producer_epoch
+  throttle_time_ms => INT32
+  error_code => INT16
+  producer_id => INT64
+  producer_epoch => INT16
+
+FIELD	DESCRIPTION
+  throttle_time_ms	The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+  error_code	The error code, or 0 if there was no error.
+  producer_id	The current producer id.
+  producer_epoch	The current epoch associated with the producer id.
+*/
+
+type InitProducerIDResponse struct {
+	CorrelationID  uint32
+	ThrottleTimeMS int32
+	ErrorCode      int16
+	ProducerID     int64
+	ProducerEpoch  int16
+}
+
+func NewInitProducerIDResponse(payload []byte) (*InitProducerIDResponse, error) {
+	r := &InitProducerIDResponse{}
+	offset := 0
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("InitProducerId reseponse length did not match: %d!=%d", responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	r.ThrottleTimeMS = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	r.ErrorCode = int16(binary.BigEndian.Uint16(payload[offset:]))
+	offset += 2
+
+	r.ProducerID = int64(binary.BigEndian.Uint64(payload[offset:]))
+	offset += 8
+
+	r.ProducerEpoch = int16(binary.BigEndian.Uint16(payload[offset:]))
+	offset += 2
+
+	if r.ErrorCode != 0 {
+		return r, getErrorFromErrorCode(r.ErrorCode)
+	}
+	return r, nil
+}
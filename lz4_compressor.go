@@ -8,3 +8,17 @@ type LZ4Compressor struct {
 func (c *LZ4Compressor) Compress(value []byte) ([]byte, error) {
 	return lz4.Encode(nil, value)
 }
+
+// AppendCompress encodes into the spare capacity of dst. go-lz4 still allocates its hash table in each call
+func (c *LZ4Compressor) AppendCompress(dst, value []byte) ([]byte, error) {
+	dst = growBuffer(dst, lz4.CompressBound(len(value)))
+	encoded, err := lz4.Encode(dst[len(dst):cap(dst)], value)
+	if err != nil {
+		return nil, err
+	}
+	return dst[:len(dst)+len(encoded)], nil
+}
+
+func (c *LZ4Compressor) Decompress(value []byte) ([]byte, error) {
+	return lz4.Decode(nil, value)
+}
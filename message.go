@@ -1,16 +1,10 @@
 package healer
 
 import (
-	"bytes"
-	"compress/gzip"
 	"encoding/binary"
 	"fmt"
 	"hash/crc32"
-	"io"
-	"io/ioutil"
 
-	"github.com/bkaradzic/go-lz4"
-	"github.com/eapache/go-xerial-snappy"
 	"github.com/golang/glog"
 )
 
@@ -45,6 +39,10 @@ type FullMessage struct {
 	PartitionID int32
 	Error       error
 	Message     *Message
+
+	// skipped is set on the placeholder of a dropped batch (aborted or control batch). Message.Offset is the last offset in the batch.
+	// it never reaches the caller of Consume, only moves the fetch offset forward
+	skipped bool
 }
 
 type Message struct {
@@ -57,6 +55,18 @@ type Message struct {
 	Attributes int8
 	Key        []byte
 	Value      []byte
+
+	// Timestamp is in milliseconds and only available since magic 1. it is -1 in magic 0
+	Timestamp     int64
+	TimestampType int8
+
+	// Headers is only available in RecordBatch (magic 2)
+	Headers []RecordHeader
+
+	// callback is set by producer to report the delivery of the message
+	callback DeliveryCallback
+	// bufferSize is the space of buffer.memory allocated for the message in producer
+	bufferSize int
 }
 type MessageSet []*Message
 
@@ -65,28 +75,28 @@ const (
 	COMPRESSION_GZIP   int8 = 1
 	COMPRESSION_SNAPPY int8 = 2
 	COMPRESSION_LZ4    int8 = 3
+	COMPRESSION_ZSTD   int8 = 4
+)
+
+// the 4th lowest bit of attributes is the timestamp type, both in Message (magic 1) and RecordBatch
+const (
+	TIMESTAMP_CREATE_TIME     int8 = 0
+	TIMESTAMP_LOG_APPEND_TIME int8 = 1
+
+	timestampTypeMask = 0x08
 )
 
 func (message *Message) decompress() ([]byte, error) {
-	compression := message.Attributes & 7
-	var rst []byte
-	switch compression {
-	case COMPRESSION_GZIP:
-		reader, err := gzip.NewReader(bytes.NewReader(message.Value))
-		if err != nil {
-			return nil, err
-		}
-		if rst, err = ioutil.ReadAll(reader); err != nil && err != io.EOF {
-			return nil, err
-		} else {
-			return rst, nil
-		}
-	case COMPRESSION_SNAPPY:
-		return snappy.Decode(message.Value)
-	case COMPRESSION_LZ4:
-		return lz4.Decode(nil, message.Value)
+	return decompress(message.Attributes&7, message.Value)
+}
+
+// decompress decompresses the value by the codec registered with the compression id
+func decompress(compression int8, value []byte) ([]byte, error) {
+	codec, ok := getCodecByID(compression)
+	if !ok {
+		return nil, fmt.Errorf("Unknown Compression Code %d", compression)
 	}
-	return nil, fmt.Errorf("Unknown Compression Code %d", compression)
+	return codec.Decompress(value)
 }
 
 func (messageSet *MessageSet) Length() int {
@@ -137,6 +147,7 @@ func (messageSet *MessageSet) Encode(payload []byte, offset int) int {
 	return offset
 }
 
+// DecodeToMessageSet decodes MessageSet(magic 0 and 1) and RecordBatch(magic 2) to MessageSet
 func DecodeToMessageSet(payload []byte) (MessageSet, error) {
 	messageSet := MessageSet{}
 	var offset int = 0
@@ -147,6 +158,16 @@ func DecodeToMessageSet(payload []byte) (MessageSet, error) {
 			break
 		}
 
+		if len(payload)-offset > magicOffset && payload[offset+magicOffset] == 2 {
+			batch, err := DecodeToRecordBatch(payload[offset:])
+			if err != nil {
+				return messageSet, err
+			}
+			messageSet = append(messageSet, batch.toMessageSet()...)
+			offset += 12 + int(batch.BatchLength)
+			continue
+		}
+
 		message := &Message{}
 
 		message.Offset = int64(binary.BigEndian.Uint64(payload[offset:]))
@@ -164,6 +185,15 @@ func DecodeToMessageSet(payload []byte) (MessageSet, error) {
 		message.Attributes = int8(payload[offset])
 		offset++
 
+		message.Timestamp = -1
+		if message.MagicByte == 1 {
+			message.Timestamp = int64(binary.BigEndian.Uint64(payload[offset:]))
+			offset += 8
+			if message.Attributes&timestampTypeMask != 0 {
+				message.TimestampType = TIMESTAMP_LOG_APPEND_TIME
+			}
+		}
+
 		keyLength := int32(binary.BigEndian.Uint32(payload[offset:]))
 		offset += 4
 		if keyLength == -1 {
@@ -174,14 +204,14 @@ func DecodeToMessageSet(payload []byte) (MessageSet, error) {
 			offset += int(keyLength)
 		}
 
-		valueLength := int(binary.BigEndian.Uint32(payload[offset:]))
+		valueLength := int32(binary.BigEndian.Uint32(payload[offset:]))
 		offset += 4
 		if valueLength == -1 {
 			message.Value = nil
 		} else {
 			message.Value = make([]byte, valueLength)
-			copy(message.Value, payload[offset:offset+valueLength])
-			offset += valueLength
+			copy(message.Value, payload[offset:offset+int(valueLength)])
+			offset += int(valueLength)
 		}
 		compression := message.Attributes & 0x07
 		if compression != COMPRESSION_NONE {
@@ -200,6 +230,18 @@ func DecodeToMessageSet(payload []byte) (MessageSet, error) {
 				glog.Error("decode message from value error:%s", err)
 				return messageSet, err
 			} else {
+				// inner offsets of magic 1 are relative, the wrapper holds the offset of the last inner message
+				// and inner messages take the timestamp of the wrapper if it is LogAppendTime
+				if message.MagicByte == 1 && len(_messageSet) > 0 {
+					delta := message.Offset - _messageSet[len(_messageSet)-1].Offset
+					for _, m := range _messageSet {
+						m.Offset += delta
+						if message.TimestampType == TIMESTAMP_LOG_APPEND_TIME {
+							m.Timestamp = message.Timestamp
+							m.TimestampType = TIMESTAMP_LOG_APPEND_TIME
+						}
+					}
+				}
 				messageSet = append(messageSet, _messageSet...)
 			}
 		} else {
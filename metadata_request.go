@@ -24,6 +24,11 @@ Topics Metadata Request
 
 Field			Description
 TopicsName		The topics to produce metadata for. If empty the request will yield metadata for all topics.
+
+Metadata Request (Version: 1) => [topics]
+  topics => STRING
+
+topics		An array of topics to fetch metadata for. If the topics array is null fetch metadata for all topics. healer sends null if Topics is empty, to keep the same meaning with v0.
 */
 
 type MetadataRequest struct {
@@ -46,7 +51,11 @@ func (metadataRequest *MetadataRequest) Encode() []byte {
 
 	offset = metadataRequest.RequestHeader.Encode(payload, offset)
 
-	binary.BigEndian.PutUint32(payload[offset:], uint32(len(metadataRequest.Topics)))
+	if metadataRequest.RequestHeader.ApiVersion >= 1 && len(metadataRequest.Topics) == 0 {
+		binary.BigEndian.PutUint32(payload[offset:], 0xffffffff)
+	} else {
+		binary.BigEndian.PutUint32(payload[offset:], uint32(len(metadataRequest.Topics)))
+	}
 	offset += 4
 
 	for _, topicname := range metadataRequest.Topics {
@@ -29,6 +29,27 @@ Leader		The node id for the kafka broker currently acting as leader for this par
 Replicas	The set of alive nodes that currently acts as slaves for the leader for this partition.
 Isr			The set subset of the replicas that are "caught up" to the leader
 Broker		The node id, hostname, and port information for a kafka brokers
+
+Metadata Response (Version: 1) => [brokers] controller_id [topic_metadata]
+  brokers => node_id host port rack
+    node_id => INT32
+    host => STRING
+    port => INT32
+    rack => NULLABLE_STRING
+  controller_id => INT32
+  topic_metadata => error_code topic is_internal [partition_metadata]
+    error_code => INT16
+    topic => STRING
+    is_internal => BOOLEAN
+    partition_metadata => error_code partition leader [replicas] [isr]
+      error_code => INT16
+      partition => INT32
+      leader => INT32
+      replicas => INT32
+      isr => INT32
+
+controller_id	The broker id of the controller broker. It is -1 in version 0
+is_internal		Indicates if the topic is considered a Kafka internal topic
 */
 
 var (
@@ -39,6 +60,7 @@ type BrokerInfo struct {
 	NodeId int32
 	Host   string
 	Port   int32
+	Rack   string
 }
 
 type PartitionMetadataInfo struct {
@@ -52,16 +74,18 @@ type PartitionMetadataInfo struct {
 type TopicMetadata struct {
 	TopicErrorCode     int16
 	TopicName          string
+	IsInternal         bool
 	PartitionMetadatas []*PartitionMetadataInfo
 }
 
 type MetadataResponse struct {
 	CorrelationID  uint32
 	Brokers        []*BrokerInfo
+	ControllerID   int32
 	TopicMetadatas []*TopicMetadata
 }
 
-func NewMetadataResponse(payload []byte) (*MetadataResponse, error) {
+func NewMetadataResponse(payload []byte, version uint16) (*MetadataResponse, error) {
 	var err error = nil
 	metadataResponse := &MetadataResponse{}
 	//TODO: actually we have judged if the lenght matches while reading data from connection
@@ -89,9 +113,23 @@ func NewMetadataResponse(payload []byte) (*MetadataResponse, error) {
 		offset += HostLength
 		metadataResponse.Brokers[i].Port = int32(binary.BigEndian.Uint32(payload[offset:]))
 		offset += 4
+		if version >= 1 {
+			rackLength := int(int16(binary.BigEndian.Uint16(payload[offset:])))
+			offset += 2
+			if rackLength > 0 {
+				metadataResponse.Brokers[i].Rack = string(payload[offset : offset+rackLength])
+				offset += rackLength
+			}
+		}
 	}
 	// end encode Brokers
 
+	metadataResponse.ControllerID = -1
+	if version >= 1 {
+		metadataResponse.ControllerID = int32(binary.BigEndian.Uint32(payload[offset:]))
+		offset += 4
+	}
+
 	// encode TopicMetadatas
 	topicMetadatasCount := uint32(binary.BigEndian.Uint32(payload[offset:]))
 	offset += 4
@@ -108,6 +146,11 @@ func NewMetadataResponse(payload []byte) (*MetadataResponse, error) {
 		metadataResponse.TopicMetadatas[i].TopicName = string(payload[offset : offset+topicNameLength])
 		offset += topicNameLength
 
+		if version >= 1 {
+			metadataResponse.TopicMetadatas[i].IsInternal = payload[offset] != 0
+			offset++
+		}
+
 		partitionMetadataInfoCount := uint32(binary.BigEndian.Uint32(payload[offset:]))
 		offset += 4
 		metadataResponse.TopicMetadatas[i].PartitionMetadatas = make([]*PartitionMetadataInfo, partitionMetadataInfoCount)
@@ -6,3 +6,7 @@ type NoneCompressor struct {
 func (c *NoneCompressor) Compress(value []byte) ([]byte, error) {
 	return value, nil
 }
+
+func (c *NoneCompressor) Decompress(value []byte) ([]byte, error) {
+	return value, nil
+}
@@ -18,10 +18,25 @@ Offset Request
 
 Field	Decription
 Time	Used to ask for all messages before a certain time (ms). There are two special values. Specify -1 to receive the latest offset (i.e. the offset of the next coming message) and -2 to receive the earliest available offset. Note that because offsets are pulled in descending order, asking for the earliest offset will always return you a single element.
+
+ListOffsets Request (Version: 1) => replica_id [topics]
+  replica_id => INT32
+  topics => topic [partitions]
+    topic => STRING
+    partitions => partition timestamp
+      partition => INT32
+      timestamp => INT64
+
+FIELD	DESCRIPTION
+  timestamp	The target timestamp for the partition. Version 1 returns the first offset whose timestamp is greater than or equal to the target, with -1 and -2 the same meaning as version 0. MaxNumberOfOffsets is not sent in version 1
 */
 
 import (
 	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type PartitionOffsetRequestInfo struct {
@@ -65,8 +80,12 @@ func NewOffsetsRequest(topic string, partitionIDs []int32, timeValue int64, offs
 func (offsetR *OffsetsRequest) Encode() []byte {
 	requestLength := 8 + 2 + len(offsetR.RequestHeader.ClientId) + 4
 	requestLength += 4
+	partitionLength := 16
+	if offsetR.RequestHeader.ApiVersion >= 1 {
+		partitionLength = 12
+	}
 	for topicName, partitionInfo := range offsetR.RequestInfo {
-		requestLength += 2 + len(topicName) + 4 + len(partitionInfo)*16
+		requestLength += 2 + len(topicName) + 4 + len(partitionInfo)*partitionLength
 	}
 	payload := make([]byte, 4+requestLength)
 	offset := 0
@@ -96,8 +115,10 @@ func (offsetR *OffsetsRequest) Encode() []byte {
 
 			binary.BigEndian.PutUint64(payload[offset:], uint64(partitionOffsetRequestInfo.Time))
 			offset += 8
-			binary.BigEndian.PutUint32(payload[offset:], partitionOffsetRequestInfo.MaxNumberOfOffsets)
-			offset += 4
+			if offsetR.RequestHeader.ApiVersion == 0 {
+				binary.BigEndian.PutUint32(payload[offset:], partitionOffsetRequestInfo.MaxNumberOfOffsets)
+				offset += 4
+			}
 		}
 	}
 
@@ -111,3 +132,31 @@ func (req *OffsetsRequest) API() uint16 {
 func (req *OffsetsRequest) SetCorrelationID(c uint32) {
 	req.RequestHeader.CorrelationID = c
 }
+
+var invalidTimestamp = errors.New("invalid time. it should be -1(latest), -2(earliest), timestamp in milliseconds, RFC3339 time or duration like -2h")
+
+// ParseTimestamp parses the time used by offsets request. it could be
+// -1/latest, -2/earliest, timestamp in milliseconds, RFC3339 time like 2006-01-02T15:04:05+08:00, or duration relative to now like -2h
+func ParseTimestamp(s string) (int64, error) {
+	switch s {
+	case "latest":
+		return -1, nil
+	case "earliest":
+		return -2, nil
+	}
+	if t, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if t < -2 {
+			return 0, invalidTimestamp
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UnixNano() / 1000000, nil
+	}
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if d, err := time.ParseDuration(s); err == nil {
+			return time.Now().Add(d).UnixNano() / 1000000, nil
+		}
+	}
+	return 0, invalidTimestamp
+}
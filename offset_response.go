@@ -10,12 +10,27 @@ OffsetsResponse => [TopicName [PartitionOffsets]]
   Partition => int32
   ErrorCode => int16
   Offset => int64
+
+ListOffsets Response (Version: 1) => [responses]
+  responses => topic [partition_responses]
+    topic => STRING
+    partition_responses => partition error_code timestamp offset
+      partition => INT32
+      error_code => INT16
+      timestamp => INT64
+      offset => INT64
+
+FIELD	DESCRIPTION
+  timestamp	The timestamp of the returned offset, -1 if the target timestamp is -1/-2 or no such offset
+  offset	The first offset whose timestamp is greater than or equal to the target timestamp, -1 if no such offset
 */
 
 // TODO rename
+// Offsets has only one element in version 1, and Timestamp is -1 in version 0
 type PartitionOffset struct {
 	Partition int32
 	ErrorCode int16
+	Timestamp int64
 	Offsets   []int64
 }
 type OffsetsResponse struct {
@@ -23,7 +38,7 @@ type OffsetsResponse struct {
 	TopicPartitionOffsets map[string][]*PartitionOffset
 }
 
-func NewOffsetsResponse(payload []byte) (*OffsetsResponse, error) {
+func NewOffsetsResponse(payload []byte, version uint16) (*OffsetsResponse, error) {
 	offsetsResponse := &OffsetsResponse{}
 	offset := 0
 	responseLength := int(binary.BigEndian.Uint32(payload))
@@ -52,11 +67,25 @@ func NewOffsetsResponse(payload []byte) (*OffsetsResponse, error) {
 			offset += 4
 			errorCode := int16(binary.BigEndian.Uint16(payload[offset:]))
 			offset += 2
+
+			if version >= 1 {
+				timestamp := int64(binary.BigEndian.Uint64(payload[offset:]))
+				offset += 8
+				offsetsResponse.TopicPartitionOffsets[topicName][j] = &PartitionOffset{
+					Partition: partition,
+					ErrorCode: errorCode,
+					Timestamp: timestamp,
+					Offsets:   []int64{int64(binary.BigEndian.Uint64(payload[offset:]))},
+				}
+				offset += 8
+				continue
+			}
 			offsetLength := binary.BigEndian.Uint32(payload[offset:])
 			offset += 4
 			offsetsResponse.TopicPartitionOffsets[topicName][j] = &PartitionOffset{
 				Partition: partition,
 				ErrorCode: errorCode,
+				Timestamp: -1,
 				Offsets:   make([]int64, offsetLength),
 			}
 			for k := uint32(0); k < offsetLength; k++ {
@@ -1,6 +1,10 @@
 package healer
 
-import "testing"
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
 
 func TestGenOffsetsRequest(t *testing.T) {
 	var (
@@ -18,3 +22,68 @@ func TestGenOffsetsRequest(t *testing.T) {
 		t.Error("offsets request payload length should be 54")
 	}
 }
+
+func TestGenOffsetsRequestV1(t *testing.T) {
+	offsetsRequest := NewOffsetsRequest("test", []int32{0}, 1500000000000, 1, "healer")
+	offsetsRequest.RequestHeader.ApiVersion = 1
+
+	payload := offsetsRequest.Encode()
+	if len(payload) != 50 {
+		t.Errorf("offsets request v1 payload length should be 50, got %d", len(payload))
+	}
+	if int(binary.BigEndian.Uint32(payload))+4 != len(payload) {
+		t.Errorf("request length %d does not match payload length %d", binary.BigEndian.Uint32(payload), len(payload))
+	}
+}
+
+func TestOffsetsResponseV1(t *testing.T) {
+	// length correlation_id [topic [partition error_code timestamp offset]]
+	payload := make([]byte, 12)
+	binary.BigEndian.PutUint32(payload[8:], 1)
+	payload = append(payload, 0, 4)
+	payload = append(payload, "test"...)
+	payload = append(payload, 0, 0, 0, 1)
+	b := make([]byte, 22)
+	binary.BigEndian.PutUint32(b, 3)
+	binary.BigEndian.PutUint64(b[6:], 1500000000123)
+	binary.BigEndian.PutUint64(b[14:], 42)
+	payload = append(payload, b...)
+	binary.BigEndian.PutUint32(payload, uint32(len(payload)-4))
+
+	response, err := NewOffsetsResponse(payload, 1)
+	if err != nil {
+		t.Fatalf("decode offsets response v1 error: %s", err)
+	}
+	p := response.TopicPartitionOffsets["test"][0]
+	if p.Partition != 3 || p.Timestamp != 1500000000123 || len(p.Offsets) != 1 || p.Offsets[0] != 42 {
+		t.Errorf("unexpected partition offset %+v", p)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	for s, expect := range map[string]int64{
+		"-1":                   -1,
+		"earliest":             -2,
+		"1500000000000":        1500000000000,
+		"2017-07-14T02:40:00Z": 1500000000000,
+	} {
+		if got, err := ParseTimestamp(s); err != nil || got != expect {
+			t.Errorf("parse %s: expect %d, got %d %v", s, expect, got, err)
+		}
+	}
+
+	got, err := ParseTimestamp("-2h")
+	if err != nil {
+		t.Fatalf("parse -2h error: %s", err)
+	}
+	expect := time.Now().Add(-2*time.Hour).UnixNano() / 1000000
+	if got > expect || got < expect-1000 {
+		t.Errorf("parse -2h: expect about %d, got %d", expect, got)
+	}
+
+	for _, s := range []string{"", "-3", "2h", "yesterday"} {
+		if _, err := ParseTimestamp(s); err == nil {
+			t.Errorf("expect error when parsing %q", s)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package healer
+
+import (
+	"errors"
+	"hash/crc32"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	noAvailablePartition    = errors.New("no available partition")
+	manualPartitionNotGiven = errors.New("ManualPartitioner needs the partition given by AddMessageToPartition")
+)
+
+// Partitioner chooses the partition of the message in Producer.
+// numPartitions is the count of all partitions of the topic, and availablePartitions are the ids of the partitions with a leader.
+// it must be safe for concurrent use
+type Partitioner interface {
+	Partition(topic string, key, value []byte, numPartitions int32, availablePartitions []int32) (int32, error)
+}
+
+// NewBatchListener is implemented by the partitioners which need to know that a batch of the partition is completed, like StickyPartitioner
+type NewBatchListener interface {
+	OnNewBatch(topic string, prevPartition int32)
+}
+
+// murmur2 is the hash used by the java client, see org.apache.kafka.common.utils.Utils.murmur2
+func murmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+		r           = 24
+	)
+	length := len(data)
+	h := seed ^ uint32(length)
+	length4 := length / 4
+	for i := 0; i < length4; i++ {
+		i4 := i * 4
+		k := uint32(data[i4]) | uint32(data[i4+1])<<8 | uint32(data[i4+2])<<16 | uint32(data[i4+3])<<24
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := length &^ 3
+	switch length % 4 {
+	case 3:
+		h ^= uint32(data[tail+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[tail+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[tail])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// toPositive is the same as org.apache.kafka.common.utils.Utils.toPositive
+func toPositive(n int32) int32 {
+	return n & 0x7fffffff
+}
+
+// Murmur2Partitioner is the default partitioner of the java client.
+// messages with key go to toPositive(murmur2(key)) % numPartitions, and messages without key are partitioned by StickyPartitioner
+type Murmur2Partitioner struct {
+	sticky StickyPartitioner
+}
+
+func (p *Murmur2Partitioner) Partition(topic string, key, value []byte, numPartitions int32, availablePartitions []int32) (int32, error) {
+	if len(key) == 0 {
+		return p.sticky.Partition(topic, key, value, numPartitions, availablePartitions)
+	}
+	if numPartitions <= 0 {
+		return -1, noAvailablePartition
+	}
+	return toPositive(murmur2(key)) % numPartitions, nil
+}
+
+func (p *Murmur2Partitioner) OnNewBatch(topic string, prevPartition int32) {
+	p.sticky.OnNewBatch(topic, prevPartition)
+}
+
+// CRC32Partitioner is the consistent partitioner of librdkafka. messages go to crc32(key) % numPartitions, and all messages without key go to the same partition
+type CRC32Partitioner struct {
+}
+
+func (p *CRC32Partitioner) Partition(topic string, key, value []byte, numPartitions int32, availablePartitions []int32) (int32, error) {
+	if numPartitions <= 0 {
+		return -1, noAvailablePartition
+	}
+	return int32(crc32.ChecksumIEEE(key) % uint32(numPartitions)), nil
+}
+
+// RoundRobinPartitioner sends messages to the available partitions in turn, ignoring the key
+type RoundRobinPartitioner struct {
+	counters sync.Map // topic -> *uint32
+}
+
+func (p *RoundRobinPartitioner) Partition(topic string, key, value []byte, numPartitions int32, availablePartitions []int32) (int32, error) {
+	counter, ok := p.counters.Load(topic)
+	if !ok {
+		counter, _ = p.counters.LoadOrStore(topic, new(uint32))
+	}
+	next := atomic.AddUint32(counter.(*uint32), 1) - 1
+	if len(availablePartitions) > 0 {
+		return availablePartitions[next%uint32(len(availablePartitions))], nil
+	}
+	if numPartitions <= 0 {
+		return -1, noAvailablePartition
+	}
+	return int32(next % uint32(numPartitions)), nil
+}
+
+// StickyPartitioner sends messages to one random available partition until the batch of it is completed, ignoring the key.
+// it makes larger batches than RoundRobinPartitioner
+type StickyPartitioner struct {
+	mutex sync.Mutex
+	// topic -> the sticky partition, which is -1 after the batch is completed until the next message
+	partitions map[string]int32
+	// topic -> the partition of the last completed batch, which is avoided in choosing the next sticky partition
+	prevPartitions map[string]int32
+}
+
+func (p *StickyPartitioner) Partition(topic string, key, value []byte, numPartitions int32, availablePartitions []int32) (int32, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if partition, ok := p.partitions[topic]; ok && partition != -1 {
+		return partition, nil
+	}
+
+	prevPartition, ok := p.prevPartitions[topic]
+	if !ok {
+		prevPartition = -1
+	}
+	var partition int32
+	switch {
+	case len(availablePartitions) == 1:
+		partition = availablePartitions[0]
+	case len(availablePartitions) > 1:
+		for {
+			partition = availablePartitions[rand.Intn(len(availablePartitions))]
+			if partition != prevPartition {
+				break
+			}
+		}
+	case numPartitions > 0:
+		partition = rand.Int31n(numPartitions)
+	default:
+		return -1, noAvailablePartition
+	}
+
+	if p.partitions == nil {
+		p.partitions = make(map[string]int32)
+	}
+	p.partitions[topic] = partition
+	return partition, nil
+}
+
+// OnNewBatch changes the sticky partition on the next message if the completed batch is of it
+func (p *StickyPartitioner) OnNewBatch(topic string, prevPartition int32) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if partition, ok := p.partitions[topic]; !ok || partition != prevPartition {
+		return
+	}
+	p.partitions[topic] = -1
+	if p.prevPartitions == nil {
+		p.prevPartitions = make(map[string]int32)
+	}
+	p.prevPartitions[topic] = prevPartition
+}
+
+// ManualPartitioner makes the caller choose the partition of each message by Producer.AddMessageToPartition. AddMessage fails with it
+type ManualPartitioner struct {
+}
+
+func (p *ManualPartitioner) Partition(topic string, key, value []byte, numPartitions int32, availablePartitions []int32) (int32, error) {
+	return -1, manualPartitionNotGiven
+}
@@ -0,0 +1,137 @@
+package healer
+
+import "testing"
+
+// the expected values are from org.apache.kafka.common.utils.UtilsTest
+func TestMurmur2(t *testing.T) {
+	cases := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for key, expected := range cases {
+		if got := murmur2([]byte(key)); got != expected {
+			t.Errorf("murmur2(%q) = %d, expected %d", key, got, expected)
+		}
+	}
+}
+
+func TestMurmur2Partitioner(t *testing.T) {
+	p := &Murmur2Partitioner{}
+	partition, err := p.Partition("test", []byte("foobar"), nil, 10, []int32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// toPositive(-790332482) % 10
+	if partition != 6 {
+		t.Errorf("expect partition 6, got %d", partition)
+	}
+
+	for _, key := range []string{"21", "foobar", "abc", "a-little-bit-long-string"} {
+		partition, _ := p.Partition("test", []byte(key), nil, 7, nil)
+		if partition < 0 || partition >= 7 {
+			t.Errorf("partition of %s is out of range: %d", key, partition)
+		}
+	}
+
+	// messages without key stick to an available partition
+	first, _ := p.Partition("test", nil, nil, 10, []int32{3, 5})
+	if first != 3 && first != 5 {
+		t.Errorf("expect an available partition, got %d", first)
+	}
+	if second, _ := p.Partition("test", nil, nil, 10, []int32{3, 5}); second != first {
+		t.Errorf("expect sticky partition %d, got %d", first, second)
+	}
+
+	if partition, err := p.Partition("empty", []byte("foobar"), nil, 0, nil); partition != -1 || err != noAvailablePartition {
+		t.Errorf("expect noAvailablePartition, got %d, %v", partition, err)
+	}
+}
+
+func TestCRC32Partitioner(t *testing.T) {
+	p := &CRC32Partitioner{}
+	for _, c := range []struct {
+		key           string
+		numPartitions int32
+		expected      int32
+	}{
+		{"abc", 10, 8},
+		{"healer", 7, 0},
+		{"", 3, 0},
+	} {
+		partition, err := p.Partition("test", []byte(c.key), nil, c.numPartitions, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if partition != c.expected {
+			t.Errorf("partition of %q in %d partitions: expect %d, got %d", c.key, c.numPartitions, c.expected, partition)
+		}
+	}
+
+	if partition, err := p.Partition("empty", []byte("abc"), nil, 0, nil); partition != -1 || err != noAvailablePartition {
+		t.Errorf("expect noAvailablePartition, got %d, %v", partition, err)
+	}
+}
+
+func TestRoundRobinPartitioner(t *testing.T) {
+	p := &RoundRobinPartitioner{}
+	available := []int32{0, 2, 3}
+	for i, expected := range []int32{0, 2, 3, 0, 2} {
+		partition, err := p.Partition("test", []byte("key"), nil, 4, available)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if partition != expected {
+			t.Errorf("message %d: expect partition %d, got %d", i, expected, partition)
+		}
+	}
+
+	// counters are per topic
+	if partition, _ := p.Partition("another", nil, nil, 4, available); partition != 0 {
+		t.Errorf("expect partition 0 for the first message of another topic, got %d", partition)
+	}
+
+	if partition, err := p.Partition("empty", nil, nil, 0, nil); partition != -1 || err != noAvailablePartition {
+		t.Errorf("expect noAvailablePartition, got %d, %v", partition, err)
+	}
+}
+
+func TestStickyPartitioner(t *testing.T) {
+	p := &StickyPartitioner{}
+	available := []int32{0, 1, 2}
+	first, err := p.Partition("test", []byte("key"), nil, 3, available)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if partition, _ := p.Partition("test", []byte("key"), nil, 3, available); partition != first {
+			t.Fatalf("expect sticky partition %d, got %d", first, partition)
+		}
+	}
+
+	// batch of another partition does not change the sticky partition
+	p.OnNewBatch("test", (first+1)%3)
+	if partition, _ := p.Partition("test", nil, nil, 3, available); partition != first {
+		t.Errorf("expect sticky partition %d, got %d", first, partition)
+	}
+
+	p.OnNewBatch("test", first)
+	second, _ := p.Partition("test", nil, nil, 3, available)
+	if second == first {
+		t.Errorf("expect a new sticky partition other than %d", first)
+	}
+
+	if _, err := p.Partition("empty", nil, nil, 0, nil); err != noAvailablePartition {
+		t.Errorf("expect noAvailablePartition, got %v", err)
+	}
+}
+
+func TestManualPartitioner(t *testing.T) {
+	p := &ManualPartitioner{}
+	if _, err := p.Partition("test", []byte("key"), nil, 3, []int32{0, 1, 2}); err != manualPartitionNotGiven {
+		t.Errorf("expect manualPartitionNotGiven, got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package healer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+var coalescerClosedError = errors.New("producer has been closed")
+
+// produceCoalescer sends the batches of all SimpleProducers in a Producer.
+// the SimpleProducers share one connection to each broker, and the batches to the same broker waiting at the same time are sent in one ProduceRequest
+type produceCoalescer struct {
+	config  *ProducerConfig
+	brokers *Brokers
+
+	mutex  sync.RWMutex
+	closed bool
+	// senders is keyed by node id, so a new connection to the same broker replaces the sender instead of adding one
+	senders map[int32]*brokerSender
+}
+
+func newProduceCoalescer(config *ProducerConfig, brokers *Brokers) *produceCoalescer {
+	return &produceCoalescer{
+		config:  config,
+		brokers: brokers,
+		senders: make(map[int32]*brokerSender),
+	}
+}
+
+// leader returns the shared connection to the leader of the partition
+func (c *produceCoalescer) leader(topic string, partition int32) (*Broker, error) {
+	leaderID, err := c.brokers.findLeader(c.config.ClientID, topic, partition)
+	if err != nil {
+		return nil, err
+	}
+	return c.brokers.GetBroker(leaderID)
+}
+
+// send puts the request of one partition into the queue of the leader, and waits for the response of the partition
+func (c *produceCoalescer) send(leader *Broker, request *ProduceRequest) (*ProduceResponse_PartitionResponse, error) {
+	task := &produceTask{
+		request: request,
+		done:    make(chan struct{}),
+	}
+
+	// the read lock is held while the task is put into the queue, so close never closes the queue in between
+	c.mutex.RLock()
+	if c.closed {
+		c.mutex.RUnlock()
+		return nil, coalescerClosedError
+	}
+	sender, ok := c.senders[leader.nodeID]
+	if !ok || sender.replacedBy(leader) {
+		c.mutex.RUnlock()
+		c.mutex.Lock()
+		if sender, ok = c.senders[leader.nodeID]; (!ok || sender.replacedBy(leader)) && !c.closed {
+			if ok {
+				// the old sender quits after the tasks in its queue are sent
+				close(sender.tasks)
+			}
+			sender = newBrokerSender(leader, c.config)
+			c.senders[leader.nodeID] = sender
+			go sender.run()
+		}
+		c.mutex.Unlock()
+		c.mutex.RLock()
+		if c.closed {
+			c.mutex.RUnlock()
+			return nil, coalescerClosedError
+		}
+	}
+	sender.tasks <- task
+	c.mutex.RUnlock()
+
+	<-task.done
+	return task.response, task.err
+}
+
+// close stops all senders. it must be called after all SimpleProducers are closed
+func (c *produceCoalescer) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for _, sender := range c.senders {
+		close(sender.tasks)
+	}
+}
+
+// produceTask is the request of one partition from SimpleProducer
+type produceTask struct {
+	request *ProduceRequest
+
+	response *ProduceResponse_PartitionResponse
+	err      error
+	done     chan struct{}
+}
+
+func (t *produceTask) topic() string {
+	return t.request.TopicBlocks[0].TopicName
+}
+
+func (t *produceTask) partition() int32 {
+	return t.request.TopicBlocks[0].PartitonBlocks[0].Partition
+}
+
+func (t *produceTask) size() int {
+	block := t.request.TopicBlocks[0].PartitonBlocks[0]
+	if t.request.RequestHeader.ApiVersion >= 3 {
+		return len(block.RecordBatch)
+	}
+	return block.MessageSet.Length()
+}
+
+// compatible tells if the two tasks could be sent in one request
+func (t *produceTask) compatible(other *produceTask) bool {
+	return t.request.RequestHeader.ApiVersion == other.request.RequestHeader.ApiVersion &&
+		t.request.TransactionalID == other.request.TransactionalID &&
+		t.request.RequiredAcks == other.request.RequiredAcks &&
+		t.request.Timeout == other.request.Timeout
+}
+
+// brokerSender sends the tasks to one broker, with at most max.in.flight.requests.per.connection requests in flight.
+// the tasks queued while waiting for a free slot are coalesced into the next request
+type brokerSender struct {
+	broker         *Broker
+	maxRequestSize int
+	tasks          chan *produceTask
+	inFlight       chan struct{}
+}
+
+func newBrokerSender(broker *Broker, config *ProducerConfig) *brokerSender {
+	return &brokerSender{
+		broker:         broker,
+		maxRequestSize: config.MaxRequestSize,
+		tasks:          make(chan *produceTask, 64),
+		inFlight:       make(chan struct{}, config.MaxInFlightRequestsPerConnection),
+	}
+}
+
+// replacedBy tells if the sender should be replaced by a new one of the leader. it is when Brokers has made a new connection
+// to the broker after the connection of the sender is dead. the stale leader in other SimpleProducers still uses the current sender
+func (s *brokerSender) replacedBy(leader *Broker) bool {
+	return s.broker != leader && s.broker.IsDead() && !leader.IsDead()
+}
+
+func (s *brokerSender) run() {
+	var next *produceTask
+	for {
+		first := next
+		next = nil
+		if first == nil {
+			var ok bool
+			if first, ok = <-s.tasks; !ok {
+				return
+			}
+		}
+		s.inFlight <- struct{}{}
+
+		tasks := []*produceTask{first}
+		size := first.size()
+	coalesce:
+		for {
+			select {
+			case task, ok := <-s.tasks:
+				if !ok {
+					break coalesce
+				}
+				if !first.compatible(task) || size+task.size() > s.maxRequestSize {
+					next = task
+					break coalesce
+				}
+				tasks = append(tasks, task)
+				size += task.size()
+			default:
+				break coalesce
+			}
+		}
+
+		// batches of one partition are never in the same or concurrent requests, since SimpleProducer sends them one by one
+		go func() {
+			s.send(tasks)
+			<-s.inFlight
+		}()
+	}
+}
+
+// send merges the tasks into one ProduceRequest, and dispatches the response of each partition to its task
+func (s *brokerSender) send(tasks []*produceTask) {
+	defer func() {
+		for _, task := range tasks {
+			close(task.done)
+		}
+	}()
+
+	first := tasks[0].request
+	request := &ProduceRequest{
+		RequestHeader: &RequestHeader{
+			ApiKey:     API_ProduceRequest,
+			ApiVersion: first.RequestHeader.ApiVersion,
+			ClientId:   first.RequestHeader.ClientId,
+		},
+		TransactionalID: first.TransactionalID,
+		RequiredAcks:    first.RequiredAcks,
+		Timeout:         first.Timeout,
+	}
+	topicIndex := make(map[string]int)
+	for _, task := range tasks {
+		topicBlock := task.request.TopicBlocks[0]
+		i, ok := topicIndex[topicBlock.TopicName]
+		if !ok {
+			i = len(request.TopicBlocks)
+			topicIndex[topicBlock.TopicName] = i
+			request.TopicBlocks = append(request.TopicBlocks, topicBlock)
+			continue
+		}
+		request.TopicBlocks[i].PartitonBlocks = append(request.TopicBlocks[i].PartitonBlocks, topicBlock.PartitonBlocks[0])
+	}
+	glog.V(5).Infof("send %d batches to %s in one produce request", len(tasks), s.broker.GetAddress())
+
+	responseBuf, err := s.broker.Request(request)
+	if err != nil {
+		for _, task := range tasks {
+			task.err = err
+		}
+		return
+	}
+	// no response with acks=0, the tasks succeed once the request is written
+	if request.RequiredAcks == 0 {
+		return
+	}
+	// the error of the first failed partition is returned together with the response. each partition is checked below
+	response, err := NewProduceResponse(responseBuf, request.RequestHeader.ApiVersion)
+	if response == nil {
+		for _, task := range tasks {
+			task.err = err
+		}
+		return
+	}
+
+	partitionResponses := make(map[topicPartition]*ProduceResponse_PartitionResponse)
+	for _, piece := range response.ProduceResponses {
+		for _, partitionResponse := range piece.Partitions {
+			partitionResponses[topicPartition{piece.Topic, partitionResponse.PartitionID}] = partitionResponse
+		}
+	}
+	for _, task := range tasks {
+		partitionResponse, ok := partitionResponses[topicPartition{task.topic(), task.partition()}]
+		if !ok {
+			task.err = fmt.Errorf("no response of %s[%d] in produce response", task.topic(), task.partition())
+			continue
+		}
+		task.response = partitionResponse
+		if partitionResponse.ErrorCode != 0 {
+			task.err = getErrorFromErrorCode(partitionResponse.ErrorCode)
+		}
+	}
+}
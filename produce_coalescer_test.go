@@ -0,0 +1,187 @@
+package healer
+
+import (
+	"sync"
+	"testing"
+)
+
+// fakeCoalescingBroker answers produce v3 requests of any partitions. base offset of a partition is partition*100,
+// and the partitions in errorPartitions get NOT_ENOUGH_REPLICAS
+type fakeCoalescingBroker struct {
+	errorPartitions map[int32]bool
+
+	mutex    sync.Mutex
+	requests [][]string // topic of each partition in each produce request
+}
+
+func (b *fakeCoalescingBroker) getRequests() [][]string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.requests
+}
+
+func (b *fakeCoalescingBroker) produceResponse(body []byte) []byte {
+	var (
+		results []fakeProduceResult
+		topics  []string
+	)
+	for _, p := range parseFakeProduceRequest(body).partitions {
+		topics = append(topics, p.topic)
+		result := fakeProduceResult{p.topic, p.partition, 0, int64(p.partition) * 100, -1}
+		if b.errorPartitions[p.partition] {
+			result.errorCode, result.baseOffset = 19, -1
+		}
+		results = append(results, result)
+	}
+
+	b.mutex.Lock()
+	b.requests = append(b.requests, topics)
+	b.mutex.Unlock()
+	return fakeProduceResponse(results)
+}
+
+func (b *fakeCoalescingBroker) handle(request *fakeRequest) []byte {
+	switch request.apiKey {
+	case API_ApiVersions:
+		return fakeApiVersionsResponse(map[uint16]uint16{API_ProduceRequest: 3})
+	case API_ProduceRequest:
+		return b.produceResponse(request.body)
+	}
+	return nil
+}
+
+// newProduceTask returns a task of a v3 request with a fake record batch of size bytes
+func newProduceTask(topic string, partition int32, size int) *produceTask {
+	request := &ProduceRequest{
+		RequestHeader: &RequestHeader{
+			ApiKey:     API_ProduceRequest,
+			ApiVersion: 3,
+			ClientId:   "healer",
+		},
+		RequiredAcks: 1,
+		Timeout:      30000,
+	}
+	request.TopicBlocks = make([]struct {
+		TopicName      string
+		PartitonBlocks []struct {
+			Partition      int32
+			MessageSetSize int32
+			MessageSet     MessageSet
+			RecordBatch    []byte
+		}
+	}, 1)
+	request.TopicBlocks[0].TopicName = topic
+	request.TopicBlocks[0].PartitonBlocks = make([]struct {
+		Partition      int32
+		MessageSetSize int32
+		MessageSet     MessageSet
+		RecordBatch    []byte
+	}, 1)
+	request.TopicBlocks[0].PartitonBlocks[0].Partition = partition
+	request.TopicBlocks[0].PartitonBlocks[0].MessageSetSize = int32(size)
+	request.TopicBlocks[0].PartitonBlocks[0].RecordBatch = make([]byte, size)
+	return &produceTask{request: request, done: make(chan struct{})}
+}
+
+func TestBrokerSenderCoalesce(t *testing.T) {
+	fakeBroker := &fakeCoalescingBroker{errorPartitions: map[int32]bool{1: true}}
+	server := newFakeBroker(t, fakeBroker.handle)
+	defer server.close()
+	leader := server.connect(t, nil)
+	defer leader.Close()
+
+	config := DefaultProducerConfig()
+	config.MaxRequestSize = 250
+	sender := newBrokerSender(leader, config)
+	tasks := []*produceTask{
+		newProduceTask("a", 0, 100),
+		newProduceTask("b", 1, 100),
+		newProduceTask("a", 2, 100), // exceeds max.request.size together with the first two
+		newProduceTask("b", 3, 100),
+	}
+	for _, task := range tasks {
+		sender.tasks <- task
+	}
+	close(sender.tasks)
+	sender.run()
+	for _, task := range tasks {
+		<-task.done
+	}
+
+	requests := fakeBroker.getRequests()
+	if len(requests) != 2 || len(requests[0]) != 2 || len(requests[1]) != 2 {
+		t.Fatalf("expect 2 requests of 2 partitions, got %v", requests)
+	}
+	for i, task := range tasks {
+		if task.topic() == "b" && task.partition() == 1 {
+			if task.err != AllError[19] {
+				t.Errorf("expect NOT_ENOUGH_REPLICAS of partition 1, got %v", task.err)
+			}
+			continue
+		}
+		if task.err != nil || task.response == nil || task.response.BaseOffset != int64(task.partition())*100 {
+			t.Errorf("unexpected response of task %d: %+v %v", i, task.response, task.err)
+		}
+	}
+}
+
+func TestProduceCoalescerSend(t *testing.T) {
+	fakeBroker := &fakeCoalescingBroker{}
+	server := newFakeBroker(t, fakeBroker.handle)
+	defer server.close()
+	leader := server.connect(t, nil)
+	defer leader.Close()
+
+	c := newProduceCoalescer(DefaultProducerConfig(), nil)
+	var wg sync.WaitGroup
+	for partition := int32(0); partition < 5; partition++ {
+		wg.Add(1)
+		go func(partition int32) {
+			defer wg.Done()
+			response, err := c.send(leader, newProduceTask("test", partition, 10).request)
+			if err != nil || response.PartitionID != partition || response.BaseOffset != int64(partition)*100 {
+				t.Errorf("unexpected response of partition %d: %+v %v", partition, response, err)
+			}
+		}(partition)
+	}
+	wg.Wait()
+
+	partitions := 0
+	for _, request := range fakeBroker.getRequests() {
+		partitions += len(request)
+	}
+	if partitions != 5 || len(fakeBroker.getRequests()) > 5 {
+		t.Errorf("expect 5 partitions sent, got %v", fakeBroker.getRequests())
+	}
+
+	c.close()
+	if _, err := c.send(leader, newProduceTask("test", 0, 10).request); err != coalescerClosedError {
+		t.Errorf("expect coalescerClosedError after close, got %v", err)
+	}
+}
+
+func TestProduceCoalescerReplaceSender(t *testing.T) {
+	server := newFakeBroker(t, (&fakeCoalescingBroker{}).handle)
+	defer server.close()
+	oldLeader := server.connect(t, nil)
+	defer oldLeader.Close()
+
+	c := newProduceCoalescer(DefaultProducerConfig(), nil)
+	defer c.close()
+	if _, err := c.send(oldLeader, newProduceTask("test", 0, 10).request); err != nil {
+		t.Fatalf("send error: %s", err)
+	}
+
+	// Brokers makes a new connection after the old one is dead
+	oldLeader.Close()
+	newLeader := server.connect(t, nil)
+	defer newLeader.Close()
+	for _, leader := range []*Broker{newLeader, oldLeader} {
+		if _, err := c.send(leader, newProduceTask("test", 0, 10).request); err != nil {
+			t.Fatalf("send error: %s", err)
+		}
+		if len(c.senders) != 1 || c.senders[newLeader.nodeID].broker != newLeader {
+			t.Errorf("expect the only sender of the new leader, got %v", c.senders)
+		}
+	}
+}
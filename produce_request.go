@@ -23,27 +23,51 @@ TopicName		The topic that data is being published to.
 Partition		The partition that data is being published to.
 MessageSetSize	The size, in bytes, of the message set that follows.
 MessageSet		A set of messages in the standard format described above.
+
+Produce Request (Version: 3) => transactional_id acks timeout [topic_data]
+  transactional_id => NULLABLE_STRING
+  acks => INT16
+  timeout => INT32
+  topic_data => topic [data]
+    topic => STRING
+    data => partition record_set
+      partition => INT32
+      record_set => RECORDS
+
+transactional_id	The transactional ID of the producer. This is used to authorize transaction produce requests. This can be null for non-transactional producers.
+record_set			Since v3, it is a RecordBatch(magic 2). It is encoded by the producer and put in RecordBatch as bytes.
+
+Produce Request (Version: 7) has the same fields with version 3. The broker accepts zstd compressed RecordBatch since version 7.
 */
 type ProduceRequest struct {
-	RequestHeader *RequestHeader
-	RequiredAcks  int16
-	Timeout       int32
-	TopicBlocks   []struct {
+	RequestHeader   *RequestHeader
+	TransactionalID string
+	RequiredAcks    int16
+	Timeout         int32
+	TopicBlocks     []struct {
 		TopicName      string
 		PartitonBlocks []struct {
 			Partition      int32
 			MessageSetSize int32
 			MessageSet     MessageSet
+			RecordBatch    []byte
 		}
 	}
 }
 
 func (produceRequest *ProduceRequest) Length() int {
 	requestLength := produceRequest.RequestHeader.length() + 10 //	RequiredAcks(2) + Timeout(4) + TopicBlocks_length(4)
+	if produceRequest.RequestHeader.ApiVersion >= 3 {
+		requestLength += 2 + len(produceRequest.TransactionalID)
+	}
 	for _, topicBlock := range produceRequest.TopicBlocks {
 		requestLength += 6 + len(topicBlock.TopicName)
 		for _, parttionBlock := range topicBlock.PartitonBlocks {
-			requestLength += 8 + parttionBlock.MessageSet.Length()
+			if produceRequest.RequestHeader.ApiVersion >= 3 {
+				requestLength += 8 + len(parttionBlock.RecordBatch)
+			} else {
+				requestLength += 8 + parttionBlock.MessageSet.Length()
+			}
 		}
 	}
 
@@ -60,6 +84,17 @@ func (produceRequest *ProduceRequest) Encode() []byte {
 
 	offset = produceRequest.RequestHeader.Encode(payload, offset)
 
+	if produceRequest.RequestHeader.ApiVersion >= 3 {
+		if produceRequest.TransactionalID == "" {
+			binary.BigEndian.PutUint16(payload[offset:], uint16(0xffff))
+			offset += 2
+		} else {
+			binary.BigEndian.PutUint16(payload[offset:], uint16(len(produceRequest.TransactionalID)))
+			offset += 2
+			offset += copy(payload[offset:], produceRequest.TransactionalID)
+		}
+	}
+
 	binary.BigEndian.PutUint16(payload[offset:], uint16(produceRequest.RequiredAcks))
 	offset += 2
 	binary.BigEndian.PutUint32(payload[offset:], uint32(produceRequest.Timeout))
@@ -78,6 +113,14 @@ func (produceRequest *ProduceRequest) Encode() []byte {
 		for _, parttionBlock := range topicBlock.PartitonBlocks {
 			binary.BigEndian.PutUint32(payload[offset:], uint32(parttionBlock.Partition))
 			offset += 4
+
+			if produceRequest.RequestHeader.ApiVersion >= 3 {
+				binary.BigEndian.PutUint32(payload[offset:], uint32(len(parttionBlock.RecordBatch)))
+				offset += 4
+				offset += copy(payload[offset:], parttionBlock.RecordBatch)
+				continue
+			}
+
 			binary.BigEndian.PutUint32(payload[offset:], uint32(parttionBlock.MessageSet.Length()))
 			offset += 4
 
@@ -25,10 +25,44 @@ error_code	Response error code
 base_offset	null
  ========= */
 
+/* =========
+Produce Response (Version: 3) => [responses] throttle_time_ms
+  responses => topic [partition_responses]
+    topic => STRING
+    partition_responses => partition error_code base_offset log_append_time
+      partition => INT32
+      error_code => INT16
+      base_offset => INT64
+      log_append_time => INT64
+  throttle_time_ms => INT32
+
+FIELD	DESCRIPTION
+log_append_time	The timestamp returned by broker after appending the messages. If CreateTime is used for the topic, the timestamp will be -1. If LogAppendTime is used for the topic, the timestamp will be the broker local time when the messages are appended.
+throttle_time_ms	Duration in milliseconds for which the request was throttled due to quota violation (Zero if the request did not violate any quota)
+ ========= */
+
+/* =========
+Produce Response (Version: 7) => [responses] throttle_time_ms
+  responses => topic [partition_responses]
+    topic => STRING
+    partition_responses => partition error_code base_offset log_append_time log_start_offset
+      partition => INT32
+      error_code => INT16
+      base_offset => INT64
+      log_append_time => INT64
+      log_start_offset => INT64
+  throttle_time_ms => INT32
+
+FIELD	DESCRIPTION
+log_start_offset	The start offset of the log at the time this produce response was created. It is added in version 5
+ ========= */
+
 type ProduceResponse_PartitionResponse struct {
-	PartitionID int32
-	ErrorCode   int16
-	BaseOffset  int64
+	PartitionID    int32
+	ErrorCode      int16
+	BaseOffset     int64
+	LogAppendTime  int64
+	LogStartOffset int64
 }
 
 type ProduceResponsePiece struct {
@@ -39,9 +73,10 @@ type ProduceResponsePiece struct {
 type ProduceResponse struct {
 	CorrelationID    uint32
 	ProduceResponses []*ProduceResponsePiece
+	ThrottleTimeMS   int32
 }
 
-func NewProduceResponse(payload []byte) (*ProduceResponse, error) {
+func NewProduceResponse(payload []byte, version uint16) (*ProduceResponse, error) {
 	var (
 		r      *ProduceResponse = &ProduceResponse{}
 		err    error            = nil
@@ -83,11 +118,24 @@ func NewProduceResponse(payload []byte) (*ProduceResponse, error) {
 			}
 			p.BaseOffset = int64(binary.BigEndian.Uint64(payload[offset:]))
 			offset += 8
+			if version >= 2 {
+				p.LogAppendTime = int64(binary.BigEndian.Uint64(payload[offset:]))
+				offset += 8
+			}
+			if version >= 5 {
+				p.LogStartOffset = int64(binary.BigEndian.Uint64(payload[offset:]))
+				offset += 8
+			}
 			produceResponse.Partitions[j] = p
 		}
 
 		r.ProduceResponses[i] = produceResponse
 	}
 
+	if version >= 1 {
+		r.ThrottleTimeMS = int32(binary.BigEndian.Uint32(payload[offset:]))
+		offset += 4
+	}
+
 	return r, err
 }
@@ -1,25 +1,61 @@
 package healer
 
 import (
+	"context"
 	"errors"
-	"math/rand"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
-	"github.com/aviddiviner/go-murmur"
 	"github.com/golang/glog"
 )
 
+var topicNotGivenError = errors.New("topic is not given, which is needed by the producer created by NewMultiTopicProducer")
+
 type Producer struct {
-	config             *ProducerConfig
-	topic              string
-	simpleProducers    map[int32]*SimpleProducer
-	currentProducer    *SimpleProducer
-	currentPartitionID int32
-	brokers            *Brokers
-	topicMeta          *TopicMetadata
+	config      *ProducerConfig
+	topic       string // topic of the messages added without topic. it is empty if the Producer is created by NewMultiTopicProducer
+	partitioner Partitioner
+	brokers     *Brokers
+	pid         *producerIDManager
+	txn         *transactionManager
+	buffer      *bufferMemory
+	coalescer   *produceCoalescer
+
+	// mutex protects topics
+	mutex  sync.Mutex
+	topics map[string]*topicPartitions
+}
+
+// topicPartitions is the cached metadata of one topic, and the SimpleProducers of its partitions
+type topicPartitions struct {
+	numPartitions       int32
+	availablePartitions []int32
+	simpleProducers     map[int32]*SimpleProducer
 }
 
+// NewProducer creates a Producer of the topic. messages of other topics could be added by AddMessageToTopic too
 func NewProducer(topic string, config *ProducerConfig) *Producer {
+	p := newProducer(topic, config)
+	if p == nil {
+		return nil
+	}
+
+	if _, err := p.getTopic(topic); err != nil {
+		glog.Error(err)
+		return nil
+	}
+	return p
+}
+
+// NewMultiTopicProducer creates a Producer which takes the topic of each message by AddMessageToTopic or SendToTopic.
+// metadata of a topic is got on the first message to it, and all topics share the connections and buffer.memory
+func NewMultiTopicProducer(config *ProducerConfig) *Producer {
+	return newProducer("", config)
+}
+
+func newProducer(topic string, config *ProducerConfig) *Producer {
 	var err error
 	err = config.checkValid()
 	if err != nil {
@@ -28,91 +64,345 @@ func NewProducer(topic string, config *ProducerConfig) *Producer {
 	}
 
 	p := &Producer{
-		config:          config,
-		topic:           topic,
-		simpleProducers: make(map[int32]*SimpleProducer),
+		config:      config,
+		topic:       topic,
+		partitioner: config.Partitioner,
+		topics:      make(map[string]*topicPartitions),
+		buffer:      newBufferMemory(config),
+	}
+	if p.partitioner == nil {
+		p.partitioner = &Murmur2Partitioner{}
 	}
 
-	p.brokers, err = NewBrokers(config.BootstrapServers, config.ClientID, DefaultBrokerConfig())
+	p.brokers, err = NewBrokers(config.BootstrapServers, config.ClientID, getBrokerConfigFromProducerConfig(config))
 	if err != nil {
 		glog.Errorf("init brokers error: %s", err)
 		return nil
 	}
+	p.coalescer = newProduceCoalescer(config, p.brokers)
 
-	err = p.refreshTopicMeta()
-	if err != nil {
-		glog.Error(err)
-		return nil
+	// all partitions share one producer id
+	if config.EnableIdempotence {
+		p.pid, err = newProducerIDManager(config)
+		if err != nil {
+			glog.Errorf("init producer id error: %s", err)
+			return nil
+		}
 	}
-	p.refreshCurrentProducer()
-	if p.currentProducer == nil {
-		return nil
+	if config.TransactionalID != "" {
+		p.txn = newTransactionManager(config, p.brokers, p.pid)
 	}
 
 	go func() {
 		for range time.NewTicker(time.Duration(config.MetadataMaxAgeMS) * time.Millisecond).C {
-			err := p.refreshTopicMeta()
-			if err != nil {
+			p.mutex.Lock()
+			topics := make([]string, 0, len(p.topics))
+			for topic := range p.topics {
+				topics = append(topics, topic)
+			}
+			p.mutex.Unlock()
+
+			if err := p.refreshTopicMeta(topics); err != nil {
 				glog.Error(err)
 			}
-			p.refreshCurrentProducer()
 		}
 	}()
 
 	return p
 }
 
-func (p *Producer) refreshTopicMeta() error {
-	for i := 0; i < p.config.FetchTopicMetaDataRetrys; i++ {
-		metadataResponse, err := p.brokers.RequestMetaData(p.config.ClientID, []string{p.topic})
-		if err != nil {
+// refreshTopicMeta gets the metadata of all the topics in one request, and updates the partitions of them.
+// it returns error if metadata of any topic is not got after all tries
+func (p *Producer) refreshTopicMeta(topics []string) error {
+	var err error
+	for i := 0; i < p.config.FetchTopicMetaDataRetrys && len(topics) > 0; i++ {
+		var metadataResponse *MetadataResponse
+		// the response comes together with the error of any topic or partition. they are checked one by one below
+		metadataResponse, err = p.brokers.RequestMetaData(p.config.ClientID, topics)
+		if metadataResponse == nil {
 			glog.Errorf("get topic metadata error: %s", err)
 			continue
 		}
 		if len(metadataResponse.TopicMetadatas) == 0 {
-			glog.Errorf("get topic metadata error: %s", zeroTopicMetadata)
+			err = zeroTopicMetadata
+			glog.Errorf("get topic metadata error: %s", err)
 			continue
 		}
-		p.topicMeta = metadataResponse.TopicMetadatas[0]
-		return nil
+
+		updated := make(map[string]bool)
+		for _, topicMeta := range metadataResponse.TopicMetadatas {
+			if topicMeta.TopicErrorCode != 0 {
+				err = getErrorFromErrorCode(topicMeta.TopicErrorCode)
+				glog.Errorf("get metadata of %s error: %s", topicMeta.TopicName, err)
+				continue
+			}
+			if len(topicMeta.PartitionMetadatas) == 0 {
+				err = fmt.Errorf("no partition in %s", topicMeta.TopicName)
+				glog.Errorf("get topic metadata error: %s", err)
+				continue
+			}
+			p.updateTopicMeta(topicMeta)
+			updated[topicMeta.TopicName] = true
+		}
+
+		remaining := make([]string, 0, len(topics))
+		for _, topic := range topics {
+			if !updated[topic] {
+				remaining = append(remaining, topic)
+			}
+		}
+		topics = remaining
 	}
-	return errors.New("failed to get topic meta after all tries")
+	if len(topics) > 0 {
+		return fmt.Errorf("failed to get topic meta of %v after all tries: %v", topics, err)
+	}
+	return nil
 }
 
-func (p *Producer) refreshCurrentProducer() {
-	var validPartitionID []int32
-	for _, partition := range p.topicMeta.PartitionMetadatas {
+func (p *Producer) updateTopicMeta(topicMeta *TopicMetadata) {
+	availablePartitions := make([]int32, 0, len(topicMeta.PartitionMetadatas))
+	for _, partition := range topicMeta.PartitionMetadatas {
 		if partition.PartitionErrorCode == 0 {
-			validPartitionID = append(validPartitionID, partition.PartitionID)
+			availablePartitions = append(availablePartitions, partition.PartitionID)
 		}
 	}
-	rand.Seed(time.Now().Unix())
-	partitionID := validPartitionID[rand.Int31n(int32(len(validPartitionID)))]
-	glog.V(5).Infof("current partitionID is %d", partitionID)
-	sp := NewSimpleProducer(p.topic, partitionID, p.config)
+	sort.Slice(availablePartitions, func(i, j int) bool { return availablePartitions[i] < availablePartitions[j] })
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	t, ok := p.topics[topicMeta.TopicName]
+	if !ok {
+		t = &topicPartitions{simpleProducers: make(map[int32]*SimpleProducer)}
+		p.topics[topicMeta.TopicName] = t
+	}
+	t.numPartitions = int32(len(topicMeta.PartitionMetadatas))
+	t.availablePartitions = availablePartitions
+}
+
+// getTopic returns the cache of the topic, getting the metadata of it on the first message to it
+func (p *Producer) getTopic(topic string) (*topicPartitions, error) {
+	if topic == "" {
+		return nil, topicNotGivenError
+	}
+
+	p.mutex.Lock()
+	t, ok := p.topics[topic]
+	p.mutex.Unlock()
+	if ok {
+		return t, nil
+	}
+
+	if err := p.refreshTopicMeta([]string{topic}); err != nil {
+		return nil, err
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.topics[topic], nil
+}
+
+// getSimpleProducer returns the SimpleProducer of the partition, creating it on the first message to the partition
+func (p *Producer) getSimpleProducer(topic string, partitionID int32) (*SimpleProducer, error) {
+	t, err := p.getTopic(topic)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if partitionID < 0 || partitionID >= t.numPartitions {
+		return nil, fmt.Errorf("invalid partition %d of %s, which has %d partitions", partitionID, topic, t.numPartitions)
+	}
+	if sp, ok := t.simpleProducers[partitionID]; ok {
+		return sp, nil
+	}
+
+	sp := newSimpleProducer(topic, partitionID, p.config, p.pid, p.txn, p.buffer, p.coalescer)
 	if sp == nil {
-		glog.Error("could not referesh current simple producer")
+		return nil, fmt.Errorf("could not create simple producer of %s[%d]", topic, partitionID)
+	}
+	if listener, ok := p.partitioner.(NewBatchListener); ok {
+		sp.onNewBatch = func() {
+			listener.OnNewBatch(topic, partitionID)
+		}
+	}
+	t.simpleProducers[partitionID] = sp
+	return sp, nil
+}
+
+// getSimpleProducers returns a snapshot of the SimpleProducers created, of all topics
+func (p *Producer) getSimpleProducers() []*SimpleProducer {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	simpleProducers := make([]*SimpleProducer, 0)
+	for _, t := range p.topics {
+		for _, sp := range t.simpleProducers {
+			simpleProducers = append(simpleProducers, sp)
+		}
 	}
-	p.currentProducer = sp
-	p.simpleProducers[partitionID] = p.currentProducer
+	return simpleProducers
 }
 
 func (p *Producer) AddMessage(key []byte, value []byte) error {
-	if key == nil || len(key) == 0 {
-		return p.currentProducer.AddMessage(key, value)
+	return p.AddMessageWithHeaders(key, value, nil)
+}
+
+// AddMessageWithHeaders adds a message with record headers, which need kafka 0.11.0+
+func (p *Producer) AddMessageWithHeaders(key []byte, value []byte, headers []RecordHeader) error {
+	return p.AddMessageWithTimestamp(key, value, headers, time.Now().UnixNano()/1000000)
+}
+
+// AddMessageWithTimestamp adds a message with CreateTime timestamp in milliseconds set by the caller.
+// the partition is chosen by the Partitioner in config
+func (p *Producer) AddMessageWithTimestamp(key []byte, value []byte, headers []RecordHeader, timestamp int64) error {
+	return p.AddMessageWithCallback(key, value, headers, timestamp, nil)
+}
+
+// AddMessageWithCallback adds a message whose delivery report is sent to callback once it is sent or fails
+func (p *Producer) AddMessageWithCallback(key []byte, value []byte, headers []RecordHeader, timestamp int64, callback DeliveryCallback) error {
+	return p.AddMessageToTopic(p.topic, key, value, headers, timestamp, callback)
+}
+
+// AddMessageToTopic adds a message to the topic, and the partition is chosen by the Partitioner in config.
+// callback could be nil
+func (p *Producer) AddMessageToTopic(topic string, key []byte, value []byte, headers []RecordHeader, timestamp int64, callback DeliveryCallback) error {
+	partitionID, err := p.partition(topic, key, value)
+	if err != nil {
+		return err
+	}
+	_, err = p.addMessage(topic, partitionID, key, value, headers, timestamp, callback)
+	return err
+}
+
+// AddMessageToPartition adds a message to the partition given by the caller, bypassing the Partitioner
+func (p *Producer) AddMessageToPartition(partitionID int32, key []byte, value []byte, headers []RecordHeader, timestamp int64) error {
+	return p.AddMessageToTopicPartition(p.topic, partitionID, key, value, headers, timestamp)
+}
+
+// AddMessageToTopicPartition adds a message to the partition of the topic given by the caller, bypassing the Partitioner
+func (p *Producer) AddMessageToTopicPartition(topic string, partitionID int32, key []byte, value []byte, headers []RecordHeader, timestamp int64) error {
+	_, err := p.addMessage(topic, partitionID, key, value, headers, timestamp, nil)
+	return err
+}
+
+// Send adds the message and flushes its partition at once. it blocks until the broker acknowledges the message per acks, or ctx is done.
+// it returns the partition and the offset of the message. the message may still be sent after ctx is done.
+// with acks=0 it returns once the message is written, and the offset is always -1
+func (p *Producer) Send(ctx context.Context, key []byte, value []byte) (int32, int64, error) {
+	return p.SendToTopic(ctx, p.topic, key, value)
+}
+
+// SendToTopic is the same as Send, but sends the message to the topic given by the caller
+func (p *Producer) SendToTopic(ctx context.Context, topic string, key []byte, value []byte) (int32, int64, error) {
+	partitionID, err := p.partition(topic, key, value)
+	if err != nil {
+		return -1, -1, err
+	}
+
+	delivered := make(chan *DeliveryReport, 1)
+	sp, err := p.addMessage(topic, partitionID, key, value, nil, time.Now().UnixNano()/1000000, func(report *DeliveryReport) {
+		delivered <- report
+	})
+	if err != nil {
+		return partitionID, -1, err
+	}
+	// the error is reported to the callback too
+	go sp.Flush()
+
+	select {
+	case report := <-delivered:
+		return report.Partition, report.Offset, report.Err
+	case <-ctx.Done():
+		return partitionID, -1, ctx.Err()
+	}
+}
+
+// partition chooses the partition of the message by the Partitioner in config
+func (p *Producer) partition(topic string, key []byte, value []byte) (int32, error) {
+	t, err := p.getTopic(topic)
+	if err != nil {
+		return -1, err
+	}
+
+	p.mutex.Lock()
+	numPartitions, availablePartitions := t.numPartitions, t.availablePartitions
+	p.mutex.Unlock()
+
+	return p.partitioner.Partition(topic, key, value, numPartitions, availablePartitions)
+}
+
+// addMessage adds the message to the SimpleProducer of the partition and returns it
+func (p *Producer) addMessage(topic string, partitionID int32, key []byte, value []byte, headers []RecordHeader, timestamp int64, callback DeliveryCallback) (*SimpleProducer, error) {
+	if p.txn != nil && !p.txn.isInTransaction() {
+		return nil, transactionNotBegunError
+	}
+	sp, err := p.getSimpleProducer(topic, partitionID)
+	if err != nil {
+		return nil, err
+	}
+	return sp, sp.AddMessageWithCallback(key, value, headers, timestamp, callback)
+}
+
+// BufferMetrics returns the usage of buffer.memory shared by all partitions
+func (p *Producer) BufferMetrics() BufferMetrics {
+	return p.buffer.metrics()
+}
+
+// BeginTransaction starts a transaction. all messages added before CommitTransaction or AbortTransaction belong to it
+func (p *Producer) BeginTransaction() error {
+	if p.txn == nil {
+		return notTransactionalError
 	}
-	partitionID := int32(murmur.MurmurHash2(key, 0)) % int32(len(p.topicMeta.PartitionMetadatas))
-	if s, ok := p.simpleProducers[partitionID]; ok {
-		return s.AddMessage(key, value)
-	} else {
-		simpleProducer := NewSimpleProducer(p.topic, partitionID, p.config)
-		p.simpleProducers[partitionID] = simpleProducer
-		return simpleProducer.AddMessage(key, value)
+	return p.txn.begin()
+}
+
+// SendOffsetsToTransaction commits the consumed offsets of the group, only if the transaction is committed.
+// offsets are topic -> partition -> the next offset to consume
+func (p *Producer) SendOffsetsToTransaction(offsets map[string]map[int32]int64, groupID string) error {
+	if p.txn == nil {
+		return notTransactionalError
+	}
+	return p.txn.sendOffsets(offsets, groupID)
+}
+
+// CommitTransaction flushes all messages and commits the transaction. call AbortTransaction if it fails
+func (p *Producer) CommitTransaction() error {
+	if p.txn == nil {
+		return notTransactionalError
+	}
+	if !p.txn.isInTransaction() {
+		return transactionNotBegunError
+	}
+	for _, sp := range p.getSimpleProducers() {
+		if err := sp.Flush(); err != nil {
+			return fmt.Errorf("flush %s[%d] before commit error: %s", sp.topic, sp.partition, err)
+		}
+	}
+	return p.txn.end(true)
+}
+
+// AbortTransaction aborts the transaction. messages in it are never seen by read_committed consumers
+func (p *Producer) AbortTransaction() error {
+	if p.txn == nil {
+		return notTransactionalError
+	}
+	if !p.txn.isInTransaction() {
+		return transactionNotBegunError
+	}
+	// buffered messages are sent so that the partitions are cleaned up together with the others by the coordinator
+	for _, sp := range p.getSimpleProducers() {
+		if err := sp.Flush(); err != nil {
+			glog.Errorf("flush %s[%d] before abort error: %s", sp.topic, sp.partition, err)
+		}
 	}
+	return p.txn.end(false)
 }
 
 func (p *Producer) Close() {
-	for _, sp := range p.simpleProducers {
+	for _, sp := range p.getSimpleProducers() {
 		sp.Close()
 	}
+	p.coalescer.close()
 }
@@ -0,0 +1,117 @@
+package healer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProducerSend(t *testing.T) {
+	config := DefaultProducerConfig()
+	sp, cleanup := newFakeProduceSimpleProducer(t, &fakeProduceBroker{baseOffset: 100, logAppendTime: -1}, config)
+	defer cleanup()
+
+	p := &Producer{
+		config:      config,
+		topic:       "test",
+		partitioner: &RoundRobinPartitioner{},
+		topics: map[string]*topicPartitions{
+			"test": {numPartitions: 3, availablePartitions: []int32{2}, simpleProducers: map[int32]*SimpleProducer{2: sp}},
+		},
+	}
+
+	partition, offset, err := p.Send(context.Background(), []byte("key"), []byte("hello"))
+	if err != nil {
+		t.Fatalf("send error: %s", err)
+	}
+	if partition != 2 || offset != 100 {
+		t.Errorf("expect partition 2 and offset 100, got %d and %d", partition, offset)
+	}
+}
+
+func TestProducerSendWithoutAcks(t *testing.T) {
+	config := DefaultProducerConfig()
+	config.Acks = 0
+	fakeBroker := &fakeProduceBroker{baseOffset: 100, logAppendTime: -1}
+	sp, cleanup := newFakeProduceSimpleProducer(t, fakeBroker, config)
+	defer cleanup()
+
+	p := &Producer{
+		config:      config,
+		topic:       "test",
+		partitioner: &RoundRobinPartitioner{},
+		topics: map[string]*topicPartitions{
+			"test": {numPartitions: 3, availablePartitions: []int32{2}, simpleProducers: map[int32]*SimpleProducer{2: sp}},
+		},
+	}
+
+	// the broker never answers, so Send must not wait for the response until the request timeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	partition, offset, err := p.Send(ctx, []byte("key"), []byte("hello"))
+	if err != nil {
+		t.Fatalf("send error: %s", err)
+	}
+	if partition != 2 || offset != -1 {
+		t.Errorf("expect partition 2 and offset -1, got %d and %d", partition, offset)
+	}
+	// Send returns before the broker reads the request
+	for i := 0; i < 100 && fakeBroker.getProduceRequests() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if fakeBroker.getProduceRequests() != 1 {
+		t.Errorf("expect 1 produce request, got %d", fakeBroker.getProduceRequests())
+	}
+}
+
+func TestProducerSendError(t *testing.T) {
+	config := DefaultProducerConfig()
+	// MESSAGE_TOO_LARGE is not retriable
+	sp, cleanup := newFakeProduceSimpleProducer(t, &fakeProduceBroker{errorCode: 10, baseOffset: -1, logAppendTime: -1}, config)
+	defer cleanup()
+
+	p := &Producer{
+		config:      config,
+		topic:       "test",
+		partitioner: &RoundRobinPartitioner{},
+		topics: map[string]*topicPartitions{
+			"test": {numPartitions: 3, availablePartitions: []int32{2}, simpleProducers: map[int32]*SimpleProducer{2: sp}},
+		},
+	}
+
+	if _, offset, err := p.Send(context.Background(), nil, []byte("hello")); err != AllError[10] || offset != -1 {
+		t.Errorf("expect MESSAGE_TOO_LARGE with offset -1, got %v and %d", err, offset)
+	}
+}
+
+func TestMultiTopicProducer(t *testing.T) {
+	c := newFakeCluster(t)
+	defer c.close()
+
+	config := DefaultProducerConfig()
+	config.BootstrapServers = c.nodes[0].address()
+	p := NewMultiTopicProducer(config)
+	if p == nil {
+		t.Fatal("create multi topic producer error")
+	}
+	defer p.Close()
+
+	if err := p.AddMessage(nil, []byte("hello")); err != topicNotGivenError {
+		t.Errorf("expect topicNotGivenError without topic, got %v", err)
+	}
+
+	partition, offset, err := p.SendToTopic(context.Background(), "test", nil, []byte("hello"))
+	if err != nil {
+		t.Fatalf("send error: %s", err)
+	}
+	if partition != 0 || offset != 100 {
+		t.Errorf("expect partition 0 and offset 100, got %d and %d", partition, offset)
+	}
+	if cached, err := p.getTopic("test"); err != nil || cached.numPartitions != 1 || len(cached.simpleProducers) != 1 {
+		t.Errorf("expect metadata of test cached with 1 partition, got %+v %v", cached, err)
+	}
+
+	if err := p.AddMessageToTopicPartition("test", 1, nil, []byte("hello"), nil, 0); err == nil {
+		t.Error("expect error of invalid partition")
+	}
+}
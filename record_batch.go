@@ -0,0 +1,448 @@
+package healer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+/*
+Since 0.11.0, kafka stores and transfers messages in RecordBatch (magic 2) instead of MessageSet.
+
+RecordBatch =>
+  BaseOffset => int64
+  BatchLength => int32
+  PartitionLeaderEpoch => int32
+  Magic => int8 (current magic value is 2)
+  CRC => uint32
+  Attributes => int16
+  LastOffsetDelta => int32
+  FirstTimestamp => int64
+  MaxTimestamp => int64
+  ProducerId => int64
+  ProducerEpoch => int16
+  BaseSequence => int32
+  Records => [Record]
+
+Record =>
+  Length => varint
+  Attributes => int8
+  TimestampDelta => varint
+  OffsetDelta => varint
+  KeyLength => varint
+  Key => data
+  ValueLen => varint
+  Value => data
+  Headers => [Header]
+
+Header =>
+  HeaderKeyLength => varint
+  HeaderKey => String
+  HeaderValueLength => varint
+  Value => data
+
+BatchLength		The length of the batch from PartitionLeaderEpoch to the end.
+CRC				The CRC-32C (Castagnoli) of the data from Attributes to the end of the batch.
+Attributes		bit 0~2 is the compression codec. bit 3 is the timestampType. bit 4 is isTransactional. bit 5 is isControlBatch.
+Records			The records count is never compressed. If compression is enabled, all the records after the count are compressed together.
+*/
+
+const (
+	// length from BaseOffset to the records count, both included
+	recordBatchHeaderLength = 61
+
+	// offset of Magic in both MessageSet and RecordBatch
+	magicOffset = 16
+
+	// bits of Attributes
+	transactionalMask = 0x10
+	controlMask       = 0x20
+)
+
+var (
+	crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+	recordBatchCrcError = errors.New("crc of record batch does not match")
+)
+
+type RecordHeader struct {
+	Key   string
+	Value []byte
+}
+
+type Record struct {
+	length         int32
+	Attributes     int8
+	TimestampDelta int64
+	OffsetDelta    int32
+	Key            []byte
+	Value          []byte
+	Headers        []RecordHeader
+}
+
+type RecordBatch struct {
+	BaseOffset           int64
+	BatchLength          int32
+	PartitionLeaderEpoch int32
+	Magic                int8
+	CRC                  uint32
+	Attributes           int16
+	LastOffsetDelta      int32
+	BaseTimestamp        int64
+	MaxTimestamp         int64
+	ProducerID           int64
+	ProducerEpoch        int16
+	BaseSequence         int32
+	Records              []*Record
+}
+
+func varintLength(v int64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutVarint(buf[:], v)
+}
+
+func bytesVarintLength(b []byte) int {
+	if b == nil {
+		return varintLength(-1)
+	}
+	return varintLength(int64(len(b))) + len(b)
+}
+
+func putVarintBytes(payload []byte, offset int, b []byte) int {
+	if b == nil {
+		return offset + binary.PutVarint(payload[offset:], -1)
+	}
+	offset += binary.PutVarint(payload[offset:], int64(len(b)))
+	offset += copy(payload[offset:], b)
+	return offset
+}
+
+// readVarintBytes returns the bytes and the new offset. nil is returned if length is -1
+func readVarintBytes(payload []byte, offset int) ([]byte, int, error) {
+	if offset >= len(payload) {
+		return nil, offset, fmt.Errorf("could not read varint at offset %d", offset)
+	}
+	l, n := binary.Varint(payload[offset:])
+	if n <= 0 {
+		return nil, offset, fmt.Errorf("could not read varint at offset %d", offset)
+	}
+	offset += n
+	if l < 0 {
+		return nil, offset, nil
+	}
+	if l > int64(len(payload)-offset) {
+		return nil, offset, fmt.Errorf("bytes length %d exceeds payload at offset %d", l, offset)
+	}
+	rst := make([]byte, l)
+	copy(rst, payload[offset:offset+int(l)])
+	return rst, offset + int(l), nil
+}
+
+func (r *Record) bodyLength() int {
+	l := 1 // Attributes
+	l += varintLength(r.TimestampDelta)
+	l += varintLength(int64(r.OffsetDelta))
+	l += bytesVarintLength(r.Key)
+	l += bytesVarintLength(r.Value)
+	l += varintLength(int64(len(r.Headers)))
+	for _, h := range r.Headers {
+		l += varintLength(int64(len(h.Key))) + len(h.Key)
+		l += bytesVarintLength(h.Value)
+	}
+	return l
+}
+
+// Length returns the encoded length of the record, including the length varint itself
+func (r *Record) Length() int {
+	l := r.bodyLength()
+	return varintLength(int64(l)) + l
+}
+
+func (r *Record) Encode(payload []byte, offset int) int {
+	r.length = int32(r.bodyLength())
+	offset += binary.PutVarint(payload[offset:], int64(r.length))
+
+	payload[offset] = byte(r.Attributes)
+	offset++
+
+	offset += binary.PutVarint(payload[offset:], r.TimestampDelta)
+	offset += binary.PutVarint(payload[offset:], int64(r.OffsetDelta))
+
+	offset = putVarintBytes(payload, offset, r.Key)
+	offset = putVarintBytes(payload, offset, r.Value)
+
+	offset += binary.PutVarint(payload[offset:], int64(len(r.Headers)))
+	for _, h := range r.Headers {
+		offset += binary.PutVarint(payload[offset:], int64(len(h.Key)))
+		offset += copy(payload[offset:], h.Key)
+		offset = putVarintBytes(payload, offset, h.Value)
+	}
+
+	return offset
+}
+
+// DecodeToRecord decodes one record from payload and returns the record and the new offset
+func DecodeToRecord(payload []byte, offset int) (*Record, int, error) {
+	var (
+		v   int64
+		n   int
+		err error
+	)
+	r := &Record{}
+
+	if offset >= len(payload) {
+		return nil, offset, fmt.Errorf("no record at offset %d of payload %d", offset, len(payload))
+	}
+	if v, n = binary.Varint(payload[offset:]); n <= 0 {
+		return nil, offset, errors.New("could not decode record length")
+	}
+	offset += n
+	if v <= 0 || v > int64(len(payload)-offset) {
+		return nil, offset, fmt.Errorf("record length %d exceeds payload", v)
+	}
+	r.length = int32(v)
+	end := offset + int(r.length)
+	// all fields are read from the record only
+	payload = payload[:end]
+
+	r.Attributes = int8(payload[offset])
+	offset++
+
+	if v, n = binary.Varint(payload[offset:]); n <= 0 {
+		return nil, offset, errors.New("could not decode record timestamp delta")
+	}
+	r.TimestampDelta = v
+	offset += n
+
+	if v, n = binary.Varint(payload[offset:]); n <= 0 {
+		return nil, offset, errors.New("could not decode record offset delta")
+	}
+	r.OffsetDelta = int32(v)
+	offset += n
+
+	if r.Key, offset, err = readVarintBytes(payload, offset); err != nil {
+		return nil, offset, err
+	}
+	if r.Value, offset, err = readVarintBytes(payload, offset); err != nil {
+		return nil, offset, err
+	}
+
+	if v, n = binary.Varint(payload[offset:]); n <= 0 {
+		return nil, offset, errors.New("could not decode record headers count")
+	}
+	offset += n
+	// each header takes 2 bytes at least
+	if v > int64(end-offset)/2 {
+		return nil, offset, fmt.Errorf("record headers count %d exceeds payload", v)
+	}
+	if v > 0 {
+		r.Headers = make([]RecordHeader, v)
+	}
+	for i := range r.Headers {
+		var key []byte
+		if key, offset, err = readVarintBytes(payload, offset); err != nil {
+			return nil, offset, err
+		}
+		r.Headers[i].Key = string(key)
+		if r.Headers[i].Value, offset, err = readVarintBytes(payload, offset); err != nil {
+			return nil, offset, err
+		}
+	}
+
+	return r, end, nil
+}
+
+func (batch *RecordBatch) compression() int8 {
+	return int8(batch.Attributes & 0x07)
+}
+
+// Encode encodes the whole batch, records are compressed by compressor if compression bits are set in Attributes
+func (batch *RecordBatch) Encode(compressor Compressor) ([]byte, error) {
+	recordsLength := 0
+	for _, r := range batch.Records {
+		recordsLength += r.Length()
+	}
+	recordsBuffer := getBuffer(recordsLength)
+	defer putBuffer(recordsBuffer)
+	records := *recordsBuffer
+	offset := 0
+	for _, r := range batch.Records {
+		offset = r.Encode(records, offset)
+	}
+
+	if batch.compression() != COMPRESSION_NONE {
+		if c, ok := builtinAppendCompressor(compressor); ok {
+			compressedBuffer := getBuffer(0)
+			defer putBuffer(compressedBuffer)
+			compressed, err := c.AppendCompress(*compressedBuffer, records)
+			if err != nil {
+				return nil, fmt.Errorf("compress records error: %s", err)
+			}
+			// keep the grown buffer in the pool
+			*compressedBuffer = compressed[:0]
+			records = compressed
+		} else {
+			compressed, err := compressor.Compress(records)
+			if err != nil {
+				return nil, fmt.Errorf("compress records error: %s", err)
+			}
+			records = compressed
+		}
+	}
+
+	payload := make([]byte, recordBatchHeaderLength+len(records))
+	offset = 0
+
+	binary.BigEndian.PutUint64(payload[offset:], uint64(batch.BaseOffset))
+	offset += 8
+
+	batch.BatchLength = int32(len(payload) - 12)
+	binary.BigEndian.PutUint32(payload[offset:], uint32(batch.BatchLength))
+	offset += 4
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(batch.PartitionLeaderEpoch))
+	offset += 4
+
+	payload[offset] = byte(batch.Magic)
+	offset++
+
+	crcPosition := offset
+	offset += 4
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(batch.Attributes))
+	offset += 2
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(batch.LastOffsetDelta))
+	offset += 4
+
+	binary.BigEndian.PutUint64(payload[offset:], uint64(batch.BaseTimestamp))
+	offset += 8
+	binary.BigEndian.PutUint64(payload[offset:], uint64(batch.MaxTimestamp))
+	offset += 8
+
+	binary.BigEndian.PutUint64(payload[offset:], uint64(batch.ProducerID))
+	offset += 8
+	binary.BigEndian.PutUint16(payload[offset:], uint16(batch.ProducerEpoch))
+	offset += 2
+	binary.BigEndian.PutUint32(payload[offset:], uint32(batch.BaseSequence))
+	offset += 4
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(len(batch.Records)))
+	offset += 4
+
+	copy(payload[offset:], records)
+
+	batch.CRC = crc32.Checksum(payload[crcPosition+4:], crc32cTable)
+	binary.BigEndian.PutUint32(payload[crcPosition:], batch.CRC)
+
+	return payload, nil
+}
+
+// DecodeToRecordBatch decodes one whole RecordBatch. payload must start with BaseOffset
+func DecodeToRecordBatch(payload []byte) (*RecordBatch, error) {
+	if len(payload) < recordBatchHeaderLength {
+		return nil, fmt.Errorf("record batch payload too short: %d", len(payload))
+	}
+	batch := &RecordBatch{}
+	offset := 0
+
+	batch.BaseOffset = int64(binary.BigEndian.Uint64(payload[offset:]))
+	offset += 8
+
+	batch.BatchLength = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	if int(batch.BatchLength)+12 < recordBatchHeaderLength || int(batch.BatchLength)+12 > len(payload) {
+		return nil, fmt.Errorf("record batch length %d exceeds payload %d", batch.BatchLength, len(payload))
+	}
+	payload = payload[:batch.BatchLength+12]
+
+	batch.PartitionLeaderEpoch = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	batch.Magic = int8(payload[offset])
+	offset++
+
+	batch.CRC = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+	if crc32.Checksum(payload[offset:], crc32cTable) != batch.CRC {
+		return nil, recordBatchCrcError
+	}
+
+	batch.Attributes = int16(binary.BigEndian.Uint16(payload[offset:]))
+	offset += 2
+
+	batch.LastOffsetDelta = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	batch.BaseTimestamp = int64(binary.BigEndian.Uint64(payload[offset:]))
+	offset += 8
+	batch.MaxTimestamp = int64(binary.BigEndian.Uint64(payload[offset:]))
+	offset += 8
+
+	batch.ProducerID = int64(binary.BigEndian.Uint64(payload[offset:]))
+	offset += 8
+	batch.ProducerEpoch = int16(binary.BigEndian.Uint16(payload[offset:]))
+	offset += 2
+	batch.BaseSequence = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	count := int(int32(binary.BigEndian.Uint32(payload[offset:])))
+	offset += 4
+
+	records := payload[offset:]
+	if compression := batch.compression(); compression != COMPRESSION_NONE {
+		var err error
+		records, err = decompress(compression, records)
+		if err != nil {
+			return nil, fmt.Errorf("decompress record batch error: %s", err)
+		}
+	}
+
+	// each record takes 1 byte at least, so a corrupted count does not allocate more than the payload
+	if count < 0 || count > len(records) {
+		return nil, fmt.Errorf("records count %d exceeds records payload %d", count, len(records))
+	}
+	batch.Records = make([]*Record, count)
+	offset = 0
+	for i := range batch.Records {
+		var (
+			r   *Record
+			err error
+		)
+		r, offset, err = DecodeToRecord(records, offset)
+		if err != nil {
+			return nil, err
+		}
+		batch.Records[i] = r
+	}
+
+	return batch, nil
+}
+
+// toMessageSet converts records in the batch to Messages with absolute offset
+func (batch *RecordBatch) toMessageSet() MessageSet {
+	messageSet := make(MessageSet, len(batch.Records))
+	logAppendTime := batch.Attributes&timestampTypeMask != 0
+	for i, r := range batch.Records {
+		messageSet[i] = &Message{
+			Offset:      batch.BaseOffset + int64(r.OffsetDelta),
+			MessageSize: r.length,
+			Crc:         batch.CRC,
+			MagicByte:   batch.Magic,
+			Attributes:  r.Attributes,
+			Key:         r.Key,
+			Value:       r.Value,
+			Headers:     r.Headers,
+
+			Timestamp:     batch.BaseTimestamp + r.TimestampDelta,
+			TimestampType: TIMESTAMP_CREATE_TIME,
+		}
+		// all records share MaxTimestamp of the batch if it is LogAppendTime
+		if logAppendTime {
+			messageSet[i].Timestamp = batch.MaxTimestamp
+			messageSet[i].TimestampType = TIMESTAMP_LOG_APPEND_TIME
+		}
+	}
+	return messageSet
+}
@@ -0,0 +1,189 @@
+package healer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"testing"
+)
+
+func TestRecordBatchEncodeDecode(t *testing.T) {
+	for _, compressionType := range []string{"none", "gzip", "snappy", "lz4", "zstd"} {
+		var compressionValue int8
+		switch compressionType {
+		case "gzip":
+			compressionValue = COMPRESSION_GZIP
+		case "snappy":
+			compressionValue = COMPRESSION_SNAPPY
+		case "lz4":
+			compressionValue = COMPRESSION_LZ4
+		case "zstd":
+			compressionValue = COMPRESSION_ZSTD
+		}
+
+		batch := &RecordBatch{
+			BaseOffset:           100,
+			PartitionLeaderEpoch: -1,
+			Magic:                2,
+			Attributes:           int16(compressionValue),
+			LastOffsetDelta:      2,
+			BaseTimestamp:        1500000000000,
+			MaxTimestamp:         1500000000000,
+			ProducerID:           -1,
+			ProducerEpoch:        -1,
+			BaseSequence:         -1,
+			Records: []*Record{
+				{OffsetDelta: 0, Key: nil, Value: []byte("hello")},
+				{OffsetDelta: 1, Key: []byte("key"), Value: []byte("world")},
+				{OffsetDelta: 2, Key: []byte(""), Value: nil, Headers: []RecordHeader{{"trace", []byte("abc")}}},
+			},
+		}
+
+		payload, err := batch.Encode(NewCompressor(compressionType))
+		if err != nil {
+			t.Fatalf("[%s] encode record batch error: %s", compressionType, err)
+		}
+
+		decoded, err := DecodeToRecordBatch(payload)
+		if err != nil {
+			t.Fatalf("[%s] decode record batch error: %s", compressionType, err)
+		}
+		if len(decoded.Records) != 3 {
+			t.Fatalf("[%s] expect 3 records, got %d", compressionType, len(decoded.Records))
+		}
+		if decoded.Records[0].Key != nil || string(decoded.Records[0].Value) != "hello" {
+			t.Errorf("[%s] record 0 does not match", compressionType)
+		}
+		if string(decoded.Records[1].Key) != "key" || string(decoded.Records[1].Value) != "world" {
+			t.Errorf("[%s] record 1 does not match", compressionType)
+		}
+		if decoded.Records[2].Key == nil || decoded.Records[2].Value != nil {
+			t.Errorf("[%s] record 2 should have empty key and null value", compressionType)
+		}
+		if len(decoded.Records[2].Headers) != 1 || decoded.Records[2].Headers[0].Key != "trace" || !bytes.Equal(decoded.Records[2].Headers[0].Value, []byte("abc")) {
+			t.Errorf("[%s] record 2 headers does not match", compressionType)
+		}
+
+		messageSet, err := DecodeToMessageSet(append(payload, payload...))
+		if err != nil {
+			t.Fatalf("[%s] decode message set error: %s", compressionType, err)
+		}
+		if len(messageSet) != 6 {
+			t.Fatalf("[%s] expect 6 messages, got %d", compressionType, len(messageSet))
+		}
+		if messageSet[2].Offset != 102 {
+			t.Errorf("[%s] offset of the 3rd message should be 102, got %d", compressionType, messageSet[2].Offset)
+		}
+		if len(messageSet[2].Headers) != 1 || messageSet[2].Headers[0].Key != "trace" {
+			t.Errorf("[%s] headers of the 3rd message does not match", compressionType)
+		}
+	}
+}
+
+func TestRecordBatchCrc(t *testing.T) {
+	batch := &RecordBatch{
+		Magic:   2,
+		Records: []*Record{{Value: []byte("hello")}},
+	}
+	payload, err := batch.Encode(NewCompressor("none"))
+	if err != nil {
+		t.Fatalf("encode record batch error: %s", err)
+	}
+
+	payload[len(payload)-1]++
+	if _, err := DecodeToRecordBatch(payload); err != recordBatchCrcError {
+		t.Errorf("expect crc error, got %v", err)
+	}
+}
+
+func TestRecordBatchTimestamp(t *testing.T) {
+	batch := &RecordBatch{
+		Magic:         2,
+		BaseTimestamp: 1500000000000,
+		MaxTimestamp:  1500000000010,
+		Records: []*Record{
+			{OffsetDelta: 0, TimestampDelta: 0, Value: []byte("hello")},
+			{OffsetDelta: 1, TimestampDelta: 10, Value: []byte("world")},
+		},
+	}
+	payload, err := batch.Encode(NewCompressor("none"))
+	if err != nil {
+		t.Fatalf("encode record batch error: %s", err)
+	}
+	messageSet, err := DecodeToMessageSet(payload)
+	if err != nil {
+		t.Fatalf("decode message set error: %s", err)
+	}
+	if messageSet[1].Timestamp != 1500000000010 || messageSet[1].TimestampType != TIMESTAMP_CREATE_TIME {
+		t.Errorf("timestamp of the 2nd message should be CreateTime 1500000000010, got %d(%d)", messageSet[1].Timestamp, messageSet[1].TimestampType)
+	}
+
+	batch.Attributes |= timestampTypeMask
+	batch.MaxTimestamp = 1600000000000
+	payload, err = batch.Encode(NewCompressor("none"))
+	if err != nil {
+		t.Fatalf("encode record batch error: %s", err)
+	}
+	messageSet, err = DecodeToMessageSet(payload)
+	if err != nil {
+		t.Fatalf("decode message set error: %s", err)
+	}
+	for _, message := range messageSet {
+		if message.Timestamp != 1600000000000 || message.TimestampType != TIMESTAMP_LOG_APPEND_TIME {
+			t.Errorf("timestamp should be LogAppendTime 1600000000000, got %d(%d)", message.Timestamp, message.TimestampType)
+		}
+	}
+}
+
+func TestRecordBatchCorrupted(t *testing.T) {
+	batch := &RecordBatch{
+		Magic:   2,
+		Records: []*Record{{Value: []byte("hello"), Headers: []RecordHeader{{"trace", []byte("abc")}}}},
+	}
+	payload, err := batch.Encode(NewCompressor("none"))
+	if err != nil {
+		t.Fatalf("encode record batch error: %s", err)
+	}
+
+	// corrupt changes the batch after the crc and computes the crc again, so that only the change is checked
+	corrupt := func(change func(payload []byte) []byte) []byte {
+		corrupted := change(append([]byte{}, payload...))
+		binary.BigEndian.PutUint32(corrupted[8:], uint32(len(corrupted)-12))
+		binary.BigEndian.PutUint32(corrupted[17:], crc32.Checksum(corrupted[21:], crc32cTable))
+		return corrupted
+	}
+	for name, corrupted := range map[string][]byte{
+		"records count": corrupt(func(p []byte) []byte {
+			binary.BigEndian.PutUint32(p[57:], math.MaxInt32)
+			return p
+		}),
+		"negative records count": corrupt(func(p []byte) []byte {
+			binary.BigEndian.PutUint32(p[57:], 0xffffffff)
+			return p
+		}),
+		"truncated record": corrupt(func(p []byte) []byte {
+			return p[:len(p)-3]
+		}),
+		"record length": corrupt(func(p []byte) []byte {
+			p[recordBatchHeaderLength] = 0
+			return p
+		}),
+		"headers count": corrupt(func(p []byte) []byte {
+			// the headers count of the record follows its value
+			p[recordBatchHeaderLength+1+1+1+1+1+1+len("hello")] = 0x7e
+			return p
+		}),
+		"batch length": corrupt(func(p []byte) []byte {
+			return p[:recordBatchHeaderLength-4]
+		}),
+	} {
+		if _, err := DecodeToRecordBatch(corrupted); err == nil {
+			t.Errorf("[%s] expect error for corrupted record batch", name)
+		}
+	}
+
+	if _, _, err := DecodeToRecord(payload, len(payload)); err == nil {
+		t.Error("expect error for record at the end of payload")
+	}
+}
@@ -6,22 +6,70 @@ import (
 
 //var Non-user facing control APIs=4-7
 var (
-	API_ProduceRequest      uint16 = 0
-	API_FetchRequest        uint16 = 1
-	API_OffsetRequest       uint16 = 2
-	API_MetadataRequest     uint16 = 3
-	API_OffsetCommitRequest uint16 = 8
-	API_OffsetFetchRequest  uint16 = 9
-	API_FindCoordinator     uint16 = 10
-	API_JoinGroup           uint16 = 11
-	API_Heartbeat           uint16 = 12
-	API_LeaveGroup          uint16 = 13
-	API_SyncGroup           uint16 = 14
-	API_DescribeGroups      uint16 = 15
-	API_ListGroups          uint16 = 16
-	API_ApiVersions         uint16 = 18
+	API_ProduceRequest          uint16 = 0
+	API_FetchRequest            uint16 = 1
+	API_OffsetRequest           uint16 = 2
+	API_MetadataRequest         uint16 = 3
+	API_OffsetCommitRequest     uint16 = 8
+	API_OffsetFetchRequest      uint16 = 9
+	API_FindCoordinator         uint16 = 10
+	API_JoinGroup               uint16 = 11
+	API_Heartbeat               uint16 = 12
+	API_LeaveGroup              uint16 = 13
+	API_SyncGroup               uint16 = 14
+	API_DescribeGroups          uint16 = 15
+	API_ListGroups              uint16 = 16
+	API_SaslHandshake           uint16 = 17
+	API_ApiVersions             uint16 = 18
+	API_CreateTopics            uint16 = 19
+	API_DeleteTopics            uint16 = 20
+	API_DeleteRecords           uint16 = 21
+	API_InitProducerID          uint16 = 22
+	API_AddPartitionsToTxn      uint16 = 24
+	API_AddOffsetsToTxn         uint16 = 25
+	API_EndTxn                  uint16 = 26
+	API_TxnOffsetCommit         uint16 = 28
+	API_DescribeConfigs         uint16 = 32
+	API_AlterConfigs            uint16 = 33
+	API_SaslAuthenticate        uint16 = 36
+	API_CreatePartitions        uint16 = 37
+	API_IncrementalAlterConfigs uint16 = 44
 )
 
+// availableVersions lists the versions of each api that healer could encode and decode.
+// apis not listed here only support version 0
+// Broker picks the highest version that is also supported by the broker, see Broker.getHighestAvailableAPIVersion
+var availableVersions = map[uint16][]uint16{
+	API_ProduceRequest:          []uint16{0, 3, 7},
+	API_FetchRequest:            []uint16{0, 4, 10},
+	API_OffsetRequest:           []uint16{0, 1},
+	API_MetadataRequest:         []uint16{0, 1},
+	API_OffsetCommitRequest:     []uint16{0, 2},
+	API_OffsetFetchRequest:      []uint16{0, 1},
+	API_FindCoordinator:         []uint16{0, 1},
+	API_JoinGroup:               []uint16{0},
+	API_Heartbeat:               []uint16{0},
+	API_LeaveGroup:              []uint16{0},
+	API_SyncGroup:               []uint16{0},
+	API_DescribeGroups:          []uint16{0},
+	API_ListGroups:              []uint16{0},
+	API_SaslHandshake:           []uint16{0, 1},
+	API_ApiVersions:             []uint16{0},
+	API_CreateTopics:            []uint16{0, 1},
+	API_DeleteTopics:            []uint16{0},
+	API_DeleteRecords:           []uint16{0},
+	API_InitProducerID:          []uint16{0},
+	API_AddPartitionsToTxn:      []uint16{0},
+	API_AddOffsetsToTxn:         []uint16{0},
+	API_EndTxn:                  []uint16{0},
+	API_TxnOffsetCommit:         []uint16{0},
+	API_DescribeConfigs:         []uint16{0, 1},
+	API_AlterConfigs:            []uint16{0},
+	API_SaslAuthenticate:        []uint16{0},
+	API_CreatePartitions:        []uint16{0},
+	API_IncrementalAlterConfigs: []uint16{0},
+}
+
 type RequestHeader struct {
 	ApiKey        uint16
 	ApiVersion    uint16
@@ -0,0 +1,223 @@
+package healer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+const (
+	SASL_MECHANISM_PLAIN         = "PLAIN"
+	SASL_MECHANISM_SCRAM_SHA_256 = "SCRAM-SHA-256"
+	SASL_MECHANISM_SCRAM_SHA_512 = "SCRAM-SHA-512"
+)
+
+// SASLConfig is used when sasl.mechanism is set. sasl is disabled if mechanism is empty
+type SASLConfig struct {
+	Mechanism string `json:"mechanism"`
+	User      string `json:"user"`
+	Password  string `json:"password"`
+}
+
+var (
+	unknownSASLMechanism = errors.New("unknown sasl mechanism. PLAIN/SCRAM-SHA-256/SCRAM-SHA-512 are supported")
+	saslUserNotSet       = errors.New("sasl user not set")
+)
+
+func (c *SASLConfig) checkValid() error {
+	if c.Mechanism == "" {
+		return nil
+	}
+	if _, err := newSaslMechanism(c); err != nil {
+		return err
+	}
+	if c.User == "" {
+		return saslUserNotSet
+	}
+	return nil
+}
+
+// saslMechanism produces the sasl tokens sending to the broker.
+// step is called with nil challenge at first, and then with each response from the broker.
+// it returns nil when the authentication completes
+type saslMechanism interface {
+	step(challenge []byte) ([]byte, error)
+}
+
+func newSaslMechanism(c *SASLConfig) (saslMechanism, error) {
+	switch c.Mechanism {
+	case SASL_MECHANISM_PLAIN:
+		return &plainMechanism{user: c.User, password: c.Password}, nil
+	case SASL_MECHANISM_SCRAM_SHA_256:
+		return newScramMechanism(sha256.New, c.User, c.Password), nil
+	case SASL_MECHANISM_SCRAM_SHA_512:
+		return newScramMechanism(sha512.New, c.User, c.Password), nil
+	}
+	return nil, unknownSASLMechanism
+}
+
+// plainMechanism implements RFC 4616
+type plainMechanism struct {
+	user     string
+	password string
+	sent     bool
+}
+
+func (m *plainMechanism) step(challenge []byte) ([]byte, error) {
+	if m.sent {
+		return nil, nil
+	}
+	m.sent = true
+	return []byte("\x00" + m.user + "\x00" + m.password), nil
+}
+
+// scramMechanism implements RFC 5802 with SHA-256/SHA-512 as Kafka does
+type scramMechanism struct {
+	hashFunc func() hash.Hash
+	user     string
+	password string
+	nonce    string
+
+	state              int
+	clientFirstBare    string
+	expectedServerSign []byte
+}
+
+func newScramMechanism(hashFunc func() hash.Hash, user, password string) *scramMechanism {
+	nonce := make([]byte, 24)
+	rand.Read(nonce)
+	return &scramMechanism{
+		hashFunc: hashFunc,
+		user:     user,
+		password: password,
+		nonce:    base64.RawStdEncoding.EncodeToString(nonce),
+	}
+}
+
+var scramUserReplacer = strings.NewReplacer("=", "=3D", ",", "=2C")
+
+func (m *scramMechanism) step(challenge []byte) ([]byte, error) {
+	m.state++
+	switch m.state {
+	case 1:
+		m.clientFirstBare = "n=" + scramUserReplacer.Replace(m.user) + ",r=" + m.nonce
+		return []byte("n,," + m.clientFirstBare), nil
+	case 2:
+		return m.clientFinal(string(challenge))
+	case 3:
+		return nil, m.verifyServerFinal(string(challenge))
+	}
+	return nil, errors.New("scram authentication has already completed")
+}
+
+func (m *scramMechanism) clientFinal(serverFirst string) ([]byte, error) {
+	var (
+		nonce      string
+		salt       []byte
+		iterations int
+		err        error
+	)
+	for _, attr := range strings.Split(serverFirst, ",") {
+		if len(attr) < 2 || attr[1] != '=' {
+			return nil, fmt.Errorf("invalid scram server-first-message: %s", serverFirst)
+		}
+		switch attr[0] {
+		case 'r':
+			nonce = attr[2:]
+		case 's':
+			if salt, err = base64.StdEncoding.DecodeString(attr[2:]); err != nil {
+				return nil, fmt.Errorf("invalid salt in scram server-first-message: %s", err)
+			}
+		case 'i':
+			if iterations, err = strconv.Atoi(attr[2:]); err != nil {
+				return nil, fmt.Errorf("invalid iteration count in scram server-first-message: %s", err)
+			}
+		case 'e':
+			return nil, fmt.Errorf("scram authentication failed: %s", attr[2:])
+		}
+	}
+	if !strings.HasPrefix(nonce, m.nonce) || len(nonce) == len(m.nonce) {
+		return nil, errors.New("invalid nonce in scram server-first-message")
+	}
+	if salt == nil || iterations <= 0 {
+		return nil, fmt.Errorf("invalid scram server-first-message: %s", serverFirst)
+	}
+
+	saltedPassword := pbkdf2(m.hashFunc, []byte(m.password), salt, iterations)
+	clientKey := m.hmac(saltedPassword, []byte("Client Key"))
+	h := m.hashFunc()
+	h.Write(clientKey)
+	storedKey := h.Sum(nil)
+
+	clientFinalWithoutProof := "c=" + base64.StdEncoding.EncodeToString([]byte("n,,")) + ",r=" + nonce
+	authMessage := []byte(m.clientFirstBare + "," + serverFirst + "," + clientFinalWithoutProof)
+
+	clientSignature := m.hmac(storedKey, authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range clientKey {
+		proof[i] = clientKey[i] ^ clientSignature[i]
+	}
+
+	serverKey := m.hmac(saltedPassword, []byte("Server Key"))
+	m.expectedServerSign = m.hmac(serverKey, authMessage)
+
+	return []byte(clientFinalWithoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof)), nil
+}
+
+func (m *scramMechanism) verifyServerFinal(serverFinal string) error {
+	if strings.HasPrefix(serverFinal, "e=") {
+		return fmt.Errorf("scram authentication failed: %s", serverFinal[2:])
+	}
+	if !strings.HasPrefix(serverFinal, "v=") {
+		return fmt.Errorf("invalid scram server-final-message: %s", serverFinal)
+	}
+	serverSign, err := base64.StdEncoding.DecodeString(strings.SplitN(serverFinal[2:], ",", 2)[0])
+	if err != nil {
+		return fmt.Errorf("invalid scram server signature: %s", err)
+	}
+	if !hmac.Equal(serverSign, m.expectedServerSign) {
+		return errors.New("scram server signature does not match")
+	}
+	return nil
+}
+
+func (m *scramMechanism) hmac(key, data []byte) []byte {
+	mac := hmac.New(m.hashFunc, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// pbkdf2 derives a key with the same length as the hash output, which is all that scram needs
+func pbkdf2(hashFunc func() hash.Hash, password, salt []byte, iterations int) []byte {
+	mac := hmac.New(hashFunc, password)
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	rst := make([]byte, len(u))
+	copy(rst, u)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range rst {
+			rst[j] ^= u[j]
+		}
+	}
+	return rst
+}
+
+// encodeSaslToken wraps the token in a size-delimited packet, which is used after SaslHandshake v0
+func encodeSaslToken(token []byte) []byte {
+	payload := make([]byte, 4+len(token))
+	binary.BigEndian.PutUint32(payload, uint32(len(token)))
+	copy(payload[4:], token)
+	return payload
+}
@@ -0,0 +1,57 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+SaslAuthenticate Request (Version: 0) => auth_bytes
+  auth_bytes => BYTES
+
+FIELD	DESCRIPTION
+  auth_bytes	SASL authentication bytes from client as defined by the SASL mechanism.
+*/
+
+type SaslAuthenticateRequest struct {
+	RequestHeader *RequestHeader
+	AuthBytes     []byte
+}
+
+func NewSaslAuthenticateRequest(apiVersion uint16, clientID string, authBytes []byte) *SaslAuthenticateRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_SaslAuthenticate,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &SaslAuthenticateRequest{requestHeader, authBytes}
+}
+
+func (r *SaslAuthenticateRequest) Length() int {
+	return r.RequestHeader.length() + 4 + len(r.AuthBytes)
+}
+
+func (r *SaslAuthenticateRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(len(r.AuthBytes)))
+	offset += 4
+	copy(payload[offset:], r.AuthBytes)
+
+	return payload
+}
+
+func (r *SaslAuthenticateRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *SaslAuthenticateRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
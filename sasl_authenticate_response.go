@@ -0,0 +1,64 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+SaslAuthenticate Response (Version: 0) => error_code error_message auth_bytes
+  error_code => INT16
+  error_message => NULLABLE_STRING
+  auth_bytes => BYTES
+
+FIELD	DESCRIPTION
+  error_code	Response error code
+  error_message	Response error message
+  auth_bytes	SASL authentication bytes from server as defined by the SASL mechanism.
+*/
+
+type SaslAuthenticateResponse struct {
+	CorrelationID uint32
+	ErrorCode     uint16
+	ErrorMessage  string
+	AuthBytes     []byte
+}
+
+func NewSaslAuthenticateResponse(payload []byte) (*SaslAuthenticateResponse, error) {
+	r := &SaslAuthenticateResponse{}
+	offset := 0
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("SaslAuthenticate reseponse length did not match: %d!=%d", responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	r.ErrorCode = binary.BigEndian.Uint16(payload[offset:])
+	offset += 2
+
+	l := int(int16(binary.BigEndian.Uint16(payload[offset:])))
+	offset += 2
+	if l > 0 {
+		r.ErrorMessage = string(payload[offset : offset+l])
+		offset += l
+	}
+
+	authBytesLength := int(int32(binary.BigEndian.Uint32(payload[offset:])))
+	offset += 4
+	if authBytesLength > 0 {
+		r.AuthBytes = make([]byte, authBytesLength)
+		copy(r.AuthBytes, payload[offset:offset+authBytesLength])
+		offset += authBytesLength
+	}
+
+	if r.ErrorCode != 0 {
+		if r.ErrorMessage != "" {
+			return r, fmt.Errorf("%s: %s", getErrorFromErrorCode(int16(r.ErrorCode)), r.ErrorMessage)
+		}
+		return r, getErrorFromErrorCode(int16(r.ErrorCode))
+	}
+	return r, nil
+}
@@ -0,0 +1,63 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+SaslHandshake Request (Version: 0) => mechanism
+  mechanism => STRING
+
+SaslHandshake Request (Version: 1) => mechanism
+  mechanism => STRING
+
+FIELD	DESCRIPTION
+  mechanism	SASL Mechanism chosen by the client.
+
+in version 0, the sasl tokens are sent as opaque packets right after the handshake.
+in version 1, they are wrapped in SaslAuthenticate requests.
+*/
+
+type SaslHandshakeRequest struct {
+	RequestHeader *RequestHeader
+	Mechanism     string
+}
+
+func NewSaslHandshakeRequest(apiVersion uint16, clientID string, mechanism string) *SaslHandshakeRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_SaslHandshake,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &SaslHandshakeRequest{requestHeader, mechanism}
+}
+
+func (r *SaslHandshakeRequest) Length() int {
+	return r.RequestHeader.length() + 2 + len(r.Mechanism)
+}
+
+func (r *SaslHandshakeRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(len(r.Mechanism)))
+	offset += 2
+	copy(payload[offset:], r.Mechanism)
+
+	return payload
+}
+
+func (r *SaslHandshakeRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *SaslHandshakeRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
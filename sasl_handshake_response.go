@@ -0,0 +1,57 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+SaslHandshake Response (Version: 0) => error_code [enabled_mechanisms]
+  error_code => INT16
+  enabled_mechanisms => STRING
+
+SaslHandshake Response (Version: 1) => error_code [enabled_mechanisms]
+  error_code => INT16
+  enabled_mechanisms => STRING
+
+FIELD	DESCRIPTION
+  error_code	Response error code
+  enabled_mechanisms	Array of mechanisms enabled in the server.
+*/
+
+type SaslHandshakeResponse struct {
+	CorrelationID     uint32
+	ErrorCode         uint16
+	EnabledMechanisms []string
+}
+
+func NewSaslHandshakeResponse(payload []byte) (*SaslHandshakeResponse, error) {
+	r := &SaslHandshakeResponse{}
+	offset := 0
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("SaslHandshake reseponse length did not match: %d!=%d", responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	r.ErrorCode = binary.BigEndian.Uint16(payload[offset:])
+	offset += 2
+
+	count := int(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	r.EnabledMechanisms = make([]string, count)
+	for i := 0; i < count; i++ {
+		l := int(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		r.EnabledMechanisms[i] = string(payload[offset : offset+l])
+		offset += l
+	}
+
+	if r.ErrorCode != 0 {
+		return r, getErrorFromErrorCode(int16(r.ErrorCode))
+	}
+	return r, nil
+}
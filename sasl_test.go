@@ -0,0 +1,116 @@
+package healer
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+)
+
+// test vector from RFC 7677
+func TestScramSHA256(t *testing.T) {
+	m := newScramMechanism(sha256.New, "user", "pencil")
+	m.nonce = "rOprNGfwEbeRWgbNEkqO"
+
+	clientFirst, err := m.step(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(clientFirst) != "n,,n=user,r=rOprNGfwEbeRWgbNEkqO" {
+		t.Errorf("client-first-message does not match: %s", clientFirst)
+	}
+
+	clientFinal, err := m.step([]byte("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(clientFinal) != "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=" {
+		t.Errorf("client-final-message does not match: %s", clientFinal)
+	}
+
+	token, err := m.step([]byte("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="))
+	if err != nil {
+		t.Errorf("verify server-final-message error: %s", err)
+	}
+	if token != nil {
+		t.Errorf("authentication should complete after server-final-message")
+	}
+}
+
+func TestScramWrongServerSignature(t *testing.T) {
+	m := newScramMechanism(sha256.New, "user", "pencil")
+	m.nonce = "rOprNGfwEbeRWgbNEkqO"
+	m.step(nil)
+	m.step([]byte("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096"))
+	if _, err := m.step([]byte("v=AAAATRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=")); err == nil {
+		t.Error("wrong server signature should be rejected")
+	}
+}
+
+// handleSaslPlain acts as a broker supporting SaslHandshake v1 and SaslAuthenticate v0, and accepts only user:password
+func handleSaslPlain(request *fakeRequest) []byte {
+	apiKey := request.apiKey
+	body := request.body
+
+	var response []byte
+	switch apiKey {
+	case API_ApiVersions:
+		response = fakeApiVersionsResponse(map[uint16]uint16{API_SaslHandshake: 1, API_SaslAuthenticate: 0})
+	case API_SaslHandshake:
+		mechanism := string(body[2:])
+		response = make([]byte, 6+2+len(SASL_MECHANISM_PLAIN))
+		if mechanism != SASL_MECHANISM_PLAIN {
+			binary.BigEndian.PutUint16(response, 33)
+		}
+		binary.BigEndian.PutUint32(response[2:], 1)
+		binary.BigEndian.PutUint16(response[6:], uint16(len(SASL_MECHANISM_PLAIN)))
+		copy(response[8:], SASL_MECHANISM_PLAIN)
+	case API_SaslAuthenticate:
+		token := string(body[4:])
+		response = make([]byte, 8)
+		binary.BigEndian.PutUint16(response[2:], 0xffff)
+		if token != "\x00user\x00password" {
+			binary.BigEndian.PutUint16(response, 58)
+		}
+	default:
+		return nil
+	}
+	return response
+}
+
+func TestSaslPlain(t *testing.T) {
+	server := newFakeBroker(t, handleSaslPlain)
+	defer server.close()
+
+	config := DefaultBrokerConfig()
+	config.ConnectTimeoutMS = 5000
+	config.TimeoutMS = 5000
+	config.SASL = SASLConfig{Mechanism: SASL_MECHANISM_PLAIN, User: "user", Password: "password"}
+
+	broker, err := NewBroker(server.address(), -1, config)
+	if err != nil {
+		t.Fatalf("sasl plain authentication error: %s", err)
+	}
+
+	// reconnect should authenticate again
+	broker.Close()
+	if err := broker.ensureOpen(); err != nil || broker.IsDead() {
+		t.Errorf("broker should be reopened: %v", err)
+	}
+	broker.Close()
+
+	// failed authentication on reconnect is returned to the caller
+	config.SASL.Password = "wrong"
+	if _, err := broker.Request(NewApiVersionsRequest(0, "healer")); err == nil {
+		t.Error("request should fail when authentication on reconnect fails")
+	}
+
+	config.SASL.Password = "wrong"
+	if _, err := NewBroker(server.address(), -1, config); err == nil {
+		t.Error("authentication with wrong password should fail")
+	}
+
+	config.SASL.Mechanism = SASL_MECHANISM_SCRAM_SHA_512
+	if _, err := NewBroker(server.address(), -1, config); err == nil {
+		t.Error("unsupported mechanism should fail")
+	}
+}
@@ -1,12 +1,15 @@
 package healer
 
 import (
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/golang/glog"
 )
 
+var readCommittedNotSupported = errors.New("read_committed needs fetch api v4, which is not supported by the leader")
+
 // SimpleConsumer instance is built to consume messages from kafka broker
 type SimpleConsumer struct {
 	topic       string
@@ -39,6 +42,9 @@ func NewSimpleConsumerWithBrokers(topic string, partitionID int32, config *Consu
 
 func NewSimpleConsumer(topic string, partitionID int32, config *ConsumerConfig) (*SimpleConsumer, error) {
 	var err error
+	if err = config.checkValid(); err != nil {
+		return nil, err
+	}
 
 	c := &SimpleConsumer{
 		config:      config,
@@ -141,6 +147,9 @@ func (c *SimpleConsumer) Consume(offset int64, messageChan chan *FullMessage) (c
 	if err != nil {
 		glog.Fatalf("could get leader broker:%s", err)
 	}
+	if c.config.IsolationLevel == "read_committed" && c.leaderBroker.getHighestAvailableAPIVersion(API_FetchRequest) < 4 {
+		return nil, readCommittedNotSupported
+	}
 
 	if c.belongTO != nil && (c.offset == -1 || c.offset == -2) {
 		var apiVersion uint16
@@ -235,7 +244,17 @@ func (c *SimpleConsumer) Consume(offset int64, messageChan chan *FullMessage) (c
 
 		for c.stop == false {
 			// TODO set CorrelationID to 0 firstly and then set by broker
-			fetchRequest := NewFetchRequest(c.config.ClientID, c.config.FetchMaxWaitMS, c.config.FetchMinBytes)
+			version := c.leaderBroker.getHighestAvailableAPIVersion(API_FetchRequest)
+			fetchRequest := NewFetchRequest(version, c.config.ClientID, c.config.FetchMaxWaitMS, c.config.FetchMinBytes)
+			fetchRequest.MaxBytes = c.config.FetchMaxBytes
+			if c.config.IsolationLevel == "read_committed" {
+				// the new leader after leader change might be an old broker
+				if version < 4 {
+					glog.Errorf("consume %s[%d] error: %s", c.topic, c.partitionID, readCommittedNotSupported)
+					return
+				}
+				fetchRequest.IsolationLevel = 1
+			}
 			fetchRequest.addPartition(c.topic, c.partitionID, c.offset, c.config.FetchMaxBytes)
 
 			buffers := make(chan []byte, 10)
@@ -248,6 +267,7 @@ func (c *SimpleConsumer) Consume(offset int64, messageChan chan *FullMessage) (c
 			}()
 
 			fetchResponseStreamDecoder := FetchResponseStreamDecoder{
+				version:     version,
 				totalLength: 0,
 				length:      0,
 				buffers:     buffers,
@@ -272,7 +292,15 @@ func (c *SimpleConsumer) Consume(offset int64, messageChan chan *FullMessage) (c
 								glog.Fatalf("could get leader broker:%s", err)
 							}
 						}
+					} else if message.skipped {
+						if message.Message.Offset >= c.offset {
+							c.offset = message.Message.Offset + 1
+						}
 					} else {
+						// a whole compressed MessageSet or RecordBatch is returned, skip messages before the fetch offset
+						if message.Message.Offset < c.offset {
+							continue
+						}
 						c.offset = message.Message.Offset + 1
 						messages <- message
 					}
@@ -1,6 +1,7 @@
 package healer
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,7 +11,12 @@ import (
 	"github.com/golang/glog"
 )
 
-var SimpleProducerClosedError = errors.New("simple producer has been closed and failed to open")
+var (
+	SimpleProducerClosedError = errors.New("simple producer has been closed and failed to open")
+	headersNotSupportedError  = errors.New("record headers need produce api v3, which is not supported by the leader")
+	idempotenceNotSupported   = errors.New("idempotence needs produce api v3, which is not supported by the leader")
+	zstdNotSupported          = errors.New("zstd compression needs produce api v7, which is not supported by the leader")
+)
 
 type SimpleProducer struct {
 	config *ProducerConfig
@@ -21,16 +27,53 @@ type SimpleProducer struct {
 	closed    bool
 
 	messageSet MessageSet
+	// batchBytes is the estimated encoded size of the messages in messageSet
+	batchBytes int
+	// batchGeneration increases each time messages are taken out, so the linger timer of a flushed batch does nothing
+	batchGeneration int
+	lingerTimer     *time.Timer
 
 	mutex sync.Locker
 	timer *time.Timer
 
+	// leaderProduceVersion is the produce version of the leader. it is guarded by mutex, since the leader is replaced
+	// in the flushing goroutine while AddMessage checks the version of headers
+	leaderProduceVersion uint16
+
 	compressionValue int8
 	compressor       Compressor
+
+	// pid is not nil if enable.idempotence is true
+	pid *producerIDManager
+
+	// flushMutex is held from taking messages out of messageSet to the end of flush,
+	// so batches are sent in order and Flush returns after all messages added before are sent
+	flushMutex sync.Mutex
+
+	// txn is not nil if the SimpleProducer belongs to a transactional Producer
+	txn *transactionManager
+
+	// buffer is shared by all SimpleProducers of a Producer
+	buffer *bufferMemory
+
+	// coalescer is not nil if the SimpleProducer belongs to a Producer. the leader is then a connection shared with
+	// the other SimpleProducers, and the batches are sent by the coalescer together with those to the same broker
+	coalescer *produceCoalescer
+
+	// onNewBatch is called when the messages are taken out to be sent as a batch. Producer notifies its Partitioner by it
+	onNewBatch func()
 }
 
 func (p *SimpleProducer) createLeader() (*Broker, error) {
-	brokers, err := NewBrokers(p.config.BootstrapServers, p.config.ClientID, DefaultBrokerConfig())
+	if p.coalescer != nil {
+		leader, err := p.coalescer.leader(p.topic, p.partition)
+		if err != nil {
+			glog.Errorf("could not get leader of topic %s[%d]: %s", p.topic, p.partition, err)
+		}
+		return leader, err
+	}
+
+	brokers, err := NewBrokers(p.config.BootstrapServers, p.config.ClientID, getBrokerConfigFromProducerConfig(p.config))
 	if err != nil {
 		glog.Errorf("init brokers error: %s", err)
 		return nil, err
@@ -57,7 +100,46 @@ func (p *SimpleProducer) createLeader() (*Broker, error) {
 	return leader, err
 }
 
+// refreshLeader finds out the leader of the partition from metadata again and connects to it
+func (p *SimpleProducer) refreshLeader() error {
+	leader, err := p.createLeader()
+	if err != nil {
+		return err
+	}
+	glog.Infof("leader of %s[%d] is %s now", p.topic, p.partition, leader.GetAddress())
+	if p.coalescer == nil {
+		p.leader.Close()
+	}
+	p.setLeader(leader)
+	return nil
+}
+
+// setLeader sets the leader and records its produce version
+func (p *SimpleProducer) setLeader(leader *Broker) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.leader = leader
+	p.leaderProduceVersion = leader.getHighestAvailableAPIVersion(API_ProduceRequest)
+}
+
+// isStaleLeaderError tells if the leader in hand may be not the leader of the partition any more.
+// connection errors, UNKNOWN_TOPIC_OR_PARTITION, LEADER_NOT_AVAILABLE and NOT_LEADER_FOR_PARTITION are
+func isStaleLeaderError(err error) bool {
+	if _, ok := err.(*Error); !ok {
+		return true
+	}
+	return err == AllError[3] || err == AllError[5] || err == AllError[6]
+}
+
 func NewSimpleProducer(topic string, partition int32, config *ProducerConfig) *SimpleProducer {
+	return newSimpleProducer(topic, partition, config, nil, nil, nil, nil)
+}
+
+// newSimpleProducer creates a SimpleProducer sharing pid, txn, buffer and coalescer with others.
+// it inits a new producer id if pid is nil and enable.idempotence is true, a new buffer if buffer is nil,
+// and its own connection to the leader if coalescer is nil
+func newSimpleProducer(topic string, partition int32, config *ProducerConfig, pid *producerIDManager, txn *transactionManager, buffer *bufferMemory, coalescer *produceCoalescer) *SimpleProducer {
 	err := config.checkValid()
 	if err != nil {
 		glog.Errorf("config error: %s", err)
@@ -70,34 +152,39 @@ func NewSimpleProducer(topic string, partition int32, config *ProducerConfig) *S
 		partition: partition,
 		closed:    false,
 
-		mutex: &sync.Mutex{},
+		mutex:     &sync.Mutex{},
+		pid:       pid,
+		txn:       txn,
+		buffer:    buffer,
+		coalescer: coalescer,
+	}
+	if p.buffer == nil {
+		p.buffer = newBufferMemory(config)
 	}
 
-	switch config.CompressionType {
-	case "none":
-		p.compressionValue = COMPRESSION_NONE
-	case "gzip":
-		p.compressionValue = COMPRESSION_GZIP
-	case "snappy":
-		p.compressionValue = COMPRESSION_SNAPPY
-	case "lz4":
-		p.compressionValue = COMPRESSION_LZ4
+	if config.EnableIdempotence && p.pid == nil {
+		p.pid, err = newProducerIDManager(config)
+		if err != nil {
+			glog.Errorf("init producer id error: %s", err)
+			return nil
+		}
 	}
-	p.compressor = NewCompressor(config.CompressionType)
 
-	if p.compressor == nil {
-		glog.Error("could not build compressor for simple_producer")
+	p.compressionValue, p.compressor, err = getLeveledCodec(config.CompressionType, config.CompressionLevel)
+	if err != nil {
+		glog.Errorf("could not build compressor for simple_producer: %s", err)
 		return nil
 	}
 
 	p.messageSet = make([]*Message, config.MessageMaxCount)
 	p.messageSet = p.messageSet[:0]
 
-	p.leader, err = p.createLeader()
+	leader, err := p.createLeader()
 	if err != nil {
 		glog.Errorf("create producer leader error: %s", err)
 		return nil
 	}
+	p.setLeader(leader)
 
 	p.timer = time.NewTimer(time.Duration(config.ConnectionsMaxIdleMS) * time.Millisecond)
 	go func() {
@@ -105,24 +192,6 @@ func NewSimpleProducer(topic string, partition int32, config *ProducerConfig) *S
 		p.Close()
 	}()
 
-	// TODO wait to the next ticker to see if messageSet changes
-	go func() {
-		for range time.NewTicker(time.Duration(config.FlushIntervalMS) * time.Millisecond).C {
-			p.mutex.Lock()
-			if len(p.messageSet) == 0 {
-				p.mutex.Unlock()
-				continue
-			}
-
-			messageSet := p.messageSet
-			p.messageSet = make([]*Message, config.MessageMaxCount)
-			p.messageSet = p.messageSet[:0]
-			p.mutex.Unlock()
-
-			p.flush(messageSet)
-		}
-	}()
-
 	return p
 }
 
@@ -132,11 +201,12 @@ func (p *SimpleProducer) ensureOpen() bool {
 		return true
 	}
 
-	p.leader, err = p.createLeader()
+	leader, err := p.createLeader()
 	if err != nil {
 		glog.Error("create producer leader error: %s", err)
 		return false
 	}
+	p.setLeader(leader)
 
 	p.closed = false
 
@@ -150,9 +220,33 @@ func (p *SimpleProducer) ensureOpen() bool {
 }
 
 func (p *SimpleProducer) AddMessage(key []byte, value []byte) error {
+	return p.AddMessageWithHeaders(key, value, nil)
+}
+
+// AddMessageWithHeaders adds a message with record headers. headers need the leader to support produce v3 (kafka 0.11.0+)
+func (p *SimpleProducer) AddMessageWithHeaders(key []byte, value []byte, headers []RecordHeader) error {
+	return p.AddMessageWithTimestamp(key, value, headers, time.Now().UnixNano()/1000000)
+}
+
+// AddMessageWithTimestamp adds a message with CreateTime timestamp in milliseconds set by the caller.
+// timestamp is dropped if the leader does not support produce v3
+func (p *SimpleProducer) AddMessageWithTimestamp(key []byte, value []byte, headers []RecordHeader, timestamp int64) error {
+	return p.AddMessageWithCallback(key, value, headers, timestamp, nil)
+}
+
+// AddMessageWithCallback adds a message whose delivery report is sent to callback once it is sent or fails
+func (p *SimpleProducer) AddMessageWithCallback(key []byte, value []byte, headers []RecordHeader, timestamp int64, callback DeliveryCallback) error {
 	if p.ensureOpen() == false {
 		return SimpleProducerClosedError
 	}
+	if len(headers) > 0 {
+		p.mutex.Lock()
+		version := p.leaderProduceVersion
+		p.mutex.Unlock()
+		if version < 3 {
+			return headersNotSupportedError
+		}
+	}
 	message := &Message{
 		Offset:      0,
 		MessageSize: 0, // compute in message encode
@@ -162,17 +256,97 @@ func (p *SimpleProducer) AddMessage(key []byte, value []byte) error {
 		MagicByte:  0,
 		Key:        key,
 		Value:      value,
+		Headers:    headers,
+
+		Timestamp:     timestamp,
+		TimestampType: TIMESTAMP_CREATE_TIME,
+
+		callback: callback,
 	}
+	size := estimateMessageSize(key, value, headers)
+	if recordBatchHeaderLength+size > p.config.MaxRequestSize {
+		return AllError[10]
+	}
+	if err := p.buffer.allocate(size); err != nil {
+		return err
+	}
+	message.bufferSize = size
+
 	p.mutex.Lock()
+	// the message does not fit in the current batch, which is sent first
+	if len(p.messageSet) > 0 && recordBatchHeaderLength+p.batchBytes+size > p.config.BatchSize {
+		p.mutex.Unlock()
+		p.Flush()
+		p.mutex.Lock()
+	}
 	p.messageSet = append(p.messageSet, message)
+	p.batchBytes += size
+	if len(p.messageSet) == 1 {
+		generation := p.batchGeneration
+		p.lingerTimer = time.AfterFunc(p.config.linger(), func() {
+			p.flushBatch(generation)
+		})
+	}
+	full := len(p.messageSet) >= p.config.MessageMaxCount || recordBatchHeaderLength+p.batchBytes >= p.config.BatchSize
 	p.mutex.Unlock()
-	if len(p.messageSet) >= p.config.MessageMaxCount {
+
+	if full {
 		p.Flush()
 	}
 	return nil
 }
 
+// estimateMessageSize returns the upper bound of the encoded size of the message, either in RecordBatch or in MessageSet
+func estimateMessageSize(key []byte, value []byte, headers []RecordHeader) int {
+	// length attributes timestampDelta offsetDelta keyLength key valueLength value headersCount [headerKeyLength headerKey headerValueLength headerValue]
+	size := binary.MaxVarintLen32 + 1 + binary.MaxVarintLen64 + binary.MaxVarintLen32*4 + len(key) + len(value)
+	for _, header := range headers {
+		size += binary.MaxVarintLen32*2 + len(header.Key) + len(header.Value)
+	}
+
+	// offset messageSize crc magic attributes timestamp keyLength key valueLength value
+	if legacySize := 8 + 4 + 4 + 1 + 1 + 8 + 4 + len(key) + 4 + len(value); legacySize > size {
+		return legacySize
+	}
+	return size
+}
+
+// takeMessages takes out all messages as a batch. it must be called with p.mutex held
+func (p *SimpleProducer) takeMessages() MessageSet {
+	messageSet := p.messageSet
+	p.messageSet = make([]*Message, 0, p.config.MessageMaxCount)
+	p.batchBytes = 0
+	p.batchGeneration++
+	if p.lingerTimer != nil {
+		p.lingerTimer.Stop()
+		p.lingerTimer = nil
+	}
+	return messageSet
+}
+
+// flushBatch is called by the linger timer started by the first message of the batch.
+// it does nothing if the batch has been flushed because of batch.size or message.max.count
+func (p *SimpleProducer) flushBatch(generation int) {
+	p.flushMutex.Lock()
+	defer p.flushMutex.Unlock()
+
+	p.mutex.Lock()
+	if generation != p.batchGeneration || len(p.messageSet) == 0 {
+		p.mutex.Unlock()
+		return
+	}
+	messageSet := p.takeMessages()
+	p.mutex.Unlock()
+
+	if err := p.flush(messageSet); err != nil {
+		glog.Errorf("flush %d messages to %s[%d] error: %s", len(messageSet), p.topic, p.partition, err)
+	}
+}
+
 func (p *SimpleProducer) Flush() error {
+	p.flushMutex.Lock()
+	defer p.flushMutex.Unlock()
+
 	p.mutex.Lock()
 
 	if len(p.messageSet) == 0 {
@@ -180,9 +354,7 @@ func (p *SimpleProducer) Flush() error {
 		return nil
 	}
 
-	messageSet := p.messageSet
-	p.messageSet = make([]*Message, p.config.MessageMaxCount)
-	p.messageSet = p.messageSet[:0]
+	messageSet := p.takeMessages()
 
 	// TODO should below code put between lock & unlock
 	if !p.timer.Stop() {
@@ -195,16 +367,55 @@ func (p *SimpleProducer) Flush() error {
 	return p.flush(messageSet)
 }
 
+// flush must be called with p.flushMutex held. the result is reported to each message
 func (p *SimpleProducer) flush(messageSet MessageSet) error {
 	glog.V(5).Infof("produce %d messsages", len(messageSet))
+	if p.onNewBatch != nil {
+		p.onNewBatch()
+	}
+
+	partitionResponse, err := p.produce(messageSet)
+	p.reportDelivery(messageSet, partitionResponse, err)
+
+	size := 0
+	for _, message := range messageSet {
+		size += message.bufferSize
+	}
+	p.buffer.release(size)
+	return err
+}
+
+// BufferMetrics returns the usage of buffer.memory, which is shared by all partitions if the SimpleProducer belongs to a Producer
+func (p *SimpleProducer) BufferMetrics() BufferMetrics {
+	return p.buffer.metrics()
+}
+
+// produce sends the messages as one batch and returns the response of the partition
+func (p *SimpleProducer) produce(messageSet MessageSet) (*ProduceResponse_PartitionResponse, error) {
 
+	version := p.leader.getHighestAvailableAPIVersion(API_ProduceRequest)
+	if p.txn != nil && version < 3 {
+		return nil, transactionNotSupportedError
+	}
+	if p.pid != nil && version < 3 {
+		return nil, idempotenceNotSupported
+	}
+	if p.compressionValue == COMPRESSION_ZSTD && version < 7 {
+		return nil, zstdNotSupported
+	}
 	produceRequest := &ProduceRequest{
 		RequiredAcks: p.config.Acks,
 		Timeout:      p.config.RequestTimeoutMS,
 	}
+	if p.txn != nil {
+		if err := p.txn.addPartition(p.topic, p.partition); err != nil {
+			return nil, err
+		}
+		produceRequest.TransactionalID = p.config.TransactionalID
+	}
 	produceRequest.RequestHeader = &RequestHeader{
 		ApiKey:     API_ProduceRequest,
-		ApiVersion: 0,
+		ApiVersion: version,
 		ClientId:   p.config.ClientID,
 	}
 
@@ -214,6 +425,7 @@ func (p *SimpleProducer) flush(messageSet MessageSet) error {
 			Partition      int32
 			MessageSetSize int32
 			MessageSet     MessageSet
+			RecordBatch    []byte
 		}
 	}, 1)
 	produceRequest.TopicBlocks[0].TopicName = p.topic
@@ -221,41 +433,160 @@ func (p *SimpleProducer) flush(messageSet MessageSet) error {
 		Partition      int32
 		MessageSetSize int32
 		MessageSet     MessageSet
+		RecordBatch    []byte
 	}, 1)
+	produceRequest.TopicBlocks[0].PartitonBlocks[0].Partition = p.partition
 
-	if p.compressionValue != 0 {
-		value := make([]byte, messageSet.Length())
-		messageSet.Encode(value, 0)
-		compressed_value, err := p.compressor.Compress(value)
-		if err != nil {
-			return fmt.Errorf("compress messageset error:%s", err)
+	var batch *RecordBatch
+	if version >= 3 {
+		batch = p.buildRecordBatch(messageSet)
+	} else {
+		if p.compressionValue != 0 {
+			value := make([]byte, messageSet.Length())
+			messageSet.Encode(value, 0)
+			compressed_value, err := p.compressor.Compress(value)
+			if err != nil {
+				return nil, fmt.Errorf("compress messageset error:%s", err)
+			}
+			var message *Message = &Message{
+				Offset:      0,
+				MessageSize: 0, // compute in message encode
+
+				Crc:        0, // compute in message encode
+				Attributes: 0x00 | p.compressionValue,
+				MagicByte:  0,
+				Key:        nil,
+				Value:      compressed_value,
+			}
+			messageSet = []*Message{message}
+		}
+		produceRequest.TopicBlocks[0].PartitonBlocks[0].MessageSetSize = int32(len(messageSet))
+		produceRequest.TopicBlocks[0].PartitonBlocks[0].MessageSet = messageSet
+	}
+
+	var err error
+	for i := 0; i <= p.config.Retries; i++ {
+		// the batch is stamped again only if the producer id has been reset, the retries of it must keep the same sequence
+		if batch != nil && (produceRequest.TopicBlocks[0].PartitonBlocks[0].RecordBatch == nil || p.pid != nil && batch.ProducerID == -1) {
+			if p.pid != nil {
+				if batch.ProducerID, batch.ProducerEpoch, batch.BaseSequence, err = p.pid.sequence(p.topic, p.partition); err != nil {
+					return nil, fmt.Errorf("could not get producer id: %s", err)
+				}
+			}
+			recordBatch, err := batch.Encode(p.compressor)
+			if err != nil {
+				return nil, fmt.Errorf("encode record batch error:%s", err)
+			}
+			produceRequest.TopicBlocks[0].PartitonBlocks[0].MessageSetSize = int32(len(recordBatch))
+			produceRequest.TopicBlocks[0].PartitonBlocks[0].RecordBatch = recordBatch
 		}
-		var message *Message = &Message{
-			Offset:      0,
-			MessageSize: 0, // compute in message encode
-
-			Crc:        0, // compute in message encode
-			Attributes: 0x00 | p.compressionValue,
-			MagicByte:  0,
-			Key:        nil,
-			Value:      compressed_value,
+
+		var partitionResponse *ProduceResponse_PartitionResponse
+		if p.coalescer != nil {
+			partitionResponse, err = p.coalescer.send(p.leader, produceRequest)
+		} else {
+			var (
+				responseBuf []byte
+				response    *ProduceResponse
+			)
+			responseBuf, err = p.leader.Request(produceRequest)
+			// no response with acks=0
+			if err == nil && produceRequest.RequiredAcks != 0 {
+				response, err = NewProduceResponse(responseBuf, version)
+				if glog.V(10) {
+					b, _ := json.Marshal(response)
+					glog.Infof("produces response: %s", b)
+				}
+			}
+			partitionResponse = getPartitionResponse(response)
+		}
+
+		// DUPLICATE_SEQUENCE_NUMBER means the batch has been written by the previous try
+		if err == nil || p.pid != nil && err == AllError[46] {
+			if p.pid != nil {
+				p.pid.advance(p.topic, p.partition, batch.ProducerID, batch.ProducerEpoch, int32(len(batch.Records)))
+			}
+			return partitionResponse, nil
 		}
-		messageSet = []*Message{message}
+
+		glog.Errorf("produce to %s[%d] error: %s", p.topic, p.partition, err)
+		if p.txn == nil && p.pid != nil && (err == AllError[45] || err == AllError[59]) {
+			// the broker did not write the batch, so it is safe to send it again with a new producer id
+			p.pid.reset(batch.ProducerID)
+			batch.ProducerID = -1
+			continue
+		}
+		if e, ok := err.(*Error); ok && !e.Retriable {
+			break
+		}
+		if i == p.config.Retries {
+			break
+		}
+
+		if isStaleLeaderError(err) {
+			if err := p.refreshLeader(); err != nil {
+				glog.Errorf("refresh leader of %s[%d] error: %s", p.topic, p.partition, err)
+			}
+		}
+		time.Sleep(time.Duration(p.config.RetryBackOffMS) * time.Millisecond)
 	}
-	produceRequest.TopicBlocks[0].PartitonBlocks[0].Partition = p.partition
-	produceRequest.TopicBlocks[0].PartitonBlocks[0].MessageSetSize = int32(len(messageSet))
-	produceRequest.TopicBlocks[0].PartitonBlocks[0].MessageSet = messageSet
 
-	responseBuf, err := p.leader.Request(produceRequest)
-	if err != nil {
-		return err
+	// the transaction could not be committed without the batch. it keeps the producer id until AbortTransaction
+	if p.txn != nil {
+		return nil, p.txn.fail(err)
 	}
-	response, err := NewProduceResponse(responseBuf)
-	if glog.V(10) {
-		b, _ := json.Marshal(response)
-		glog.Infof("produces response: %s", b)
+
+	// we could not tell whether the broker has written the batch with the sequence. drop the producer id to avoid OUT_OF_ORDER_SEQUENCE_NUMBER on the next batch
+	if e, ok := err.(*Error); p.pid != nil && (!ok || e.Retriable) {
+		p.pid.reset(batch.ProducerID)
 	}
-	return err
+	return nil, err
+}
+
+// getPartitionResponse returns the response of the only partition in the request of SimpleProducer
+func getPartitionResponse(response *ProduceResponse) *ProduceResponse_PartitionResponse {
+	if response == nil || len(response.ProduceResponses) == 0 || len(response.ProduceResponses[0].Partitions) == 0 {
+		return nil
+	}
+	return response.ProduceResponses[0].Partitions[0]
+}
+
+// buildRecordBatch puts all messages into one RecordBatch(magic 2). timestamp of the first message is the base timestamp
+func (p *SimpleProducer) buildRecordBatch(messageSet MessageSet) *RecordBatch {
+	baseTimestamp := messageSet[0].Timestamp
+	maxTimestamp := baseTimestamp
+	for _, message := range messageSet {
+		if message.Timestamp > maxTimestamp {
+			maxTimestamp = message.Timestamp
+		}
+	}
+	batch := &RecordBatch{
+		BaseOffset:           0,
+		PartitionLeaderEpoch: -1,
+		Magic:                2,
+		Attributes:           int16(p.compressionValue),
+		LastOffsetDelta:      int32(len(messageSet) - 1),
+		BaseTimestamp:        baseTimestamp,
+		MaxTimestamp:         maxTimestamp,
+		ProducerID:           -1,
+		ProducerEpoch:        -1,
+		BaseSequence:         -1,
+		Records:              make([]*Record, len(messageSet)),
+	}
+	if p.txn != nil {
+		batch.Attributes |= transactionalMask
+	}
+	for i, message := range messageSet {
+		batch.Records[i] = &Record{
+			Attributes:     0,
+			TimestampDelta: message.Timestamp - baseTimestamp,
+			OffsetDelta:    int32(i),
+			Key:            message.Key,
+			Value:          message.Value,
+			Headers:        message.Headers,
+		}
+	}
+	return batch
 }
 
 func (p *SimpleProducer) Close() {
@@ -263,5 +594,7 @@ func (p *SimpleProducer) Close() {
 	p.Flush()
 	glog.Info("SimpleProducer closing")
 	p.closed = true
-	p.leader.Close()
+	if p.coalescer == nil {
+		p.leader.Close()
+	}
 }
@@ -0,0 +1,243 @@
+package healer
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCluster has two brokers, node 0 and node 1, serving one topic test with one partition.
+// the broker which is not the leader answers produce requests with NOT_LEADER_FOR_PARTITION
+type fakeCluster struct {
+	nodes []*fakeBroker
+
+	mutex    sync.Mutex
+	leader   int32
+	produced []int32 // node id of the broker where each batch is written
+}
+
+func newFakeCluster(t *testing.T) *fakeCluster {
+	c := &fakeCluster{}
+	for nodeID := int32(0); nodeID < 2; nodeID++ {
+		nodeID := nodeID
+		c.nodes = append(c.nodes, newFakeBroker(t, func(request *fakeRequest) []byte {
+			return c.handle(nodeID, request)
+		}))
+	}
+	return c
+}
+
+func (c *fakeCluster) close() {
+	for _, node := range c.nodes {
+		node.close()
+	}
+}
+
+func (c *fakeCluster) setLeader(nodeID int32) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.leader = nodeID
+}
+
+func (c *fakeCluster) produceResponse(nodeID int32, body []byte) []byte {
+	c.mutex.Lock()
+	var (
+		errorCode  int16
+		baseOffset int64 = -1
+	)
+	if nodeID == c.leader {
+		baseOffset = 100
+		c.produced = append(c.produced, nodeID)
+	} else {
+		errorCode = 6
+	}
+	c.mutex.Unlock()
+
+	var results []fakeProduceResult
+	for _, p := range parseFakeProduceRequest(body).partitions {
+		results = append(results, fakeProduceResult{p.topic, p.partition, errorCode, baseOffset, -1})
+	}
+	return fakeProduceResponse(results)
+}
+
+func (c *fakeCluster) handle(nodeID int32, request *fakeRequest) []byte {
+	switch request.apiKey {
+	case API_ApiVersions:
+		return fakeApiVersionsResponse(map[uint16]uint16{API_ProduceRequest: 3})
+	case API_MetadataRequest:
+		c.mutex.Lock()
+		leader := c.leader
+		c.mutex.Unlock()
+		return fakeMetadataResponse(c.nodes, "test", []int32{leader})
+	case API_ProduceRequest:
+		return c.produceResponse(nodeID, request.body)
+	}
+	return nil
+}
+
+// newFakeClusterSimpleProducer returns a SimpleProducer of test[0] whose leader is found from the fake cluster
+func newFakeClusterSimpleProducer(t *testing.T, c *fakeCluster, config *ProducerConfig) (*SimpleProducer, func()) {
+	config.BootstrapServers = c.nodes[0].address()
+	return newFakeSimpleProducer(t, config, "test", 0, nil)
+}
+
+func TestRetryWithNewLeader(t *testing.T) {
+	c := newFakeCluster(t)
+	defer c.close()
+
+	config := DefaultProducerConfig()
+	config.Retries = 3
+	config.RetryBackOffMS = 10
+	p, cleanup := newFakeClusterSimpleProducer(t, c, config)
+	defer cleanup()
+	if p.leader.nodeID != 0 {
+		t.Fatalf("expect leader 0, got %d", p.leader.nodeID)
+	}
+
+	c.setLeader(1)
+	var report *DeliveryReport
+	messageSet := MessageSet{&Message{Value: []byte("hello"), callback: func(r *DeliveryReport) { report = r }}}
+	if err := p.flush(messageSet); err != nil {
+		t.Fatalf("flush error: %s", err)
+	}
+	if p.leader.nodeID != 1 {
+		t.Errorf("expect leader 1 after retry, got %d", p.leader.nodeID)
+	}
+	if len(c.produced) != 1 || c.produced[0] != 1 {
+		t.Errorf("expect the batch written once to node 1, got %v", c.produced)
+	}
+	if report == nil || report.Err != nil || report.Offset != 100 {
+		t.Errorf("expect offset 100 reported, got %+v", report)
+	}
+}
+
+func TestHeadersWhileLeaderRefreshed(t *testing.T) {
+	c := newFakeCluster(t)
+	defer c.close()
+
+	config := DefaultProducerConfig()
+	p, cleanup := newFakeClusterSimpleProducer(t, c, config)
+	defer cleanup()
+
+	// the leader is replaced by retries in the flushing goroutine while messages are added
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			p.flushMutex.Lock()
+			if err := p.refreshLeader(); err != nil {
+				t.Errorf("refresh leader error: %s", err)
+			}
+			p.flushMutex.Unlock()
+		}
+	}()
+	headers := []RecordHeader{{Key: "k", Value: []byte("v")}}
+	for i := 0; i < 10; i++ {
+		if err := p.AddMessageWithHeaders(nil, []byte("hello"), headers); err != nil {
+			t.Fatalf("add message with headers error: %s", err)
+		}
+	}
+	<-done
+}
+
+func TestRetryExhausted(t *testing.T) {
+	c := newFakeCluster(t)
+	defer c.close()
+
+	config := DefaultProducerConfig()
+	config.Retries = 0
+	p, cleanup := newFakeClusterSimpleProducer(t, c, config)
+	defer cleanup()
+
+	c.setLeader(1)
+	var report *DeliveryReport
+	messageSet := MessageSet{&Message{Value: []byte("hello"), callback: func(r *DeliveryReport) { report = r }}}
+	if err := p.flush(messageSet); err != AllError[6] {
+		t.Fatalf("expect NOT_LEADER_FOR_PARTITION without retries, got %v", err)
+	}
+	if report == nil || report.Err != AllError[6] {
+		t.Errorf("expect NOT_LEADER_FOR_PARTITION reported, got %+v", report)
+	}
+	if len(c.produced) != 0 {
+		t.Errorf("expect nothing written, got %v", c.produced)
+	}
+}
+
+func TestMessageTooLarge(t *testing.T) {
+	config := DefaultProducerConfig()
+	config.MaxRequestSize = 200
+	fakeBroker := &fakeProduceBroker{baseOffset: 100, logAppendTime: -1}
+	p, cleanup := newFakeProduceSimpleProducer(t, fakeBroker, config)
+	defer cleanup()
+
+	if err := p.AddMessage(nil, make([]byte, 200)); err != AllError[10] {
+		t.Errorf("expect MESSAGE_TOO_LARGE, got %v", err)
+	}
+	if len(p.messageSet) != 0 {
+		t.Errorf("message too large should not be added")
+	}
+	if err := p.AddMessage(nil, make([]byte, 10)); err != nil {
+		t.Errorf("add small message error: %s", err)
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	config := DefaultProducerConfig()
+	config.BatchSize = 300
+	config.LingerMS = 3600000
+	fakeBroker := &fakeProduceBroker{baseOffset: 100, logAppendTime: -1}
+	p, cleanup := newFakeProduceSimpleProducer(t, fakeBroker, config)
+	defer cleanup()
+
+	// each message is estimated as 136 bytes, so that only one fits in a batch of 300 bytes with the 61 bytes header
+	for i := 0; i < 3; i++ {
+		if err := p.AddMessage(nil, make([]byte, 100)); err != nil {
+			t.Fatalf("add message error: %s", err)
+		}
+	}
+	if n := fakeBroker.getProduceRequests(); n != 2 {
+		t.Errorf("expect 2 batches sent before the third message, got %d", n)
+	}
+	if len(p.messageSet) != 1 || p.batchBytes != 136 {
+		t.Errorf("expect 1 message of 136 bytes in the batch, got %d of %d bytes", len(p.messageSet), p.batchBytes)
+	}
+}
+
+func TestLinger(t *testing.T) {
+	config := DefaultProducerConfig()
+	config.LingerMS = 50
+	fakeBroker := &fakeProduceBroker{baseOffset: 100, logAppendTime: -1}
+	p, cleanup := newFakeProduceSimpleProducer(t, fakeBroker, config)
+	defer cleanup()
+
+	delivered := make(chan *DeliveryReport, 1)
+	start := time.Now()
+	if err := p.AddMessageWithCallback(nil, []byte("hello"), nil, 0, func(r *DeliveryReport) { delivered <- r }); err != nil {
+		t.Fatalf("add message error: %s", err)
+	}
+	select {
+	case report := <-delivered:
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("batch is sent before linger.ms: %s", elapsed)
+		}
+		if report.Err != nil || report.Offset != 100 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch is not sent after linger.ms")
+	}
+
+	// the timer of the flushed batch must not flush the next batch
+	p.AddMessage(nil, []byte("hello"))
+	p.Flush()
+	p.AddMessage(nil, []byte("world"))
+	if n := fakeBroker.getProduceRequests(); n != 2 {
+		t.Errorf("expect 2 batches sent, got %d", n)
+	}
+	p.mutex.Lock()
+	buffered := len(p.messageSet)
+	p.mutex.Unlock()
+	if buffered != 1 {
+		t.Errorf("expect 1 message waiting for linger.ms, got %d", buffered)
+	}
+}
@@ -1,6 +1,9 @@
 package healer
 
-import "github.com/eapache/go-xerial-snappy"
+import (
+	"github.com/eapache/go-xerial-snappy"
+	rawsnappy "github.com/golang/snappy"
+)
 
 type SnappyCompressor struct {
 }
@@ -8,3 +11,14 @@ type SnappyCompressor struct {
 func (c *SnappyCompressor) Compress(value []byte) ([]byte, error) {
 	return snappy.Encode(value), nil
 }
+
+// AppendCompress encodes into the spare capacity of dst, it is the same raw snappy block as Compress
+func (c *SnappyCompressor) AppendCompress(dst, value []byte) ([]byte, error) {
+	dst = growBuffer(dst, rawsnappy.MaxEncodedLen(len(value)))
+	encoded := rawsnappy.Encode(dst[len(dst):cap(dst)], value)
+	return dst[:len(dst)+len(encoded)], nil
+}
+
+func (c *SnappyCompressor) Decompress(value []byte) ([]byte, error) {
+	return snappy.Decode(value)
+}
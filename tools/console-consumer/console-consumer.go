@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"strings"
 
 	goflag "flag"
 
@@ -19,6 +20,7 @@ var (
 	topic         = flag.String("topic", "", "REQUIRED: The topic to consume from.")
 	maxMessages   = flag.Int("max-messages", math.MaxInt32, "The number of messages to consume (default: 2147483647)")
 	fromBeginning = flag.Bool("from-beginning", false, "default false")
+	printHeaders  = flag.Bool("print-headers", false, "print record headers of each message. default false")
 )
 
 func init() {
@@ -29,8 +31,10 @@ func init() {
 	flag.Int32Var(&consumerConfig.FetchMinBytes, "fetch.min.bytes", consumerConfig.FetchMinBytes, "The minimum amount of data the server should return for a fetch request. If insufficient data is available the request will wait for that much data to accumulate before answering the request.")
 	flag.Int32Var(&consumerConfig.FetchMaxBytes, "fetch.max.bytes", consumerConfig.FetchMaxBytes, "The maximum bytes to include in the message set for this partition. This helps bound the size of the response")
 	flag.Int32Var(&consumerConfig.FetchMaxWaitMS, "fetch.max.wait.ms", consumerConfig.FetchMaxWaitMS, "The maximum amount of time the server will block before answering the fetch request if there isn't sufficient data to immediately satisfy fetch.min.bytes")
+	flag.StringVar(&consumerConfig.IsolationLevel, "isolation.level", consumerConfig.IsolationLevel, "read_uncommitted or read_committed. messages of aborted transactions are dropped if it is read_committed")
 	flag.IntVar(&consumerConfig.ConnectTimeoutMS, "connect.timeout.ms", consumerConfig.ConnectTimeoutMS, "connect timeout to broker")
 	flag.IntVar(&consumerConfig.TimeoutMS, "timeout.ms", consumerConfig.TimeoutMS, "read timeout from connection to broker")
+	healer.RegisterSecurityFlags(flag.CommandLine, &consumerConfig.TLSEnabled, &consumerConfig.TLS, &consumerConfig.SASL)
 }
 func main() {
 	flag.Parse()
@@ -54,6 +58,14 @@ func main() {
 
 	for i := 0; i < *maxMessages; i++ {
 		message := <-messages
-		fmt.Printf("%d: %s\n", message.Message.Offset, message.Message.Value)
+		if *printHeaders {
+			headers := make([]string, len(message.Message.Headers))
+			for i, h := range message.Message.Headers {
+				headers[i] = fmt.Sprintf("%s:%s", h.Key, h.Value)
+			}
+			fmt.Printf("%d: [%s] %s\n", message.Message.Offset, strings.Join(headers, ","), message.Message.Value)
+		} else {
+			fmt.Printf("%d: %s\n", message.Message.Offset, message.Message.Value)
+		}
 	}
 }
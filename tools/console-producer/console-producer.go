@@ -6,22 +6,51 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/childe/healer"
 	"github.com/golang/glog"
 )
 
 var (
-	config = healer.DefaultProducerConfig()
-	topic  = flag.String("topic", "", "REQUIRED: The topic to consume from.")
+	config  = healer.DefaultProducerConfig()
+	topic   = flag.String("topic", "", "REQUIRED: The topic to consume from.")
+	headers = flag.String("headers", "", "headers attached to every message, in the format of key1:value1,key2:value2. need kafka 0.11.0+")
 )
 
+func parseHeaders(s string) []healer.RecordHeader {
+	if s == "" {
+		return nil
+	}
+	rst := make([]healer.RecordHeader, 0)
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(kv, ":", 2)
+		header := healer.RecordHeader{Key: parts[0]}
+		if len(parts) == 2 {
+			header.Value = []byte(parts[1])
+		}
+		rst = append(rst, header)
+	}
+	return rst
+}
+
 func init() {
 	flag.StringVar(&config.BootstrapServers, "brokers", "127.0.0.1:9092", "The list of hostname and port of the server to connect to.")
-	flag.StringVar(&config.CompressionType, "compression.type", "none", "defalut:none")
+	flag.StringVar(&config.CompressionType, "compression.type", "none", "none/gzip/snappy/lz4/zstd. defalut:none")
+	flag.IntVar(&config.CompressionLevel, "compression.level", config.CompressionLevel, "gzip(1-9) or zstd(1-22). 0 means the default level of the codec")
 	flag.IntVar(&config.MessageMaxCount, "message.max.count", config.MessageMaxCount, "")
-	flag.IntVar(&config.FlushIntervalMS, "flush.interval.ms", config.FlushIntervalMS, "")
+	flag.IntVar(&config.FlushIntervalMS, "flush.interval.ms", config.FlushIntervalMS, "deprecated, use linger.ms")
+	flag.IntVar(&config.LingerMS, "linger.ms", config.LingerMS, "how long the batch waits for more messages since its first message. -1 means flush.interval.ms")
+	flag.IntVar(&config.BatchSize, "batch.size", config.BatchSize, "max encoded size of a batch in bytes")
+	flag.IntVar(&config.MaxRequestSize, "max.request.size", config.MaxRequestSize, "max encoded size of a message in bytes")
+	flag.Int64Var(&config.BufferMemory, "buffer.memory", config.BufferMemory, "total bytes of the messages waiting to be sent")
+	flag.IntVar(&config.MaxBlockMS, "max.block.ms", config.MaxBlockMS, "how long to block when buffer.memory is exhausted")
 	flag.IntVar(&config.MetadataMaxAgeMS, "metadata.max.age.ms", config.MetadataMaxAgeMS, "The period of time in milliseconds after which we force a refresh of metadata even if we haven't seen any partition leadership changes to proactively discover any new brokers or partitions.")
+	flag.IntVar(&config.Retries, "retries", config.Retries, "resend the batch whose send fails with a potentially transient error")
+	flag.IntVar(&config.RetryBackOffMS, "retry.backoff.ms", config.RetryBackOffMS, "the time to wait before sending the batch again")
+	flag.IntVar(&config.MaxInFlightRequestsPerConnection, "max.in.flight.requests.per.connection", config.MaxInFlightRequestsPerConnection, "how many produce requests are sent to a broker before their responses come back")
+	flag.BoolVar(&config.EnableIdempotence, "enable.idempotence", config.EnableIdempotence, "make sure that retries never write duplicates. acks is set to -1 if enabled")
+	healer.RegisterSecurityFlags(flag.CommandLine, &config.TLSEnabled, &config.TLS, &config.SASL)
 }
 
 func main() {
@@ -32,6 +61,10 @@ func main() {
 		os.Exit(4)
 	}
 
+	if config.EnableIdempotence {
+		config.Acks = -1
+	}
+
 	producer := healer.NewProducer(*topic, config)
 
 	if producer == nil {
@@ -39,6 +72,8 @@ func main() {
 		os.Exit(5)
 	}
 
+	recordHeaders := parseHeaders(*headers)
+
 	var (
 		text     []byte = nil
 		line     []byte = nil
@@ -61,6 +96,8 @@ func main() {
 			}
 			text = append(text, line...)
 		}
-		producer.AddMessage(nil, text)
+		if err = producer.AddMessageWithHeaders(nil, text, recordHeaders); err != nil {
+			glog.Errorf("add message error:%s", err)
+		}
 	}
 }
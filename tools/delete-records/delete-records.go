@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/childe/healer"
+	"github.com/golang/glog"
+)
+
+var (
+	brokerConfig = healer.DefaultBrokerConfig()
+
+	brokerList    = flag.String("brokers", "127.0.0.1:9092", "REQUIRED: The list of hostname and port of the server to connect to.")
+	clientID      = flag.String("clientID", "healer", "The ID of this client.")
+	topic         = flag.String("topic", "", "REQUIRED: The topic to delete records from.")
+	partition     = flag.Int("partition", -1, "REQUIRED: The partition to delete records from.")
+	offset        = flag.Int64("offset", -2, "delete the records before this offset. -1 means the high watermark, that is all records")
+	before        = flag.String("before", "", "delete the records before this time, which is timestamp in milliseconds, RFC3339 time or duration relative to now like -2h. it is used if offset is not set")
+	deleteTimeout = flag.Int("delete-timeout", 30000, "how long to wait for the deletion to complete, in milliseconds")
+)
+
+func init() {
+	flag.IntVar(&brokerConfig.ConnectTimeoutMS, "connect-timeout", brokerConfig.ConnectTimeoutMS, fmt.Sprintf("connect timeout to broker. default %d", brokerConfig.ConnectTimeoutMS))
+	flag.IntVar(&brokerConfig.TimeoutMS, "timeout", brokerConfig.TimeoutMS, fmt.Sprintf("read timeout from connection to broker. default %d", brokerConfig.TimeoutMS))
+	healer.RegisterBrokerFlags(flag.CommandLine, brokerConfig)
+}
+
+func main() {
+	flag.Parse()
+
+	if *topic == "" || *partition < 0 {
+		glog.Error("need topic and partition")
+		flag.PrintDefaults()
+		os.Exit(4)
+	}
+	if *offset < -1 && *before == "" {
+		glog.Error("need offset or before")
+		flag.PrintDefaults()
+		os.Exit(4)
+	}
+
+	brokers, err := healer.NewBrokers(*brokerList, *clientID, brokerConfig)
+	if err != nil {
+		glog.Errorf("create brokers error:%s", err)
+		os.Exit(5)
+	}
+
+	deleteOffset := *offset
+	if deleteOffset < -1 {
+		timeValue, err := healer.ParseTimestamp(*before)
+		if err != nil {
+			glog.Errorf("parse before %s error:%s", *before, err)
+			os.Exit(4)
+		}
+		offsetsResponses, err := brokers.RequestOffsets(*clientID, *topic, int32(*partition), timeValue, 1)
+		if err != nil {
+			glog.Errorf("get offset at %s error:%s", *before, err)
+			os.Exit(5)
+		}
+		deleteOffset = -2
+		for _, offsetsResponse := range offsetsResponses {
+			for _, partitionOffset := range offsetsResponse.TopicPartitionOffsets[*topic] {
+				if partitionOffset.Partition == int32(*partition) && len(partitionOffset.Offsets) > 0 {
+					deleteOffset = partitionOffset.Offsets[0]
+				}
+			}
+		}
+		if deleteOffset < 0 {
+			glog.Errorf("no message of %s[%d] at or after %s", *topic, *partition, *before)
+			os.Exit(5)
+		}
+		glog.Infof("offset of %s[%d] at %s: %d", *topic, *partition, *before, deleteOffset)
+	}
+
+	lowWatermarks, err := brokers.DeleteRecords(*clientID, *topic, map[int32]int64{int32(*partition): deleteOffset}, int32(*deleteTimeout))
+	for partitionID, lowWatermark := range lowWatermarks {
+		fmt.Printf("%s:%d:%d\n", *topic, partitionID, lowWatermark)
+	}
+	if err != nil {
+		glog.Errorf("delete records error:%s", err)
+		os.Exit(5)
+	}
+}
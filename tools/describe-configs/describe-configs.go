@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/childe/healer"
+	"github.com/golang/glog"
+)
+
+var (
+	brokerConfig = healer.DefaultBrokerConfig()
+
+	brokerList   = flag.String("brokers", "127.0.0.1:9092", "REQUIRED: The list of hostname and port of the server to connect to.")
+	clientID     = flag.String("clientID", "healer", "The ID of this client.")
+	resourceType = flag.String("resource-type", "topic", "topic or broker")
+	resourceName = flag.String("resource-name", "", "REQUIRED: topic name or broker id")
+	configNames  = flag.String("configs", "", "config names separated by comma. all configs are described if not set")
+)
+
+func init() {
+	flag.IntVar(&brokerConfig.ConnectTimeoutMS, "connect-timeout", brokerConfig.ConnectTimeoutMS, fmt.Sprintf("connect timeout to broker. default %d", brokerConfig.ConnectTimeoutMS))
+	flag.IntVar(&brokerConfig.TimeoutMS, "timeout", brokerConfig.TimeoutMS, fmt.Sprintf("read timeout from connection to broker. default %d", brokerConfig.TimeoutMS))
+	healer.RegisterBrokerFlags(flag.CommandLine, brokerConfig)
+}
+
+func main() {
+	flag.Parse()
+
+	if *resourceName == "" {
+		glog.Error("need resource-name")
+		flag.PrintDefaults()
+		os.Exit(4)
+	}
+
+	var t int8
+	switch *resourceType {
+	case "topic":
+		t = healer.RESOURCE_TYPE_TOPIC
+	case "broker":
+		t = healer.RESOURCE_TYPE_BROKER
+	default:
+		glog.Errorf("unknown resource type %s", *resourceType)
+		os.Exit(4)
+	}
+
+	var names []string
+	if *configNames != "" {
+		names = strings.Split(*configNames, ",")
+	}
+
+	helper, err := healer.NewHelper(*brokerList, *clientID, brokerConfig)
+	if err != nil {
+		glog.Errorf("create helper error:%s", err)
+		os.Exit(5)
+	}
+
+	entries, err := helper.DescribeConfigs(t, *resourceName, names)
+	if err != nil {
+		glog.Errorf("describe configs error:%s", err)
+		os.Exit(5)
+	}
+
+	for _, entry := range entries {
+		value := entry.ConfigValue
+		if entry.IsSensitive {
+			value = "<sensitive>"
+		}
+		fmt.Printf("%s=%s source=%s default=%t sensitive=%t readonly=%t\n", entry.ConfigName, value, healer.ConfigSourceName(entry.ConfigSource), entry.IsDefault, entry.IsSensitive, entry.ReadOnly)
+	}
+}
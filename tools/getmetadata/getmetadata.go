@@ -11,15 +11,21 @@ import (
 )
 
 var (
+	brokerConfig = healer.DefaultBrokerConfig()
+
 	brokerList = flag.String("brokers", "127.0.0.1:9092", "REQUIRED: The list of hostname and port of the server to connect to.")
 	clientID   = flag.String("clientID", "healer", "The ID of this client.")
 	topic      = flag.String("topic", "", "REQUIRED: The topic to get offset from.")
 )
 
+func init() {
+	healer.RegisterBrokerFlags(flag.CommandLine, brokerConfig)
+}
+
 func main() {
 	flag.Parse()
 
-	brokers, err := healer.NewBrokers(*brokerList, *clientID, healer.DefaultBrokerConfig())
+	brokers, err := healer.NewBrokers(*brokerList, *clientID, brokerConfig)
 	if err != nil {
 		glog.Errorf("create brokers error:%s", err)
 		os.Exit(5)
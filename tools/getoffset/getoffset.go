@@ -11,14 +11,20 @@ import (
 )
 
 var (
+	brokerConfig = healer.DefaultBrokerConfig()
+
 	brokerList = flag.String("brokers", "127.0.0.1:9092", "<hostname:port,...,hostname:port> The comma separated list of brokers in the Kafka cluster. (default: 127.0.0.1:9092)")
 	topic      = flag.String("topic", "", "REQUIRED: The topic to get offset from.")
-	timestamp  = flag.Int64("timestamp", -1, "timestamp/-1(latest)/-2(earliest). timestamp of the offsets before that.(default: -1) ")
+	timestamp  = flag.String("timestamp", "-1", "-1(latest)/-2(earliest)/timestamp in milliseconds/RFC3339 time/duration relative to now like -2h. the first offset at or after the time is returned if offsets is 1 and the broker supports ListOffsets v1, otherwise the segment offsets before the time.(default: -1) ")
 	offsets    = flag.Uint("offsets", 1, "number of offsets returned (default: 1)")
 	clientID   = flag.String("clientID", "healer", "The ID of this client.")
 	format     = flag.String("format", "", "output original kafka response if set to original")
 )
 
+func init() {
+	healer.RegisterBrokerFlags(flag.CommandLine, brokerConfig)
+}
+
 func main() {
 	flag.Parse()
 
@@ -28,13 +34,19 @@ func main() {
 		os.Exit(4)
 	}
 
-	brokers, err := healer.NewBrokers(*brokerList, *clientID, healer.DefaultBrokerConfig())
+	timeValue, err := healer.ParseTimestamp(*timestamp)
+	if err != nil {
+		glog.Errorf("parse timestamp %s error:%s", *timestamp, err)
+		os.Exit(4)
+	}
+
+	brokers, err := healer.NewBrokers(*brokerList, *clientID, brokerConfig)
 	if err != nil {
 		glog.Errorf("create brokers error:%s", err)
 		os.Exit(5)
 	}
 
-	offsetsResponse, err := brokers.RequestOffsets(*clientID, *topic, -1, *timestamp, (uint32)(*offsets))
+	offsetsResponse, err := brokers.RequestOffsets(*clientID, *topic, -1, timeValue, (uint32)(*offsets))
 
 	if err != nil {
 		glog.Errorf("failed to get offsets:%s", err)
@@ -60,6 +72,9 @@ func main() {
 						}
 						fmt.Printf("%d", offset)
 					}
+					if partitionOffsets.Timestamp >= 0 {
+						fmt.Printf(":%d", partitionOffsets.Timestamp)
+					}
 					fmt.Println()
 				}
 			}
@@ -18,12 +18,13 @@ var (
 	offsetsStorage = flag.String("offsets.storage", "kafka", "default kafka. Select where offsets should be stored (zookeeper or kafka).")
 	clientID       = flag.String("clientID", "healer", "The ID of this client. default healer")
 	groupID        = flag.String("groupID", "", "REQUIRED: The ID of this client.")
-	timestamp      = flag.Int64("timestamp", -3, "REQUIRED: -2 which means beginning; -1 means end.")
+	timestamp      = flag.String("timestamp", "", "REQUIRED: -2 which means beginning; -1 means end; or timestamp in milliseconds, RFC3339 time, duration relative to now like -2h. the offset is reset to the first message at or after the time")
 )
 
 func init() {
 	flag.IntVar(&brokerConfig.ConnectTimeoutMS, "connect-timeout", brokerConfig.ConnectTimeoutMS, fmt.Sprintf("connect timeout to broker. default %d", brokerConfig.ConnectTimeoutMS))
 	flag.IntVar(&brokerConfig.TimeoutMS, "timeout", brokerConfig.TimeoutMS, fmt.Sprintf("read timeout from connection to broker. default %d", brokerConfig.TimeoutMS))
+	healer.RegisterBrokerFlags(flag.CommandLine, brokerConfig)
 }
 
 func main() {
@@ -41,14 +42,14 @@ func main() {
 		os.Exit(4)
 	}
 
-	if *timestamp < -2 {
+	timeValue, err := healer.ParseTimestamp(*timestamp)
+	if err != nil {
 		flag.PrintDefaults()
-		fmt.Printf("illegel timestamp:%d\n", *timestamp)
+		fmt.Printf("illegel timestamp %s:%s\n", *timestamp, err)
 		os.Exit(4)
 	}
 
 	var (
-		err     error
 		brokers *healer.Brokers
 	)
 	brokers, err = healer.NewBrokers(*brokersList, *clientID, brokerConfig)
@@ -80,7 +81,7 @@ func main() {
 		}
 
 		// get offset
-		offsetsResponses, err := brokers.RequestOffsets(*clientID, *topic, int32(partitionID), *timestamp, 3)
+		offsetsResponses, err := brokers.RequestOffsets(*clientID, *topic, int32(partitionID), timeValue, 1)
 		if err != nil {
 			glog.Fatalf("could not get offsets:%s", err)
 		}
@@ -98,6 +99,10 @@ func main() {
 					}
 					glog.Infof("%s:%d:%v", topic, partition, _offsets)
 					offset = int64(_offsets[0])
+					if offset == -1 {
+						// no message at or after the time, reset to the end as kafka-consumer-groups does
+						offset = getLatestOffset(brokers, topic, partition)
+					}
 					offsets[partitionID] = offset
 				}
 			}
@@ -198,3 +203,19 @@ func main() {
 		glog.Errorf("commit offset [%s][%d]:%d error:%s", *topic, partitionID, offset, err)
 	}
 }
+
+func getLatestOffset(brokers *healer.Brokers, topic string, partition int32) int64 {
+	offsetsResponses, err := brokers.RequestOffsets(*clientID, topic, partition, -1, 1)
+	if err != nil {
+		glog.Fatalf("could not get latest offset of %s[%d]:%s", topic, partition, err)
+	}
+	for _, offsetsResponse := range offsetsResponses {
+		for _, partitionOffset := range offsetsResponse.TopicPartitionOffsets[topic] {
+			if partitionOffset.ErrorCode == 0 && len(partitionOffset.Offsets) > 0 {
+				return partitionOffset.Offsets[0]
+			}
+		}
+	}
+	glog.Fatalf("could not get latest offset of %s[%d]", topic, partition)
+	return -1
+}
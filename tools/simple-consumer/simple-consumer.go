@@ -34,8 +34,10 @@ func init() {
 	flag.Int32Var(&consumerConfig.FetchMinBytes, "fetch.min.bytes", consumerConfig.FetchMinBytes, "The minimum amount of data the server should return for a fetch request. If insufficient data is available the request will wait for that much data to accumulate before answering the request.")
 	flag.Int32Var(&consumerConfig.FetchMaxBytes, "fetch.max.bytes", consumerConfig.FetchMaxBytes, "The maximum bytes to include in the message set for this partition. This helps bound the size of the response")
 	flag.Int32Var(&consumerConfig.FetchMaxWaitMS, "fetch.max.wait.ms", consumerConfig.FetchMaxWaitMS, "The maximum amount of time the server will block before answering the fetch request if there isn't sufficient data to immediately satisfy fetch.min.bytes")
+	flag.StringVar(&consumerConfig.IsolationLevel, "isolation.level", consumerConfig.IsolationLevel, "read_uncommitted or read_committed. messages of aborted transactions are dropped if it is read_committed")
 	flag.IntVar(&consumerConfig.ConnectTimeoutMS, "connect.timeout.ms", consumerConfig.ConnectTimeoutMS, "connect timeout to broker")
 	flag.IntVar(&consumerConfig.TimeoutMS, "timeout.ms", consumerConfig.TimeoutMS, "read timeout from connection to broker")
+	healer.RegisterSecurityFlags(flag.CommandLine, &consumerConfig.TLSEnabled, &consumerConfig.TLS, &consumerConfig.SASL)
 	flag.BoolVar(&printOffset, "printoffset", printOffset, "if print offset before message")
 }
 
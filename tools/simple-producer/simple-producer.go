@@ -23,8 +23,18 @@ func init() {
 	flag.CommandLine.AddGoFlagSet(goflag.CommandLine)
 
 	flag.StringVar(&config.BootstrapServers, "bootstrap.servers", config.BootstrapServers, "The list of hostname and port of the server to connect to.")
-	flag.StringVar(&config.CompressionType, "compression.type", config.CompressionType, "The compression type for all data generated by the producer. The default is none (i.e. no compression). Valid values are none, gzip, snappy, or lz4. Compression is of full batches of data, so the efficacy of batching will also impact the compression ratio (more batching means better compression).")
+	flag.StringVar(&config.CompressionType, "compression.type", config.CompressionType, "The compression type for all data generated by the producer. The default is none (i.e. no compression). Valid values are none, gzip, snappy, lz4 or zstd. zstd needs kafka 2.1+. Compression is of full batches of data, so the efficacy of batching will also impact the compression ratio (more batching means better compression).")
+	flag.IntVar(&config.CompressionLevel, "compression.level", config.CompressionLevel, "The compression level of gzip(1-9) or zstd(1-22). 0 means the default level of the codec.")
+	flag.IntVar(&config.LingerMS, "linger.ms", config.LingerMS, "The producer groups together any records that arrive in between request transmissions into a single batched request. The batch is sent linger.ms after its first record at the latest. -1 means flush.interval.ms, which is deprecated.")
+	flag.IntVar(&config.BatchSize, "batch.size", config.BatchSize, "The max encoded size of a batch in bytes. The batch is sent once it is full.")
+	flag.IntVar(&config.MaxRequestSize, "max.request.size", config.MaxRequestSize, "The max encoded size of a record in bytes. Larger records are rejected with MESSAGE_TOO_LARGE.")
+	flag.Int64Var(&config.BufferMemory, "buffer.memory", config.BufferMemory, "The total bytes of memory the producer can use to buffer records waiting to be sent to the server.")
+	flag.IntVar(&config.MaxBlockMS, "max.block.ms", config.MaxBlockMS, "How long to block when buffer.memory is exhausted. 0 means failing at once.")
 	flag.IntVar(&config.ConnectionsMaxIdleMS, "connections.max.idle.ms", config.ConnectionsMaxIdleMS, "Close idle connections after the number of milliseconds specified by this config.")
+	flag.IntVar(&config.Retries, "retries", config.Retries, "resend the batch whose send fails with a potentially transient error")
+	flag.IntVar(&config.RetryBackOffMS, "retry.backoff.ms", config.RetryBackOffMS, "the time to wait before sending the batch again")
+	flag.BoolVar(&config.EnableIdempotence, "enable.idempotence", config.EnableIdempotence, "make sure that retries never write duplicates. acks is set to -1 if enabled")
+	healer.RegisterSecurityFlags(flag.CommandLine, &config.TLSEnabled, &config.TLS, &config.SASL)
 }
 
 func main() {
@@ -35,6 +45,10 @@ func main() {
 		os.Exit(4)
 	}
 
+	if config.EnableIdempotence {
+		config.Acks = -1
+	}
+
 	simpleProducer := healer.NewSimpleProducer(*topic, int32(*partition), config)
 
 	if simpleProducer == nil {
@@ -0,0 +1,288 @@
+package healer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+var (
+	notTransactionalError        = errors.New("transactional.id is not set")
+	transactionNotBegunError     = errors.New("transaction has not begun")
+	transactionInProgressError   = errors.New("transaction is already in progress")
+	transactionNotSupportedError = errors.New("transaction needs produce api v3, which is not supported by the leader")
+)
+
+// transactionAbortableError is returned after a batch of the transaction failed. the transaction could only be aborted then
+type transactionAbortableError struct {
+	err error
+}
+
+func (e *transactionAbortableError) Error() string {
+	return fmt.Sprintf("transaction must be aborted: %s", e.err)
+}
+
+// isRetriableTxnError tells if the request to the coordinator could be sent again. connection errors are retriable too
+func isRetriableTxnError(err error) bool {
+	if e, ok := err.(*Error); ok {
+		return e.Retriable
+	}
+	return true
+}
+
+// transactionManager talks to the transaction coordinator of the transactional id, and tracks the partitions in the current transaction
+type transactionManager struct {
+	config  *ProducerConfig
+	brokers *Brokers
+	pid     *producerIDManager
+
+	mutex         sync.Mutex
+	coordinator   *Broker
+	inTransaction bool
+	partitions    map[topicPartition]bool
+	offsetsAdded  bool
+
+	// abortableError is set when a batch of the transaction failed, and cleared after the transaction is aborted
+	abortableError *transactionAbortableError
+}
+
+func newTransactionManager(config *ProducerConfig, brokers *Brokers, pid *producerIDManager) *transactionManager {
+	return &transactionManager{
+		config:  config,
+		brokers: brokers,
+		pid:     pid,
+	}
+}
+
+func (t *transactionManager) begin() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.inTransaction {
+		return transactionInProgressError
+	}
+	// the last abort could not init the producer id
+	if !t.pid.inited() {
+		if err := t.pid.reinit(); err != nil {
+			return err
+		}
+	}
+	t.inTransaction = true
+	t.partitions = make(map[topicPartition]bool)
+	t.offsetsAdded = false
+	return nil
+}
+
+func (t *transactionManager) isInTransaction() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.inTransaction
+}
+
+// fail makes the transaction abortable after a batch of it failed. the producer id is not reset here because the
+// other batches of the transaction are still using it, a new one is inited when the transaction is aborted
+func (t *transactionManager) fail(err error) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.abortableError == nil {
+		t.abortableError = &transactionAbortableError{err}
+	}
+	return t.abortableError
+}
+
+// addPartition adds the partition to the transaction by AddPartitionsToTxn before any message is produced to it
+func (t *transactionManager) addPartition(topic string, partition int32) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.inTransaction {
+		return transactionNotBegunError
+	}
+	if t.abortableError != nil {
+		return t.abortableError
+	}
+	tp := topicPartition{topic, partition}
+	if t.partitions[tp] {
+		return nil
+	}
+
+	producerID, producerEpoch, err := t.pid.current()
+	if err != nil {
+		return err
+	}
+	request := NewAddPartitionsToTxnRequest(0, t.config.ClientID, t.config.TransactionalID, producerID, producerEpoch)
+	request.AddPartition(topic, partition)
+	err = t.send("", request, func(payload []byte) error {
+		_, err := NewAddPartitionsToTxnResponse(payload)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("add %s[%d] to transaction error: %s", topic, partition, err)
+	}
+	t.partitions[tp] = true
+	return nil
+}
+
+// sendOffsets commits the offsets of the group as a part of the transaction. offsets are the next offsets to consume
+func (t *transactionManager) sendOffsets(offsets map[string]map[int32]int64, groupID string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.inTransaction {
+		return transactionNotBegunError
+	}
+	if t.abortableError != nil {
+		return t.abortableError
+	}
+
+	producerID, producerEpoch, err := t.pid.current()
+	if err != nil {
+		return err
+	}
+
+	addOffsetsRequest := NewAddOffsetsToTxnRequest(0, t.config.ClientID, t.config.TransactionalID, producerID, producerEpoch, groupID)
+	err = t.send("", addOffsetsRequest, func(payload []byte) error {
+		_, err := NewAddOffsetsToTxnResponse(payload)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("add offsets of group %s to transaction error: %s", groupID, err)
+	}
+	t.offsetsAdded = true
+
+	txnOffsetCommitRequest := NewTxnOffsetCommitRequest(0, t.config.ClientID, t.config.TransactionalID, groupID, producerID, producerEpoch)
+	for topic, partitions := range offsets {
+		for partitionID, offset := range partitions {
+			txnOffsetCommitRequest.AddPartiton(topic, partitionID, offset, "")
+		}
+	}
+	err = t.send(groupID, txnOffsetCommitRequest, func(payload []byte) error {
+		_, err := NewTxnOffsetCommitResponse(payload)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("commit offsets of group %s in transaction error: %s", groupID, err)
+	}
+	return nil
+}
+
+// end commits or aborts the transaction by EndTxn. all messages in the transaction must have been flushed before commit.
+// an abortable transaction could not be committed, and aborting it inits a new producer id instead of EndTxn
+func (t *transactionManager) end(commit bool) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.inTransaction {
+		return transactionNotBegunError
+	}
+
+	if t.abortableError != nil {
+		if commit {
+			return t.abortableError
+		}
+		// sequences of the failed batches are out of sync with the broker. InitProducerId bumps the epoch, and the
+		// coordinator aborts the ongoing transaction of the previous epoch
+		if err := t.pid.reinit(); err != nil {
+			return fmt.Errorf("init producer id to abort transaction error: %s", err)
+		}
+		t.clear()
+		return nil
+	}
+
+	// nothing has been added to the transaction in the coordinator
+	if len(t.partitions) == 0 && !t.offsetsAdded {
+		t.clear()
+		return nil
+	}
+
+	producerID, producerEpoch, err := t.pid.current()
+	if err != nil {
+		return err
+	}
+	request := NewEndTxnRequest(0, t.config.ClientID, t.config.TransactionalID, producerID, producerEpoch, commit)
+	err = t.send("", request, func(payload []byte) error {
+		_, err := NewEndTxnResponse(payload)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	t.clear()
+	return nil
+}
+
+// clear drops the state of the ended transaction. it must be called with t.mutex held
+func (t *transactionManager) clear() {
+	t.inTransaction = false
+	t.partitions = nil
+	t.offsetsAdded = false
+	t.abortableError = nil
+}
+
+// send sends the request to the transaction coordinator, or to the coordinator of the group if groupID is not empty.
+// it retries on retriable errors until transaction.timeout.ms. it must be called with t.mutex held
+func (t *transactionManager) send(groupID string, request Request, decode func([]byte) error) error {
+	deadline := time.Now().Add(time.Duration(t.config.TransactionTimeoutMS) * time.Millisecond)
+	for {
+		err := func() error {
+			coordinator, err := t.getCoordinator(groupID)
+			if err != nil {
+				return err
+			}
+			responseBuf, err := coordinator.Request(request)
+			if err != nil {
+				return err
+			}
+			return decode(responseBuf)
+		}()
+		if err == nil {
+			return nil
+		}
+
+		// COORDINATOR_NOT_AVAILABLE NOT_COORDINATOR or connection error
+		if _, ok := err.(*Error); !ok || err == AllError[15] || err == AllError[16] {
+			if groupID == "" {
+				t.coordinator = nil
+			}
+		}
+		if !isRetriableTxnError(err) || time.Now().After(deadline) {
+			return err
+		}
+		glog.Infof("request[%d] to coordinator error: %s. retry", request.API(), err)
+		time.Sleep(time.Duration(t.config.RetryBackOffMS) * time.Millisecond)
+	}
+}
+
+// getCoordinator returns the cached transaction coordinator, or finds the coordinator of the group if groupID is not empty
+func (t *transactionManager) getCoordinator(groupID string) (*Broker, error) {
+	if groupID == "" && t.coordinator != nil {
+		return t.coordinator, nil
+	}
+
+	var (
+		coordinatorResponse *FindCoordinatorResponse
+		err                 error
+	)
+	if groupID == "" {
+		coordinatorResponse, err = t.brokers.FindTransactionCoordinator(t.config.ClientID, t.config.TransactionalID)
+	} else {
+		coordinatorResponse, err = t.brokers.FindCoordinator(t.config.ClientID, groupID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	coordinator, err := t.brokers.GetBroker(coordinatorResponse.Coordinator.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	if groupID == "" {
+		glog.Infof("transaction coordinator for %s: %s", t.config.TransactionalID, coordinator.address)
+		t.coordinator = coordinator
+	}
+	return coordinator, nil
+}
@@ -0,0 +1,194 @@
+package healer
+
+import (
+	"sync"
+	"testing"
+)
+
+// fakeTransactionCoordinator is the transaction coordinator and the group coordinator at the same time.
+// it answers CONCURRENT_TRANSACTIONS to the first AddPartitionsToTxn
+type fakeTransactionCoordinator struct {
+	host string
+	port int32
+
+	mutex     sync.Mutex
+	apis      []uint16
+	committed []bool
+}
+
+func (c *fakeTransactionCoordinator) handle(request *fakeRequest) []byte {
+	apiKey := request.apiKey
+	body := request.body
+
+	c.mutex.Lock()
+	c.apis = append(c.apis, apiKey)
+	addPartitionsCount := 0
+	for _, api := range c.apis {
+		if api == API_AddPartitionsToTxn {
+			addPartitionsCount++
+		}
+	}
+	c.mutex.Unlock()
+
+	d := &fakeDecoder{body}
+	var e fakeEncoder
+	switch apiKey {
+	case API_ApiVersions:
+		return fakeApiVersionsResponse(map[uint16]uint16{API_FindCoordinator: 1})
+	case API_FindCoordinator:
+		// throttle_time_ms error_code error_message node_id host port
+		e.putInt32(0)
+		e.putInt16(0)
+		e.putInt16(-1)
+		e.putInt32(1)
+		e.putString(c.host)
+		e.putInt32(c.port)
+	case API_AddPartitionsToTxn, API_TxnOffsetCommit:
+		// echo the first topic and partition
+		d.readString()
+		if apiKey == API_TxnOffsetCommit {
+			d.readString()
+		}
+		d.readInt64()
+		d.readInt16()
+		d.readInt32()
+		topic := d.readString()
+		d.readInt32()
+		partition := d.readInt32()
+
+		// throttle_time_ms [topic [partition error_code]]
+		var errorCode int16
+		if apiKey == API_AddPartitionsToTxn && addPartitionsCount == 1 {
+			errorCode = 51
+		}
+		e.putInt32(0)
+		e.putInt32(1)
+		e.putString(topic)
+		e.putInt32(1)
+		e.putInt32(partition)
+		e.putInt16(errorCode)
+	case API_AddOffsetsToTxn:
+		// throttle_time_ms error_code
+		e.putInt32(0)
+		e.putInt16(0)
+	case API_EndTxn:
+		c.mutex.Lock()
+		c.committed = append(c.committed, body[len(body)-1] == 1)
+		c.mutex.Unlock()
+		e.putInt32(0)
+		e.putInt16(0)
+	default:
+		return nil
+	}
+	return e.Bytes()
+}
+
+func TestTransactionManager(t *testing.T) {
+	coordinator := &fakeTransactionCoordinator{host: "127.0.0.1"}
+	server := newFakeBroker(t, coordinator.handle)
+	defer server.close()
+	coordinator.port = server.port()
+
+	brokerConfig := DefaultBrokerConfig()
+	brokerConfig.TimeoutMS = 5000
+	brokers := &Brokers{
+		config:      brokerConfig,
+		brokersInfo: map[int32]*BrokerInfo{1: {NodeId: 1, Host: "127.0.0.1", Port: server.port()}},
+		brokers:     make(map[int32]*Broker),
+		mutex:       &sync.Mutex{},
+	}
+
+	config := DefaultProducerConfig()
+	config.TransactionalID = "test-txn"
+	config.RetryBackOffMS = 1
+	txn := newTransactionManager(config, brokers, &producerIDManager{producerID: 1000, producerEpoch: 1})
+
+	if err := txn.addPartition("test", 0); err != transactionNotBegunError {
+		t.Errorf("add partition out of transaction should fail, got %v", err)
+	}
+	if err := txn.begin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.begin(); err != transactionInProgressError {
+		t.Errorf("begin twice should fail, got %v", err)
+	}
+
+	if err := txn.addPartition("test", 0); err != nil {
+		t.Fatalf("add partition error: %s", err)
+	}
+	if err := txn.addPartition("test", 0); err != nil {
+		t.Fatalf("add partition again error: %s", err)
+	}
+	if err := txn.sendOffsets(map[string]map[int32]int64{"source": {0: 100}}, "test-group"); err != nil {
+		t.Fatalf("send offsets error: %s", err)
+	}
+	if err := txn.end(true); err != nil {
+		t.Fatalf("commit error: %s", err)
+	}
+	if txn.isInTransaction() {
+		t.Error("transaction should end after commit")
+	}
+
+	// nothing is added to the coordinator in the empty transaction
+	txn.begin()
+	if err := txn.end(false); err != nil {
+		t.Fatalf("abort error: %s", err)
+	}
+
+	expected := []uint16{
+		API_ApiVersions, API_FindCoordinator,
+		API_AddPartitionsToTxn, API_AddPartitionsToTxn,
+		API_AddOffsetsToTxn, API_FindCoordinator, API_TxnOffsetCommit,
+		API_EndTxn,
+	}
+	if len(coordinator.apis) != len(expected) {
+		t.Fatalf("expect requests %v, got %v", expected, coordinator.apis)
+	}
+	for i := range expected {
+		if coordinator.apis[i] != expected[i] {
+			t.Fatalf("expect requests %v, got %v", expected, coordinator.apis)
+		}
+	}
+	if len(coordinator.committed) != 1 || !coordinator.committed[0] {
+		t.Errorf("expect one committed EndTxn, got %v", coordinator.committed)
+	}
+}
+
+func TestAbortableTransaction(t *testing.T) {
+	config := DefaultProducerConfig()
+	config.TransactionalID = "test-txn"
+	config.BootstrapServers = "127.0.0.1:1"
+	config.TransactionTimeoutMS = 10
+	config.RetryBackOffMS = 1
+	pid := &producerIDManager{config: config, producerID: 1000, producerEpoch: 1}
+	txn := newTransactionManager(config, nil, pid)
+
+	txn.begin()
+	err := txn.fail(AllError[45])
+	if _, ok := err.(*transactionAbortableError); !ok {
+		t.Fatalf("expect transactionAbortableError, got %v", err)
+	}
+	if err := txn.addPartition("test", 0); err != txn.abortableError {
+		t.Errorf("add partition to abortable transaction should fail, got %v", err)
+	}
+	if err := txn.sendOffsets(map[string]map[int32]int64{"source": {0: 100}}, "test-group"); err != txn.abortableError {
+		t.Errorf("send offsets to abortable transaction should fail, got %v", err)
+	}
+	if err := txn.end(true); err != txn.abortableError {
+		t.Errorf("commit abortable transaction should fail, got %v", err)
+	}
+	if producerID, _, err := pid.current(); producerID != 1000 || err != nil {
+		t.Errorf("producer id should be kept until abort, got %d %v", producerID, err)
+	}
+
+	// the producer id could not be inited by the unreachable coordinator
+	if err := txn.end(false); err == nil {
+		t.Error("abort should fail if producer id could not be inited")
+	}
+	if !txn.isInTransaction() || txn.abortableError == nil {
+		t.Error("transaction should be abortable until the producer id is inited")
+	}
+	if _, _, err := pid.current(); err != producerIDNotInitedError {
+		t.Errorf("current should not init the producer id of a transactional producer, got %v", err)
+	}
+}
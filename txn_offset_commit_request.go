@@ -0,0 +1,157 @@
+package healer
+
+import (
+	"encoding/binary"
+)
+
+/*
+TxnOffsetCommit Request (Version: 0) => transactional_id group_id producer_id producer_epoch [topics]
+  transactional_id => STRING
+  group_id => STRING
+  producer_id => INT64
+  producer_epoch => INT16
+  topics => name [partitions]
+    name => STRING
+    partitions => partition_index committed_offset committed_metadata
+      partition_index => INT32
+      committed_offset => INT64
+      committed_metadata => NULLABLE_STRING
+
+FIELD	DESCRIPTION
+  transactional_id	The ID of the transaction.
+  group_id	The ID of the group.
+  producer_id	The current producer ID in use by the transactional ID.
+  producer_epoch	The current epoch associated with the producer ID.
+  topics	Each topic that we want to commit offsets for.
+  name	The topic name.
+  partitions	The partitions inside the topic that we want to commit offsets for.
+  partition_index	The index of the partition within the topic.
+  committed_offset	The message offset to be committed.
+  committed_metadata	Any associated metadata the client wants to keep.
+*/
+
+type TxnOffsetCommitRequestPartition struct {
+	PartitionID int32
+	Offset      int64
+	Metadata    string
+}
+
+type TxnOffsetCommitRequestTopic struct {
+	Topic      string
+	Partitions []*TxnOffsetCommitRequestPartition
+}
+
+type TxnOffsetCommitRequest struct {
+	RequestHeader   *RequestHeader
+	TransactionalID string
+	GroupID         string
+	ProducerID      int64
+	ProducerEpoch   int16
+	Topics          []*TxnOffsetCommitRequestTopic
+}
+
+func NewTxnOffsetCommitRequest(apiVersion uint16, clientID, transactionalID, groupID string, producerID int64, producerEpoch int16) *TxnOffsetCommitRequest {
+	requestHeader := &RequestHeader{
+		ApiKey:     API_TxnOffsetCommit,
+		ApiVersion: apiVersion,
+		ClientId:   clientID,
+	}
+	return &TxnOffsetCommitRequest{
+		RequestHeader:   requestHeader,
+		TransactionalID: transactionalID,
+		GroupID:         groupID,
+		ProducerID:      producerID,
+		ProducerEpoch:   producerEpoch,
+		Topics:          make([]*TxnOffsetCommitRequestTopic, 0),
+	}
+}
+
+func (r *TxnOffsetCommitRequest) AddPartiton(topic string, partitionID int32, offset int64, metadata string) {
+	var theTopic *TxnOffsetCommitRequestTopic = nil
+	for _, t := range r.Topics {
+		if t.Topic == topic {
+			theTopic = t
+			break
+		}
+	}
+	if theTopic == nil {
+		theTopic = &TxnOffsetCommitRequestTopic{
+			Topic:      topic,
+			Partitions: make([]*TxnOffsetCommitRequestPartition, 0),
+		}
+		r.Topics = append(r.Topics, theTopic)
+	}
+	theTopic.Partitions = append(theTopic.Partitions, &TxnOffsetCommitRequestPartition{partitionID, offset, metadata})
+}
+
+func (r *TxnOffsetCommitRequest) Length() int {
+	l := r.RequestHeader.length()
+	l += 2 + len(r.TransactionalID) + 2 + len(r.GroupID) + 8 + 2
+	l += 4
+	for _, t := range r.Topics {
+		l += 2 + len(t.Topic)
+		l += 4
+		for _, p := range t.Partitions {
+			l += 4 + 8 + 2 + len(p.Metadata)
+		}
+	}
+	return l
+}
+
+func (r *TxnOffsetCommitRequest) Encode() []byte {
+	requestLength := r.Length()
+
+	payload := make([]byte, requestLength+4)
+	offset := 0
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(requestLength))
+	offset += 4
+
+	offset = r.RequestHeader.Encode(payload, offset)
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(len(r.TransactionalID)))
+	offset += 2
+	offset += copy(payload[offset:], r.TransactionalID)
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(len(r.GroupID)))
+	offset += 2
+	offset += copy(payload[offset:], r.GroupID)
+
+	binary.BigEndian.PutUint64(payload[offset:], uint64(r.ProducerID))
+	offset += 8
+
+	binary.BigEndian.PutUint16(payload[offset:], uint16(r.ProducerEpoch))
+	offset += 2
+
+	binary.BigEndian.PutUint32(payload[offset:], uint32(len(r.Topics)))
+	offset += 4
+	for _, t := range r.Topics {
+		binary.BigEndian.PutUint16(payload[offset:], uint16(len(t.Topic)))
+		offset += 2
+		offset += copy(payload[offset:], t.Topic)
+
+		binary.BigEndian.PutUint32(payload[offset:], uint32(len(t.Partitions)))
+		offset += 4
+		for _, p := range t.Partitions {
+			binary.BigEndian.PutUint32(payload[offset:], uint32(p.PartitionID))
+			offset += 4
+
+			binary.BigEndian.PutUint64(payload[offset:], uint64(p.Offset))
+			offset += 8
+
+			binary.BigEndian.PutUint16(payload[offset:], uint16(len(p.Metadata)))
+			offset += 2
+			offset += copy(payload[offset:], p.Metadata)
+		}
+	}
+
+	return payload
+}
+
+func (r *TxnOffsetCommitRequest) API() uint16 {
+	return r.RequestHeader.ApiKey
+}
+
+func (r *TxnOffsetCommitRequest) SetCorrelationID(c uint32) {
+	r.RequestHeader.CorrelationID = c
+}
@@ -0,0 +1,92 @@
+package healer
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+/*
+TxnOffsetCommit Response (Version: 0) => throttle_time_ms [topics]
+  throttle_time_ms => INT32
+  topics => name [partitions]
+    name => STRING
+    partitions => partition_index error_code
+      partition_index => INT32
+      error_code => INT16
+
+FIELD	DESCRIPTION
+  throttle_time_ms	The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+  topics	The responses for each topic.
+  name	The topic name.
+  partitions	The responses for each partition in the topic.
+  partition_index	The partition index.
+  error_code	The error code, or 0 if there was no error.
+*/
+
+type TxnOffsetCommitResponsePartition struct {
+	PartitionID int32
+	ErrorCode   int16
+}
+
+type TxnOffsetCommitResponseTopic struct {
+	Topic      string
+	Partitions []*TxnOffsetCommitResponsePartition
+}
+
+type TxnOffsetCommitResponse struct {
+	CorrelationID  uint32
+	ThrottleTimeMS int32
+	Topics         []*TxnOffsetCommitResponseTopic
+}
+
+func NewTxnOffsetCommitResponse(payload []byte) (*TxnOffsetCommitResponse, error) {
+	var (
+		r      *TxnOffsetCommitResponse = &TxnOffsetCommitResponse{}
+		err    error                    = nil
+		offset int                      = 0
+		l      int                      = 0
+	)
+	responseLength := int(binary.BigEndian.Uint32(payload))
+	if responseLength+4 != len(payload) {
+		return nil, fmt.Errorf("TxnOffsetCommit reseponse length did not match: %d!=%d", responseLength+4, len(payload))
+	}
+	offset += 4
+
+	r.CorrelationID = binary.BigEndian.Uint32(payload[offset:])
+	offset += 4
+
+	r.ThrottleTimeMS = int32(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+
+	l = int(binary.BigEndian.Uint32(payload[offset:]))
+	offset += 4
+	r.Topics = make([]*TxnOffsetCommitResponseTopic, l)
+	for i := range r.Topics {
+		topic := &TxnOffsetCommitResponseTopic{}
+		r.Topics[i] = topic
+
+		l = int(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		topic.Topic = string(payload[offset : offset+l])
+		offset += l
+
+		l = int(binary.BigEndian.Uint32(payload[offset:]))
+		offset += 4
+		topic.Partitions = make([]*TxnOffsetCommitResponsePartition, l)
+		for j := range topic.Partitions {
+			p := &TxnOffsetCommitResponsePartition{}
+			topic.Partitions[j] = p
+
+			p.PartitionID = int32(binary.BigEndian.Uint32(payload[offset:]))
+			offset += 4
+			p.ErrorCode = int16(binary.BigEndian.Uint16(payload[offset:]))
+			offset += 2
+
+			if err == nil && p.ErrorCode != 0 {
+				err = getErrorFromErrorCode(p.ErrorCode)
+			}
+		}
+	}
+
+	return r, err
+}
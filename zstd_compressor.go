@@ -0,0 +1,61 @@
+package healer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// the encoder and decoder are safe for concurrent EncodeAll and DecodeAll, and they reuse the internal buffers
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+
+	// encoders are shared by the producers with the same level
+	zstdEncodersMutex sync.Mutex
+	zstdEncoders      = make(map[int]*zstd.Encoder)
+)
+
+// ZstdCompressor needs kafka 2.1+, which supports produce v7 and fetch v10. the zero value compresses in the default level
+type ZstdCompressor struct {
+	encoder *zstd.Encoder
+}
+
+// WithLevel returns a ZstdCompressor compressing in the level, which is 1 to 22 as the zstd command line.
+// the levels are mapped to the nearest ones implemented by klauspost/compress
+func (c *ZstdCompressor) WithLevel(level int) (Codec, error) {
+	if level < 1 || level > 22 {
+		return nil, fmt.Errorf("invalid zstd compression level %d. it should be in [1, 22]", level)
+	}
+
+	zstdEncodersMutex.Lock()
+	defer zstdEncodersMutex.Unlock()
+
+	encoder, ok := zstdEncoders[level]
+	if !ok {
+		var err error
+		encoder, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		if err != nil {
+			return nil, err
+		}
+		zstdEncoders[level] = encoder
+	}
+	return &ZstdCompressor{encoder: encoder}, nil
+}
+
+func (c *ZstdCompressor) Compress(value []byte) ([]byte, error) {
+	return c.AppendCompress(nil, value)
+}
+
+func (c *ZstdCompressor) AppendCompress(dst, value []byte) ([]byte, error) {
+	encoder := c.encoder
+	if encoder == nil {
+		encoder = zstdEncoder
+	}
+	return encoder.EncodeAll(value, dst), nil
+}
+
+func (c *ZstdCompressor) Decompress(value []byte) ([]byte, error) {
+	return zstdDecoder.DecodeAll(value, nil)
+}